@@ -61,26 +61,48 @@ func isSiteAvailable(csciID string) bool {
 	return true
 }
 
-// 从C-SMA获取聚合指标
-func getCmaMetrics() (map[string][]models.ServiceInstanceInfo, error) {
+// 从C-SMA获取聚合指标，同时返回陈旧实例的集合（ServiceID|CSCIID）
+func getCmaMetrics() (map[string][]models.ServiceInstanceInfo, map[string]bool, error) {
 	utils.Logger.Debug("CPS", "请求C-SMA聚合指标：%s/sync", config.Cfg.CSMA.URL) // 修正：utils.Logger
 	resp, err := http.Get(config.Cfg.CSMA.URL + "/sync")
 	if err != nil {
 		utils.Logger.Error("CPS", "获取C-SMA指标失败：%v", err) // 修正：utils.Logger
-		return nil, err
+		return nil, nil, err
 	}
 	defer resp.Body.Close()
 
 	var cmaResp struct {
-		Success bool                                   `json:"success"`
-		Data    map[string][]models.ServiceInstanceInfo `json:"data"`
+		Success   bool                                   `json:"success"`
+		Data      map[string][]models.ServiceInstanceInfo `json:"data"`
+		Freshness []models.InstanceFreshness              `json:"freshness"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&cmaResp); err != nil {
 		utils.Logger.Error("CPS", "解析C-SMA指标失败：%v", err) // 修正：utils.Logger
-		return nil, err
+		return nil, nil, err
 	}
-	utils.Logger.Info("CPS", "获取C-SMA指标成功，共%d个服务", len(cmaResp.Data)) // 修正：utils.Logger
-	return cmaResp.Data, nil
+	stale := make(map[string]bool)
+	for _, f := range cmaResp.Freshness {
+		if f.State == models.MetricStale {
+			stale[f.ServiceID+"|"+f.CSCIID] = true
+		}
+	}
+	utils.Logger.Info("CPS", "获取C-SMA指标成功，共%d个服务，%d个陈旧实例", len(cmaResp.Data), len(stale)) // 修正：utils.Logger
+	return cmaResp.Data, stale, nil
+}
+
+// 按配置的策略决定是否使用陈旧实例（never：丢弃；fallback：没有新鲜实例时才使用；always：同等对待）
+func applyStalePolicy(fresh, stale []models.ServiceInstanceInfo) []models.ServiceInstanceInfo {
+	switch config.Cfg.CPS.StalePolicy {
+	case models.StalePolicyNever:
+		return fresh
+	case models.StalePolicyAlways:
+		return append(fresh, stale...)
+	}
+	if len(fresh) == 0 && len(stale) > 0 {
+		utils.Logger.Warn("CPS", "无新鲜的候选实例，使用%d个陈旧实例", len(stale))
+		return stale
+	}
+	return fresh
 }
 
 func main() {
@@ -115,25 +137,31 @@ func main() {
 		utils.Logger.Info("CPS", "接收选择请求，服务ID：%s，最大成本：%d，最大延迟：%d", req.ServiceID, req.MaxAcceptCost, req.MaxAcceptDelay) // 修正：utils.Logger
 
 		// 1. 获取C-SMA指标
-		metrics, err := getCmaMetrics()
+		metrics, stale, err := getCmaMetrics()
 		if err != nil {
 			utils.Logger.Error("CPS", "获取指标失败：%v", err) // 修正：utils.Logger
 			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "msg": "获取指标失败：" + err.Error()})
 			return
 		}
 
-		// 2. 筛选可用且符合条件的Site
-		var candidates []models.ServiceInstanceInfo
+		// 2. 筛选可用且符合条件的Site（陈旧实例单独收集，按策略决定是否使用）
+		var candidates, staleCandidates []models.ServiceInstanceInfo
 		for _, instance := range metrics[req.ServiceID] {
 			if !isSiteAvailable(instance.CSCIID) {
 				continue
 			}
 			if instance.Cost <= req.MaxAcceptCost && instance.Gas > 0 {
 				instance.Delay += getNetworkDelay(instance.CSCIID)
+				if stale[instance.ServiceID+"|"+instance.CSCIID] {
+					staleCandidates = append(staleCandidates, instance)
+					utils.Logger.Debug("CPS", "候选Site（陈旧）：%+v", instance)
+					continue
+				}
 				candidates = append(candidates, instance)
 				utils.Logger.Debug("CPS", "候选Site：%+v", instance) // 修正：utils.Logger
 			}
 		}
+		candidates = applyStalePolicy(candidates, staleCandidates)
 
 		// 3. 无可用实例
 		if len(candidates) == 0 {
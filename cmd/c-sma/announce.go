@@ -0,0 +1,175 @@
+package main
+
+import (
+	"cmas-cats-go/models"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// siteTable 某个Site推送的服务模型表（草案Table 3）
+type siteTable struct {
+	seq       uint64                                // 已应用的最新序号
+	instances map[string]models.ServiceInstanceInfo // ServiceID|CSCIID → 实例
+	resync    bool                                  // 检测到序号缺口，等待Site重发快照
+	updatedAt time.Time                             // 最近一次应用通告的时间
+}
+
+// announcedTables 各Site推送的服务模型表（应用后的整张表写入指标表的site:<id>来源）
+// 协议：Site连接后先发送全量快照，之后只发送增量；序号不连续时要求重发快照
+// 每次应用通告都会刷新该Site全部条目的更新时间，Site没有变化时应定期发送空增量，否则条目会变为陈旧直至过期
+type announcedTables struct {
+	mu    sync.RWMutex
+	sites map[string]*siteTable
+}
+
+var announced = &announcedTables{sites: make(map[string]*siteTable)}
+
+// instanceKey 实例在服务模型表中的标识
+func instanceKey(info models.ServiceInstanceInfo) string {
+	return info.ServiceID + "|" + info.CSCIID
+}
+
+// Apply 应用一条通告并返回回复
+func (at *announcedTables) Apply(ann models.TableAnnouncement) (models.AnnounceAck, error) {
+	if ann.SiteID == "" {
+		return models.AnnounceAck{}, fmt.Errorf("缺少site_id")
+	}
+	at.mu.Lock()
+	defer at.mu.Unlock()
+
+	ack := models.AnnounceAck{SiteID: ann.SiteID}
+	table := at.sites[ann.SiteID]
+	switch ann.Type {
+	case models.AnnounceSnapshot:
+		// 快照总是被接受，替换该Site的整张表
+		instances := make(map[string]models.ServiceInstanceInfo, len(ann.Instances))
+		for _, info := range ann.Instances {
+			if info.ServiceID == "" || info.CSCIID == "" {
+				return ack, fmt.Errorf("快照中的实例缺少service_id或csci_id")
+			}
+			instances[instanceKey(info)] = info
+		}
+		table = &siteTable{seq: ann.Seq, instances: instances, updatedAt: time.Now()}
+		at.sites[ann.SiteID] = table
+		metrics.ReplaceSource(siteSource(ann.SiteID), table.list(), nil)
+		ack.Accepted, ack.Seq, ack.Msg = true, ann.Seq, fmt.Sprintf("已应用快照（%d个实例）", len(instances))
+		return ack, nil
+
+	case models.AnnounceDelta:
+		switch {
+		case table == nil:
+			ack.Resync, ack.Msg = true, "未收到该Site的快照，请发送全量快照"
+			return ack, nil
+		case table.resync:
+			ack.Seq, ack.Resync, ack.Msg = table.seq, true, "等待全量快照"
+			return ack, nil
+		case ann.Seq <= table.seq:
+			// 重发的旧增量：已应用过，直接确认
+			ack.Accepted, ack.Seq, ack.Msg = true, table.seq, fmt.Sprintf("序号%d已应用，忽略", ann.Seq)
+			return ack, nil
+		case ann.Seq != table.seq+1:
+			table.resync = true
+			ack.Seq, ack.Resync = table.seq, true
+			ack.Msg = fmt.Sprintf("序号缺口（期望%d，收到%d），请发送全量快照", table.seq+1, ann.Seq)
+			return ack, nil
+		}
+		// 先校验全部增量，避免部分应用
+		for _, d := range ann.Changes {
+			if d.Instance.ServiceID == "" || d.Instance.CSCIID == "" {
+				return ack, fmt.Errorf("增量中的实例缺少service_id或csci_id")
+			}
+			switch d.Op {
+			case models.DeltaAdd, models.DeltaUpdate, models.DeltaRemove:
+			default:
+				return ack, fmt.Errorf("未知的增量操作%q", d.Op)
+			}
+		}
+		for _, d := range ann.Changes {
+			key := instanceKey(d.Instance)
+			if d.Op == models.DeltaRemove {
+				delete(table.instances, key)
+				continue
+			}
+			table.instances[key] = d.Instance
+		}
+		table.seq, table.updatedAt = ann.Seq, time.Now()
+		metrics.ReplaceSource(siteSource(ann.SiteID), table.list(), nil)
+		ack.Accepted, ack.Seq, ack.Msg = true, ann.Seq, fmt.Sprintf("已应用%d条增量", len(ann.Changes))
+		return ack, nil
+	}
+	return ack, fmt.Errorf("未知的通告类型%q（snapshot/delta）", ann.Type)
+}
+
+// list 该Site表中的全部实例
+func (t *siteTable) list() []models.ServiceInstanceInfo {
+	list := make([]models.ServiceInstanceInfo, 0, len(t.instances))
+	for _, info := range t.instances {
+		list = append(list, info)
+	}
+	return list
+}
+
+// siteStatus Site通告状态
+type siteStatus struct {
+	SiteID    string    `json:"site_id"`
+	Seq       uint64    `json:"seq"`
+	Instances int       `json:"instances"`
+	Resync    bool      `json:"resync"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Status 各Site的通告状态
+func (at *announcedTables) Status() []siteStatus {
+	at.mu.RLock()
+	defer at.mu.RUnlock()
+	result := make([]siteStatus, 0, len(at.sites))
+	for id, table := range at.sites {
+		result = append(result, siteStatus{
+			SiteID: id, Seq: table.seq, Instances: len(table.instances), Resync: table.resync, UpdatedAt: table.updatedAt,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].SiteID < result[j].SiteID })
+	return result
+}
+
+// handleAnnounce Site推送服务模型表（POST快照/增量；GET查询各Site的通告状态）
+func handleAnnounce(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	switch r.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"data":    announced.Status(),
+			"msg":     "查询成功",
+		})
+		return
+	case http.MethodPost:
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "msg": "只支持GET/POST"})
+		return
+	}
+
+	var ann models.TableAnnouncement
+	if err := json.NewDecoder(r.Body).Decode(&ann); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "msg": "通告解析失败：" + err.Error()})
+		return
+	}
+	ack, err := announced.Apply(ann)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "data": ack, "msg": err.Error()})
+		return
+	}
+	if ack.Resync {
+		// 409：Site需要重发全量快照
+		w.WriteHeader(http.StatusConflict)
+	}
+	fmt.Printf("Site %s 通告（%s，序号%d）：%s\n", ann.SiteID, ann.Type, ann.Seq, ack.Msg)
+	json.NewEncoder(w).Encode(map[string]interface{}{"success": ack.Accepted, "data": ack, "msg": ack.Msg})
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"cmas-cats-go/config"
+	"cmas-cats-go/utils"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Site发现方式（config.Cfg.CSMA.Discovery.Sources）
+const (
+	discoveryDockerLabels = "docker-labels" // 按容器标签发现本机Site容器
+	discoveryInventory    = "inventory"     // 按Site清单文件发现（修改后自动重新加载）
+	discoveryStatic       = "static"        // 静态配置的远端Site地址
+)
+
+// 容器未声明时的指标接口
+const (
+	defaultMetricsPath = "/metrics"
+	defaultMetricsPort = 5000
+)
+
+// siteDiscoverer 一种Site发现方式
+type siteDiscoverer interface {
+	Name() string
+	Discover() ([]scrapeTarget, error)
+}
+
+// newDiscoverer 按名称创建发现方式
+func newDiscoverer(name string) (siteDiscoverer, error) {
+	switch name {
+	case discoveryDockerLabels:
+		return dockerLabelDiscoverer{}, nil
+	case discoveryInventory:
+		return &inventoryDiscoverer{path: config.Cfg.CSMA.Discovery.InventoryFile}, nil
+	case discoveryStatic:
+		return newStaticDiscoverer(config.Cfg.CSMA.Discovery.StaticSites), nil
+	}
+	return nil, fmt.Errorf("未知的Site发现方式%q（%s/%s/%s）", name, discoveryDockerLabels, discoveryInventory, discoveryStatic)
+}
+
+// discovery 组合启用的发现方式；某种方式失败时沿用它上一次的结果，避免Site被误判为下线
+type discovery struct {
+	sources []siteDiscoverer
+	mu      sync.Mutex
+	last    map[string][]scrapeTarget // 发现方式 → 最近一次成功的结果
+}
+
+func newDiscovery(names []string) *discovery {
+	d := &discovery{last: make(map[string][]scrapeTarget)}
+	for _, name := range names {
+		source, err := newDiscoverer(name)
+		if err != nil {
+			fmt.Printf("忽略Site发现方式: %v\n", err)
+			continue
+		}
+		d.sources = append(d.sources, source)
+	}
+	return d
+}
+
+var sites = newDiscovery(config.Cfg.CSMA.Discovery.Sources)
+
+// Targets 本轮待采集的Site（同一访问地址被多种方式发现时以先启用的方式为准）
+func (d *discovery) Targets() []scrapeTarget {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var targets []scrapeTarget
+	seen := make(map[string]bool)
+	for _, source := range d.sources {
+		found, err := source.Discover()
+		if err != nil {
+			fmt.Printf("Site发现（%s）失败，沿用上次结果: %v\n", source.Name(), err)
+			found = d.last[source.Name()]
+		} else {
+			d.last[source.Name()] = found
+		}
+		for _, t := range found {
+			if seen[t.CSCIID] {
+				continue
+			}
+			seen[t.CSCIID] = true
+			targets = append(targets, t)
+		}
+	}
+	return targets
+}
+
+// Current 各发现方式最近一次的结果
+func (d *discovery) Current() map[string][]scrapeTarget {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	result := make(map[string][]scrapeTarget, len(d.sources))
+	for _, source := range d.sources {
+		result[source.Name()] = append([]scrapeTarget{}, d.last[source.Name()]...)
+	}
+	return result
+}
+
+// containerTarget 容器Site的采集目标：CSCIID固定为容器IP:容器端口，采集地址按配置的访问方式生成
+func containerTarget(c utils.ContainerInfo, source string, serviceIDs []string, path string, port int) (scrapeTarget, error) {
+	if c.IP == "" {
+		return scrapeTarget{}, fmt.Errorf("容器%s没有IP", c.Name)
+	}
+	cfg := config.Cfg.CSMA.Discovery
+	host, hostPort := c.IP, strconv.Itoa(port)
+	if cfg.Reach != "container" {
+		if hostPort = c.Ports[port]; hostPort == "" {
+			return scrapeTarget{}, fmt.Errorf("容器%s的端口%d未映射到宿主机", c.Name, port)
+		}
+		host = cfg.HostIP
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return scrapeTarget{
+		Name:       c.Name,
+		Source:     source,
+		URL:        fmt.Sprintf("http://%s:%s%s", host, hostPort, path),
+		CSCIID:     fmt.Sprintf("%s:%d", c.IP, port),
+		ServiceIDs: serviceIDs,
+	}, nil
+}
+
+// dockerLabelDiscoverer 按标签发现Site容器（服务ID、指标路径与端口由标签声明）
+// 配置了容器名前缀时同时发现未打标签的旧容器，按默认指标接口采集
+type dockerLabelDiscoverer struct{}
+
+func (dockerLabelDiscoverer) Name() string { return discoveryDockerLabels }
+
+func (dockerLabelDiscoverer) Discover() ([]scrapeTarget, error) {
+	ids, err := utils.ListContainerIDs("label=" + utils.LabelSite)
+	if err != nil {
+		return nil, err
+	}
+	if prefix := config.Cfg.CSMA.Discovery.NamePrefix; prefix != "" {
+		more, err := utils.ListContainerIDs("name=" + prefix)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, more...)
+	}
+	seen := make(map[string]bool, len(ids))
+	var unique []string
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	containers, err := utils.InspectContainers(unique...)
+	if err != nil {
+		return nil, err
+	}
+
+	var targets []scrapeTarget
+	for _, c := range containers {
+		if !c.Running {
+			continue
+		}
+		path, port := defaultMetricsPath, defaultMetricsPort
+		if p := c.Labels[utils.LabelMetricsPath]; p != "" {
+			path = p
+		}
+		if p := c.Labels[utils.LabelMetricsPort]; p != "" {
+			n, err := strconv.Atoi(p)
+			if err != nil || n <= 0 {
+				fmt.Printf("容器%s的标签%s=%q无效，跳过\n", c.Name, utils.LabelMetricsPort, p)
+				continue
+			}
+			port = n
+		}
+		t, err := containerTarget(c, discoveryDockerLabels, splitServiceIDs(c.Labels[utils.LabelServices]), path, port)
+		if err != nil {
+			fmt.Printf("跳过容器%s: %v\n", c.Name, err)
+			continue
+		}
+		targets = append(targets, t)
+	}
+	return targets, nil
+}
+
+// splitServiceIDs 解析逗号分隔的服务ID
+func splitServiceIDs(s string) []string {
+	var ids []string
+	for _, id := range strings.Split(s, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// inventoryDiscoverer 按Site清单文件（格式同config/docker_sites.json）发现Site容器
+// 每轮检查文件的修改时间与大小，变化后重新加载；解析失败时沿用上次加载的清单
+type inventoryDiscoverer struct {
+	path    string
+	modTime time.Time
+	size    int64
+	loaded  bool
+	sites   []config.DockerSiteConfig
+}
+
+func (d *inventoryDiscoverer) Name() string { return discoveryInventory }
+
+// reload 文件变化时重新加载清单
+func (d *inventoryDiscoverer) reload() error {
+	info, err := os.Stat(d.path)
+	if os.IsNotExist(err) {
+		// 清单被删除（或从未创建）视为空清单
+		if !d.loaded || !d.modTime.IsZero() {
+			fmt.Printf("Site清单%s不存在\n", d.path)
+		}
+		d.sites, d.loaded, d.modTime, d.size = nil, true, time.Time{}, 0
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if d.loaded && info.ModTime().Equal(d.modTime) && info.Size() == d.size {
+		return nil
+	}
+	d.modTime, d.size = info.ModTime(), info.Size()
+
+	data, err := os.ReadFile(d.path)
+	if err != nil {
+		return err
+	}
+	var inventory struct {
+		Sites []config.DockerSiteConfig `json:"sites"`
+	}
+	if err := json.Unmarshal(data, &inventory); err != nil {
+		if d.loaded {
+			fmt.Printf("解析Site清单%s失败，沿用上次加载的清单: %v\n", d.path, err)
+			return nil
+		}
+		return fmt.Errorf("解析Site清单%s失败: %v", d.path, err)
+	}
+	d.sites, d.loaded = inventory.Sites, true
+	fmt.Printf("已加载Site清单%s（%d个Site）\n", d.path, len(d.sites))
+	return nil
+}
+
+func (d *inventoryDiscoverer) Discover() ([]scrapeTarget, error) {
+	if err := d.reload(); err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(d.sites))
+	for _, site := range d.sites {
+		if site.ContainerName != "" {
+			names = append(names, site.ContainerName)
+		}
+	}
+	containers, err := utils.InspectContainers(names...)
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]utils.ContainerInfo, len(containers))
+	for _, c := range containers {
+		byName[c.Name] = c
+	}
+
+	var targets []scrapeTarget
+	for _, site := range d.sites {
+		c, ok := byName[site.ContainerName]
+		if !ok || !c.Running {
+			continue
+		}
+		port := site.Port
+		if port <= 0 {
+			port = defaultMetricsPort
+		}
+		t, err := containerTarget(c, discoveryInventory, splitServiceIDs(site.ServiceID), defaultMetricsPath, port)
+		if err != nil {
+			fmt.Printf("跳过清单中的容器%s: %v\n", site.ContainerName, err)
+			continue
+		}
+		targets = append(targets, t)
+	}
+	return targets, nil
+}
+
+// staticDiscoverer 静态配置的远端Site（指标地址，如http://10.0.0.5:5000/metrics）
+// 路径为空时使用/metrics，CSCIID取地址中的host:port
+type staticDiscoverer struct {
+	targets []scrapeTarget
+}
+
+// newStaticDiscoverer 启动时解析一次静态地址，无效地址被忽略
+func newStaticDiscoverer(urls []string) staticDiscoverer {
+	var d staticDiscoverer
+	for _, raw := range urls {
+		u, err := url.Parse(raw)
+		if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			fmt.Printf("忽略无效的静态Site地址%q\n", raw)
+			continue
+		}
+		if u.Path == "" || u.Path == "/" {
+			u.Path = defaultMetricsPath
+		}
+		d.targets = append(d.targets, scrapeTarget{
+			Name:   u.Host,
+			Source: discoveryStatic,
+			URL:    u.String(),
+			CSCIID: u.Host,
+		})
+	}
+	return d
+}
+
+func (staticDiscoverer) Name() string { return discoveryStatic }
+
+func (d staticDiscoverer) Discover() ([]scrapeTarget, error) {
+	return append([]scrapeTarget{}, d.targets...), nil
+}
@@ -1,12 +1,11 @@
 package main
 
 import (
+	"cmas-cats-go/config"
 	"cmas-cats-go/models"
-	"cmas-cats-go/utils"
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"strings"
 	"time"
 )
 
@@ -20,15 +19,12 @@ type ServiceMetric struct {
 	Delay     float64 `json:"delay"`
 }
 
-var metricMap = make(map[string][]models.ServiceInstanceInfo) // 存储所有服务指标
-
 func main() {
-	// 定时扫描cmas容器（每5秒一次）
+	// 定时发现Site并采集指标（周期带随机抖动，避免各Site被同时请求）
 	go func() {
-		ticker := time.NewTicker(5 * time.Second)
-		defer ticker.Stop()
-		for range ticker.C {
-			scanCmasContainers()
+		for {
+			time.Sleep(jitter(config.Cfg.CSMA.Scrape.Interval))
+			scanSites()
 		}
 	}()
 
@@ -36,11 +32,8 @@ func main() {
 	http.HandleFunc("/api/metrics/all", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 
-		// 转换metricMap为JSON返回
-		response := make(map[string][]models.ServiceInstanceInfo)
-		for serviceID, instances := range metricMap {
-			response[serviceID] = instances
-		}
+		// 合并轮询采集与Site推送的实例后返回
+		response := currentMetrics(metrics.Snapshot(), time.Now())
 
 		json.NewEncoder(w).Encode(response)
 	})
@@ -49,72 +42,158 @@ func main() {
 	http.HandleFunc("/sync", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 
-		// 返回指标数据给CPS
+		// 返回指标数据给CPS（附表版本，便于判断是否变化；freshness标明每个实例是否陈旧）
+		snap, now := metrics.Snapshot(), time.Now()
+		freshness := currentFreshness(snap, now)
+		stale := 0
+		for _, f := range freshness {
+			if f.State == models.MetricStale {
+				stale++
+			}
+		}
 		response := map[string]interface{}{
-			"success": true,
-			"data":    metricMap,
-			"msg":     "指标同步成功",
+			"success":    true,
+			"data":       currentMetrics(snap, now),
+			"freshness":  freshness,
+			"stale":      stale,
+			"version":    snap.Version,
+			"updated_at": snap.UpdatedAt,
+			"msg":        "指标同步成功",
 		}
 
 		json.NewEncoder(w).Encode(response)
 	})
 
+	// 指标表快照：表版本及每个条目的来源与更新时间
+	http.HandleFunc("/api/metrics/snapshot", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		snap := metrics.Snapshot()
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success":    true,
+			"version":    snap.Version,
+			"updated_at": snap.UpdatedAt,
+			"data":       snap.List(),
+			"msg":        "查询成功",
+		})
+	})
+
+	// 各Site的采集统计（最近成功时间、错误次数、耗时、退避状态）
+	http.HandleFunc("/api/scrape/stats", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		stats := scrapes.Stats()
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"data":    stats,
+			"total":   len(stats),
+			"msg":     "查询成功",
+		})
+	})
+
+	// 各发现方式最近一次发现的Site
+	http.HandleFunc("/api/discovery", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"data":    sites.Current(),
+			"msg":     "查询成功",
+		})
+	})
+
+	// Site推送服务模型表（首次全量快照，之后增量）
+	http.HandleFunc("/api/announce", handleAnnounce)
+
 	// 启动CSMA服务（8083端口）
 	fmt.Println("CSMA服务启动：0.0.0.0:8083")
 	http.ListenAndServe(":8083", nil)
 }
 
-// scanCmasContainers 扫描cmas容器，拉取指标
-func scanCmasContainers() {
-	// 1. 调用工具函数获取cmas容器列表
-	containerOutput, err := utils.ListCmasContainers()
-	if err != nil {
-		fmt.Printf("扫描容器失败: %v\n", err)
-		return
+// scanSites 发现各Site并拉取指标
+func scanSites() {
+	// 1. 通过启用的发现方式（容器标签、Site清单、静态地址）获取待采集的Site
+	discovered := sites.Targets()
+
+	// 获取已通过部署验证的实例，失败时沿用上一次结果
+	if config.Cfg.CSMA.RequireValidation {
+		if set, err := fetchValidatedContainers(); err != nil {
+			fmt.Printf("获取已验证实例失败，沿用上次结果: %v\n", err)
+		} else {
+			validated.Store(set)
+		}
 	}
-	lines := strings.Split(containerOutput, "\n")
+	current := validated.Load()
 
-	// 临时存储新扫描到的指标
-	newMetrics := make(map[string][]models.ServiceInstanceInfo)
-
-	for _, line := range lines {
-		if line == "" {
-			continue
-		}
-		fields := strings.Split(line, "\t")
-		if len(fields) < 5 {
-			continue
-		}
-		containerName := fields[0]
-		containerIP := fields[4]
-		serviceID := strings.TrimPrefix(containerName, "cmas-")
-		hostPort := strings.Split(fields[2], ":")[0] // 提取宿主机端口
-
-		// 2. 拉取该容器的/metrics接口（用宿主机IP+端口）
-		metricURL := fmt.Sprintf("http://%s:%s/metrics", "192.168.235.48", hostPort) // 替换为你的服务器IP
-		resp, err := http.Get(metricURL)
-		if err != nil {
-			fmt.Printf("拉取%s指标失败: %v\n", serviceID, err)
+	// 2. 未通过部署验证的实例不采集（容器按容器名，远端Site按访问地址）
+	var targets []scrapeTarget
+	for _, t := range discovered {
+		if config.Cfg.CSMA.RequireValidation && !current.containers[t.Name] && !current.instances[t.CSCIID] {
+			fmt.Printf("Site %s未通过部署验证，跳过\n", t.Name)
 			continue
 		}
-		defer resp.Body.Close()
+		targets = append(targets, t)
+	}
 
-		// 3. 解析指标
-		var containerMetrics map[string]*models.ServiceInstanceInfo
-		if err := json.NewDecoder(resp.Body).Decode(&containerMetrics); err != nil {
-			fmt.Printf("解析%s指标失败: %v\n", serviceID, err)
-			continue
-		}
+	// 3. 并发采集（单Site超时，失败的Site指数退避）
+	newMetrics, missed := scrapes.Run(targets)
 
-		// 4. 更新到newMetrics
-		for sid, metric := range containerMetrics {
-			// 更新CSCIID为容器内部IP:端口格式
-			metric.CSCIID = fmt.Sprintf("%s:%s", containerIP, "5000") // 使用容器内部端口
-			newMetrics[sid] = append(newMetrics[sid], *metric)
-			fmt.Printf("更新%s指标成功: %+v\n", sid, metric)
-		}
+	// 4. 用新指标替换轮询来源的条目：本轮没有新数据的Site保留最近一次成功的条目，已下线的Site直接移除
+	keep := make(map[string]bool, len(missed))
+	for _, t := range missed {
+		keep[t.CSCIID] = true
+	}
+	metrics.ReplaceSource(sourcePoll, newMetrics, keep)
+
+	// 5. 移除超过TTL未更新的条目（包括长时间未通告的Site）
+	if n := metrics.Expire(time.Now()); n > 0 {
+		fmt.Printf("移除%d个过期的指标条目\n", n)
 	}
+}
+
+// currentMetrics 对外提供的指标：轮询采集与Site推送的实例合并（要求部署验证时只保留已验证的实例）
+// 陈旧条目仍然返回，由调用方根据freshness决定是否使用
+func currentMetrics(snap *metricSnapshot, now time.Time) map[string][]models.ServiceInstanceInfo {
+	return snap.ByService(validatedInstances(), now)
+}
 
-	// 5. 将新指标更新到全局metricMap
-	metricMap = newMetrics
-}
\ No newline at end of file
+// currentFreshness 与currentMetrics对应的各实例新鲜度
+func currentFreshness(snap *metricSnapshot, now time.Time) []models.InstanceFreshness {
+	return snap.Freshness(validatedInstances(), now)
+}
+
+// validatedInstances 要求部署验证时返回已验证的CSCIID集合，否则返回nil（不过滤）
+func validatedInstances() map[string]bool {
+	if config.Cfg.CSMA.RequireValidation {
+		return validated.Load().instances
+	}
+	return nil
+}
+
+// fetchValidatedContainers 从Platform获取已通过部署验证的实例（容器名集合与CSCIID集合）
+func fetchValidatedContainers() (*validatedSet, error) {
+	client := &http.Client{Timeout: 3 * time.Second}
+	resp, err := client.Get(config.Cfg.Platform.URL + "/api/v1/deployments?status=" + models.ValidationValidated)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Success bool                        `json:"success"`
+		Data    []models.InstanceValidation `json:"data"`
+		Msg     string                      `json:"msg"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	if !result.Success {
+		return nil, fmt.Errorf("Platform返回失败：%s", result.Msg)
+	}
+	set := &validatedSet{
+		containers: make(map[string]bool, len(result.Data)),
+		instances:  make(map[string]bool, len(result.Data)),
+	}
+	for _, rec := range result.Data {
+		set.containers[rec.ContainerName] = true
+		set.instances[rec.CSCIID] = true
+	}
+	return set, nil
+}
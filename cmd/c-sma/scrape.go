@@ -0,0 +1,230 @@
+package main
+
+import (
+	"cmas-cats-go/config"
+	"cmas-cats-go/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// maxMetricsBody 单个/metrics响应的最大读取字节数
+const maxMetricsBody = 1 << 20
+
+// scrapeTarget 一个待采集的Site实例
+type scrapeTarget struct {
+	Name       string   `json:"name"`                  // 实例标识（容器名，远端Site为host:port）
+	Source     string   `json:"source"`                // 发现方式
+	URL        string   `json:"url"`                   // 指标接口地址
+	CSCIID     string   `json:"csci_id"`               // 对外提供的访问地址（容器IP:端口）
+	ServiceIDs []string `json:"service_ids,omitempty"` // 声明提供的服务（为空时以指标接口返回为准）
+}
+
+// scrapeStats 单个Site的采集统计
+type scrapeStats struct {
+	Name                string        `json:"name"`
+	Source              string        `json:"source"`
+	URL                 string        `json:"url"`
+	LastAttempt         time.Time     `json:"last_attempt"`
+	LastSuccess         time.Time     `json:"last_success"`
+	LastDuration        time.Duration `json:"last_duration_ns"`
+	LastError           string        `json:"last_error,omitempty"`
+	Successes           int           `json:"successes"`
+	Errors              int           `json:"errors"`
+	ConsecutiveFailures int           `json:"consecutive_failures"`
+	NextAttempt         time.Time     `json:"next_attempt"` // 退避中时为下一次允许采集的时间
+}
+
+// scrapeResult 单次采集结果
+type scrapeResult struct {
+	target    scrapeTarget
+	instances []models.ServiceInstanceInfo
+	err       error
+	duration  time.Duration
+}
+
+// scraper 并发采集各Site的/metrics：有界worker池、单Site超时、失败指数退避
+type scraper struct {
+	client *http.Client
+	mu     sync.Mutex
+	stats  map[string]*scrapeStats
+}
+
+var scrapes = &scraper{client: &http.Client{}, stats: make(map[string]*scrapeStats)}
+
+// jitter 按配置的比例给时长加随机抖动
+func jitter(d time.Duration) time.Duration {
+	j := config.Cfg.CSMA.Scrape.Jitter
+	if j <= 0 || d <= 0 {
+		return d
+	}
+	return time.Duration(float64(d) * (1 + j*(2*rand.Float64()-1)))
+}
+
+// backoff 连续失败n次后的退避时间
+func backoff(n int) time.Duration {
+	cfg := config.Cfg.CSMA.Scrape
+	if n <= 0 || cfg.BackoffBase <= 0 {
+		return 0
+	}
+	d := cfg.BackoffBase
+	for i := 1; i < n && (cfg.BackoffMax <= 0 || d < cfg.BackoffMax); i++ {
+		d *= 2
+	}
+	if cfg.BackoffMax > 0 && d > cfg.BackoffMax {
+		d = cfg.BackoffMax
+	}
+	return jitter(d)
+}
+
+// Run 采集一轮：跳过退避中的Site，其余交给worker池并发采集
+// 返回成功采集到的实例，以及本轮没有新数据（退避中或采集失败）的Site
+func (s *scraper) Run(targets []scrapeTarget) ([]models.ServiceInstanceInfo, []scrapeTarget) {
+	now := time.Now()
+	var due, missed []scrapeTarget
+	current := make(map[string]bool, len(targets))
+	s.mu.Lock()
+	for _, t := range targets {
+		current[t.Name] = true
+	}
+	for name := range s.stats {
+		if !current[name] {
+			delete(s.stats, name) // 已下线的Site
+		}
+	}
+	for _, t := range targets {
+		if st, ok := s.stats[t.Name]; ok && now.Before(st.NextAttempt) {
+			missed = append(missed, t)
+			continue
+		}
+		due = append(due, t)
+	}
+	s.mu.Unlock()
+
+	workers := config.Cfg.CSMA.Scrape.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+	if workers > len(due) {
+		workers = len(due)
+	}
+	jobs := make(chan scrapeTarget)
+	results := make(chan scrapeResult)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for t := range jobs {
+				results <- s.scrape(t)
+			}
+		}()
+	}
+	go func() {
+		for _, t := range due {
+			jobs <- t
+		}
+		close(jobs)
+		wg.Wait()
+		close(results)
+	}()
+
+	var instances []models.ServiceInstanceInfo
+	for r := range results {
+		s.record(r)
+		if r.err != nil {
+			fmt.Printf("拉取%s指标失败: %v\n", r.target.Name, r.err)
+			missed = append(missed, r.target)
+			continue
+		}
+		instances = append(instances, r.instances...)
+	}
+	return instances, missed
+}
+
+// scrape 采集单个Site（超时由上下文控制，响应体在本函数内关闭）
+func (s *scraper) scrape(t scrapeTarget) scrapeResult {
+	start := time.Now()
+	result := scrapeResult{target: t}
+	ctx, cancel := context.WithTimeout(context.Background(), config.Cfg.CSMA.Scrape.Timeout)
+	defer cancel()
+	result.instances, result.err = s.fetch(ctx, t)
+	result.duration = time.Since(start)
+	return result
+}
+
+func (s *scraper) fetch(ctx context.Context, t scrapeTarget) ([]models.ServiceInstanceInfo, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, maxMetricsBody))
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	var containerMetrics map[string]*models.ServiceInstanceInfo
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxMetricsBody)).Decode(&containerMetrics); err != nil {
+		return nil, fmt.Errorf("解析指标失败: %v", err)
+	}
+	declared := make(map[string]bool, len(t.ServiceIDs))
+	for _, sid := range t.ServiceIDs {
+		declared[sid] = true
+	}
+	var instances []models.ServiceInstanceInfo
+	for sid, metric := range containerMetrics {
+		if metric == nil || (len(declared) > 0 && !declared[sid]) {
+			continue
+		}
+		metric.ServiceID = sid
+		metric.CSCIID = t.CSCIID
+		instances = append(instances, *metric)
+	}
+	return instances, nil
+}
+
+// record 更新采集统计；失败时按连续失败次数计算下一次允许采集的时间
+func (s *scraper) record(r scrapeResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, ok := s.stats[r.target.Name]
+	if !ok {
+		st = &scrapeStats{Name: r.target.Name}
+		s.stats[r.target.Name] = st
+	}
+	now := time.Now()
+	st.Source, st.URL, st.LastAttempt, st.LastDuration = r.target.Source, r.target.URL, now, r.duration
+	if r.err != nil {
+		st.Errors++
+		st.ConsecutiveFailures++
+		st.LastError = r.err.Error()
+		st.NextAttempt = now.Add(backoff(st.ConsecutiveFailures))
+		return
+	}
+	st.Successes++
+	st.ConsecutiveFailures = 0
+	st.LastSuccess, st.LastError, st.NextAttempt = now, "", time.Time{}
+}
+
+// Stats 各Site的采集统计（按名称排序）
+func (s *scraper) Stats() []scrapeStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := make([]scrapeStats, 0, len(s.stats))
+	for _, st := range s.stats {
+		list = append(list, *st)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"cmas-cats-go/config"
+	"cmas-cats-go/models"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 指标来源
+const sourcePoll = "poll" // C-SMA轮询容器/metrics采集
+
+// siteSource Site推送的服务模型表来源
+func siteSource(siteID string) string {
+	return "site:" + siteID
+}
+
+// metricEntry 指标表条目
+type metricEntry struct {
+	Source    string                     `json:"source"`     // 来源（poll或site:<id>）
+	Info      models.ServiceInstanceInfo `json:"info"`       // 实例指标
+	UpdatedAt time.Time                  `json:"updated_at"` // 最近一次采集/通告的时间
+}
+
+// metricSnapshot 指标表快照（发布后不再修改，可被任意多个读者并发使用）
+type metricSnapshot struct {
+	Version   uint64                 `json:"version"`    // 表版本，内容每变化一次加1
+	UpdatedAt time.Time              `json:"updated_at"` // 快照生成时间
+	Entries   map[string]metricEntry `json:"-"`          // 来源|ServiceID|CSCIID → 条目
+}
+
+// entryKey 条目在指标表中的标识
+func entryKey(source string, info models.ServiceInstanceInfo) string {
+	return source + "|" + instanceKey(info)
+}
+
+// List 全部条目（按服务ID、访问地址、来源排序）
+func (s *metricSnapshot) List() []metricEntry {
+	list := make([]metricEntry, 0, len(s.Entries))
+	for _, e := range s.Entries {
+		list = append(list, e)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		if a.Info.ServiceID != b.Info.ServiceID {
+			return a.Info.ServiceID < b.Info.ServiceID
+		}
+		if a.Info.CSCIID != b.Info.CSCIID {
+			return a.Info.CSCIID < b.Info.CSCIID
+		}
+		return a.Source < b.Source
+	})
+	return list
+}
+
+// entryState 条目在now时刻的新鲜度（按配置的StaleAfter与MetricTTL）
+func entryState(e metricEntry, now time.Time) string {
+	age := now.Sub(e.UpdatedAt)
+	switch {
+	case config.Cfg.CSMA.MetricTTL > 0 && age > config.Cfg.CSMA.MetricTTL:
+		return models.MetricExpired
+	case config.Cfg.CSMA.StaleAfter > 0 && age > config.Cfg.CSMA.StaleAfter:
+		return models.MetricStale
+	}
+	return models.MetricFresh
+}
+
+// chosen 每个实例对外提供的条目（ServiceID|CSCIID → 条目），过期条目不参与
+// 同一实例有多个来源时优先新鲜的条目，其次以Site推送为准；validated非空时只保留已通过部署验证的实例
+func (s *metricSnapshot) chosen(validated map[string]bool, now time.Time) map[string]metricEntry {
+	chosen := make(map[string]metricEntry)
+	for _, e := range s.Entries {
+		if validated != nil && !validated[e.Info.CSCIID] {
+			continue
+		}
+		state := entryState(e, now)
+		if state == models.MetricExpired {
+			continue
+		}
+		key := instanceKey(e.Info)
+		if old, ok := chosen[key]; ok {
+			oldFresh, fresh := entryState(old, now) == models.MetricFresh, state == models.MetricFresh
+			if oldFresh != fresh {
+				if oldFresh {
+					continue
+				}
+			} else if old.Source != sourcePoll {
+				continue
+			}
+		}
+		chosen[key] = e
+	}
+	return chosen
+}
+
+// ByService 按服务ID分组的实例（包含陈旧条目，不包含过期条目）
+func (s *metricSnapshot) ByService(validated map[string]bool, now time.Time) map[string][]models.ServiceInstanceInfo {
+	result := make(map[string][]models.ServiceInstanceInfo)
+	for _, e := range s.chosen(validated, now) {
+		result[e.Info.ServiceID] = append(result[e.Info.ServiceID], e.Info)
+	}
+	for _, list := range result {
+		sort.Slice(list, func(i, j int) bool { return list[i].CSCIID < list[j].CSCIID })
+	}
+	return result
+}
+
+// Freshness 与ByService对应的各实例新鲜度（按服务ID、访问地址排序）
+func (s *metricSnapshot) Freshness(validated map[string]bool, now time.Time) []models.InstanceFreshness {
+	chosen := s.chosen(validated, now)
+	list := make([]models.InstanceFreshness, 0, len(chosen))
+	for _, e := range chosen {
+		list = append(list, models.InstanceFreshness{
+			ServiceID: e.Info.ServiceID,
+			CSCIID:    e.Info.CSCIID,
+			Source:    e.Source,
+			UpdatedAt: e.UpdatedAt,
+			AgeMs:     now.Sub(e.UpdatedAt).Milliseconds(),
+			State:     entryState(e, now),
+		})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].ServiceID != list[j].ServiceID {
+			return list[i].ServiceID < list[j].ServiceID
+		}
+		return list[i].CSCIID < list[j].CSCIID
+	})
+	return list
+}
+
+// metricStore 并发安全的指标表
+// 写入方串行化后基于当前快照生成新快照并原子替换（copy-on-write），读取方无锁获取不可变快照
+type metricStore struct {
+	mu      sync.Mutex // 串行化写入
+	current atomic.Pointer[metricSnapshot]
+}
+
+func newMetricStore() *metricStore {
+	ms := &metricStore{}
+	ms.current.Store(&metricSnapshot{UpdatedAt: time.Now(), Entries: map[string]metricEntry{}})
+	return ms
+}
+
+var metrics = newMetricStore()
+
+// Snapshot 当前快照
+func (ms *metricStore) Snapshot() *metricSnapshot {
+	return ms.current.Load()
+}
+
+// ReplaceSource 用新数据整体替换某个来源的条目，返回替换后的表版本
+// 本次提供的条目都会刷新更新时间；访问地址在keep中的旧条目（本轮采集失败的实例）原样保留，
+// 作为最近一次成功的数据随时间变为陈旧直至过期；只有内容变化（新增、删除或指标变化）时版本加1
+func (ms *metricStore) ReplaceSource(source string, infos []models.ServiceInstanceInfo, keep map[string]bool) uint64 {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	old := ms.current.Load()
+	now := time.Now()
+	entries := make(map[string]metricEntry, len(old.Entries)+len(infos))
+	changed := false
+	for key, e := range old.Entries {
+		if e.Source != source || keep[e.Info.CSCIID] {
+			entries[key] = e
+		}
+	}
+	for _, info := range infos {
+		key := entryKey(source, info)
+		if prev, ok := old.Entries[key]; !ok || prev.Info != info {
+			changed = true
+		}
+		entries[key] = metricEntry{Source: source, Info: info, UpdatedAt: now}
+	}
+	// 旧数据中该来源被删除的条目
+	for key, e := range old.Entries {
+		if _, ok := entries[key]; !ok && e.Source == source {
+			changed = true
+		}
+	}
+
+	version := old.Version
+	if changed {
+		version++
+	}
+	ms.current.Store(&metricSnapshot{Version: version, UpdatedAt: now, Entries: entries})
+	return version
+}
+
+// Expire 移除now时刻已过期的条目，返回移除的条目数（有移除时版本加1）
+func (ms *metricStore) Expire(now time.Time) int {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	old := ms.current.Load()
+	entries := make(map[string]metricEntry, len(old.Entries))
+	for key, e := range old.Entries {
+		if entryState(e, now) != models.MetricExpired {
+			entries[key] = e
+		}
+	}
+	removed := len(old.Entries) - len(entries)
+	if removed > 0 {
+		ms.current.Store(&metricSnapshot{Version: old.Version + 1, UpdatedAt: now, Entries: entries})
+	}
+	return removed
+}
+
+// validatedSet 最近一次从Platform获取的已通过部署验证的实例
+type validatedSet struct {
+	containers map[string]bool // 容器名
+	instances  map[string]bool // CSCIID（用于过滤Site推送的实例）
+}
+
+var validated atomic.Pointer[validatedSet]
+
+func init() {
+	validated.Store(&validatedSet{containers: map[string]bool{}, instances: map[string]bool{}})
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"cmas-cats-go/models"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestMetricStoreConcurrent 多个来源并发替换、过期清理与无锁读取（配合go test -race运行）
+func TestMetricStoreConcurrent(t *testing.T) {
+	ms := newMetricStore()
+	sources := []string{sourcePoll, siteSource("cmas-site-1"), siteSource("cmas-site-2")}
+	const rounds = 200
+
+	var writers, readers sync.WaitGroup
+	stop := make(chan struct{})
+
+	for i, source := range sources {
+		writers.Add(1)
+		go func(i int, source string) {
+			defer writers.Done()
+			for r := 0; r < rounds; r++ {
+				infos := make([]models.ServiceInstanceInfo, 0, 3)
+				for n := 0; n < 1+r%3; n++ {
+					infos = append(infos, models.ServiceInstanceInfo{
+						ServiceID: fmt.Sprintf("S%d", n),
+						Gas:       r,
+						Cost:      i,
+						CSCIID:    fmt.Sprintf("10.0.0.%d:%d", n, 8000+i),
+						Delay:     r % 50,
+					})
+				}
+				// 隔轮保留本来源上一轮的实例，模拟采集失败时沿用旧数据
+				var keep map[string]bool
+				if r%2 == 1 {
+					keep = map[string]bool{fmt.Sprintf("10.0.0.2:%d", 8000+i): true}
+				}
+				ms.ReplaceSource(source, infos, keep)
+			}
+		}(i, source)
+	}
+
+	writers.Add(1)
+	go func() {
+		defer writers.Done()
+		for r := 0; r < rounds; r++ {
+			// 用未来的时间点清理，使部分条目真正被移除
+			ms.Expire(time.Now().Add(time.Duration(r%4) * time.Minute))
+		}
+	}()
+
+	for i := 0; i < 4; i++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			var last uint64
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				snap := ms.Snapshot()
+				if snap.Version < last {
+					t.Errorf("version went backwards: %d after %d", snap.Version, last)
+					return
+				}
+				last = snap.Version
+
+				now := time.Now()
+				byService := snap.ByService(nil, now)
+				freshness := snap.Freshness(nil, now)
+				total := 0
+				for _, list := range byService {
+					total += len(list)
+				}
+				if total != len(freshness) {
+					t.Errorf("ByService has %d instances, Freshness has %d", total, len(freshness))
+					return
+				}
+				if n := len(snap.List()); n != len(snap.Entries) {
+					t.Errorf("List has %d entries, snapshot has %d", n, len(snap.Entries))
+					return
+				}
+			}
+		}()
+	}
+
+	writers.Wait()
+	close(stop)
+	readers.Wait()
+
+	// 写入结束后重新提供各来源的数据，表中应恰好是最后一轮的内容
+	for i, source := range sources {
+		ms.ReplaceSource(source, []models.ServiceInstanceInfo{
+			{ServiceID: "S0", Gas: 1, Cost: i, CSCIID: fmt.Sprintf("10.0.0.0:%d", 8000+i)},
+		}, nil)
+	}
+	snap := ms.Snapshot()
+	if len(snap.Entries) != len(sources) {
+		t.Fatalf("entries = %d, want %d: %v", len(snap.Entries), len(sources), snap.List())
+	}
+	if n := ms.Expire(time.Now()); n != 0 {
+		t.Errorf("Expire removed %d fresh entries", n)
+	}
+}
@@ -6,13 +6,21 @@ import (
     "encoding/json"
     "fmt"
     "net/http"
+    "os"
     "strings"
 )
 
 // 1. 向Platform注册服务（通用函数）
+// 提供者API Key从环境变量CMAS_API_KEY读取
 func registerService(service models.Service) (string, error) {
     jsonData, _ := json.Marshal(service)
-    resp, err := http.Post(config.Cfg.Platform.URL+"/api/v1/services", "application/json", strings.NewReader(string(jsonData)))
+    httpReq, err := http.NewRequest("POST", config.Cfg.Platform.URL+"/api/v1/services", strings.NewReader(string(jsonData)))
+    if err != nil {
+        return "", err
+    }
+    httpReq.Header.Set("Content-Type", "application/json")
+    httpReq.Header.Set("X-API-Key", os.Getenv("CMAS_API_KEY"))
+    resp, err := http.DefaultClient.Do(httpReq)
     if err != nil {
         return "", err
     }
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+)
+
+// adminTokenEnv 管理员令牌环境变量，未设置时所有管理接口均拒绝访问
+const adminTokenEnv = "CMAS_ADMIN_TOKEN"
+
+// adminOnly 管理接口中间件：校验请求头 X-Admin-Token
+func adminOnly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := os.Getenv(adminTokenEnv)
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"success": false, "msg": "管理接口未启用（未配置" + adminTokenEnv + "）"})
+			return
+		}
+		given := c.GetHeader("X-Admin-Token")
+		if subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"success": false, "msg": "管理员令牌无效"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// ctxAdminKey 请求携带有效管理员令牌时写入上下文
+const ctxAdminKey = "cmas.admin"
+
+// isAdminRequest 请求是否携带有效的管理员令牌
+func isAdminRequest(c *gin.Context) bool {
+	token := os.Getenv(adminTokenEnv)
+	return token != "" && subtle.ConstantTimeCompare([]byte(c.GetHeader("X-Admin-Token")), []byte(token)) == 1
+}
+
+// adminOr 携带有效管理员令牌的请求直接放行，否则交给next认证
+func adminOr(next gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if isAdminRequest(c) {
+			c.Set(ctxAdminKey, true)
+			c.Next()
+			return
+		}
+		next(c)
+	}
+}
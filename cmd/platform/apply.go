@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"cmas-cats-go/models"
+	"cmas-cats-go/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// applicationTable Site申请记录表（记录哪些Site部署了哪些服务）
+type applicationTable struct {
+	mu      sync.RWMutex
+	store   storage.Store
+	seq     int
+	records []models.Application
+}
+
+// newApplicationTable 创建申请记录表并从存储加载（ID序号从已有记录的最大值继续）
+func newApplicationTable(store storage.Store) (*applicationTable, error) {
+	records, err := storage.LoadAll[models.Application](store, storage.BucketApplications)
+	if err != nil {
+		return nil, fmt.Errorf("加载申请记录失败：%v", err)
+	}
+	at := &applicationTable{store: store}
+	for _, app := range records {
+		var n int
+		if _, err := fmt.Sscanf(app.ID, "app-%d", &n); err == nil && n > at.seq {
+			at.seq = n
+		}
+		at.records = append(at.records, app)
+	}
+	return at, nil
+}
+
+// Add 追加申请记录并分配ID
+func (at *applicationTable) Add(app models.Application) (models.Application, error) {
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	at.seq++
+	app.ID = fmt.Sprintf("app-%d", at.seq)
+	app.AppliedAt = time.Now()
+	if err := at.store.Put(storage.BucketApplications, app.ID, app); err != nil {
+		return app, fmt.Errorf("保存申请记录失败：%v", err)
+	}
+	at.records = append(at.records, app)
+	return app, nil
+}
+
+// List 按服务ID/SiteID筛选申请记录（空值表示不过滤），按申请时间倒序
+func (at *applicationTable) List(serviceID, siteID string) []models.Application {
+	at.mu.RLock()
+	defer at.mu.RUnlock()
+	result := make([]models.Application, 0, len(at.records))
+	for _, app := range at.records {
+		if (serviceID == "" || app.ServiceID == serviceID) && (siteID == "" || app.SiteID == siteID) {
+			result = append(result, app)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].AppliedAt.After(result[j].AppliedAt) })
+	return result
+}
+
+// fileDigest 计算文件SHA-256与大小
+func fileDigest(path string) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), n, nil
+}
+
+// registerApplyRoutes 注册Site的Apply接口
+// Apply(service id)返回运行代码、数据样本、计算/存储要求、计算时间与软件依赖（草案Figure 2）
+// 预期结果仍保存在私有样本表中，不下发给Site；代码包与数据样本只提供给已认证的Site或管理员，
+// 认证关闭时只能用管理员令牌下载
+func registerApplyRoutes(r *gin.Engine, registry *serviceRegistry, samples *sampleTable, apps *applicationTable, keys *keyTable, auth *authenticator) {
+	api := r.Group("/api/v1/apply")
+	private := adminOr(auth.requirePrivate(models.RoleSite))
+
+	// 申请部署服务：返回部署包描述并记录申请
+	api.POST("/:id", auth.require(models.RoleSite), func(c *gin.Context) {
+		id := c.Param("id")
+		svc, ok := registry.Get(id)
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"success": false, "service_id": id, "msg": fmt.Sprintf("服务ID %s 不存在", id)})
+			return
+		}
+
+		code := gin.H{"code_location": svc.CodeLocation}
+		if svc.CodeBundle != "" {
+			digest, size, err := fileDigest(svc.CodeBundle)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"success": false, "service_id": id, "msg": "读取代码包失败：" + err.Error()})
+				return
+			}
+			code["download_url"] = fmt.Sprintf("/api/v1/apply/%s/bundle", id)
+			code["file_name"] = filepath.Base(svc.CodeBundle)
+			code["sha256"] = digest
+			code["size"] = size
+			// 签名随部署包下发，Site可用公钥独立校验代码包
+			if sig := svc.BundleSignature; sig != nil {
+				if err := verifyBundleFile(keys, svc.CodeBundle, sig); err != nil {
+					c.JSON(http.StatusConflict, gin.H{"success": false, "service_id": id, "msg": err.Error()})
+					return
+				}
+				key, _ := keys.Get(sig.KeyID)
+				code["signature"] = gin.H{
+					"digest":     sig.Digest,
+					"signature":  sig.Signature,
+					"key_id":     sig.KeyID,
+					"algorithm":  sig.Algorithm,
+					"public_key": key.PublicKey,
+					"signed_by":  sig.SignedBy,
+				}
+			}
+		}
+		var sample gin.H
+		if s, ok := samples.Get(id); ok {
+			sample = gin.H{
+				"download_url": fmt.Sprintf("/api/v1/apply/%s/sample", id),
+				"file_name":    s.SampleName,
+				"size":         len(s.Sample),
+			}
+		}
+
+		app := models.Application{ServiceID: id}
+		app.SiteID, app.SiteAuth = requestIdentity(c)
+		app, err := apps.Add(app)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "service_id": id, "msg": err.Error()})
+			return
+		}
+		fmt.Printf("[APPLY] Site %s 申请部署服务%s（%s）\n", app.SiteID, id, app.ID)
+
+		c.JSON(http.StatusOK, gin.H{
+			"success":        true,
+			"service_id":     id,
+			"application_id": app.ID,
+			"data": gin.H{
+				"service":               svc,
+				"code":                  code,
+				"sample":                sample,
+				"computing_requirement": svc.ComputingRequirement,
+				"storage_requirement":   svc.StorageRequirement,
+				"computing_time":        svc.ComputingTime,
+				"software_dependency":   svc.SoftwareDependency,
+			},
+			"msg": "申请成功",
+		})
+	})
+
+	// 下载代码包
+	api.GET("/:id/bundle", private, func(c *gin.Context) {
+		id := c.Param("id")
+		svc, ok := registry.Get(id)
+		if !ok || svc.CodeBundle == "" || !fileExists(svc.CodeBundle) {
+			c.JSON(http.StatusNotFound, gin.H{"success": false, "service_id": id, "msg": fmt.Sprintf("服务%s无可下载的代码包", id)})
+			return
+		}
+		c.FileAttachment(svc.CodeBundle, filepath.Base(svc.CodeBundle))
+	})
+
+	// 下载数据样本
+	api.GET("/:id/sample", private, func(c *gin.Context) {
+		id := c.Param("id")
+		s, ok := samples.Get(id)
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"success": false, "service_id": id, "msg": fmt.Sprintf("服务%s无数据样本", id)})
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", s.SampleName))
+		c.DataFromReader(http.StatusOK, int64(len(s.Sample)), "application/octet-stream", bytes.NewReader(s.Sample), nil)
+	})
+
+	// 查询申请记录（支持 service_id / site_id 过滤），任意已认证身份可查
+	r.GET("/api/v1/applications", auth.require(), func(c *gin.Context) {
+		records := apps.List(c.Query("service_id"), c.Query("site_id"))
+		c.JSON(http.StatusOK, gin.H{"success": true, "data": records, "total": len(records), "msg": "查询成功"})
+	})
+}
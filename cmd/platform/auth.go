@@ -0,0 +1,384 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"cmas-cats-go/config"
+	"cmas-cats-go/models"
+	"cmas-cats-go/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	authSecretEnv      = "CMAS_AUTH_SECRET" // 令牌签名密钥环境变量，未设置时每次启动随机生成
+	ctxIdentityKey     = "cmas.identity"    // gin上下文中的身份
+	ctxAuthResponseKey = "cmas.auth"        // gin上下文中的Auth_RESPONSE
+)
+
+// identityStore 提供者/Site身份表（按ID及API Key哈希索引）
+type identityStore struct {
+	mu     sync.RWMutex
+	store  storage.Store
+	byID   map[string]models.Identity
+	byHash map[string]string // API Key哈希 -> 身份ID
+}
+
+// newIdentityStore 创建身份表并从存储加载
+func newIdentityStore(store storage.Store) (*identityStore, error) {
+	identities, err := storage.LoadAll[models.Identity](store, storage.BucketIdentities)
+	if err != nil {
+		return nil, fmt.Errorf("加载身份表失败：%v", err)
+	}
+	is := &identityStore{
+		store:  store,
+		byID:   make(map[string]models.Identity, len(identities)),
+		byHash: make(map[string]string, len(identities)),
+	}
+	for _, ident := range identities {
+		is.byID[ident.ID] = ident
+		is.byHash[ident.APIKeyHash] = ident.ID
+	}
+	return is, nil
+}
+
+// hashAPIKey 计算API Key的SHA-256（身份表只保存哈希）
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Add 登记身份（ID已存在时报错）
+func (is *identityStore) Add(id models.Identity) error {
+	return is.put(id, false)
+}
+
+// put 写入身份，overwrite为true时覆盖同ID身份（预置身份文件以文件为准）
+func (is *identityStore) put(id models.Identity, overwrite bool) error {
+	if id.Role != models.RoleProvider && id.Role != models.RoleSite {
+		return fmt.Errorf("未知的身份角色：%s", id.Role)
+	}
+	is.mu.Lock()
+	defer is.mu.Unlock()
+	old, exists := is.byID[id.ID]
+	if exists && !overwrite {
+		return fmt.Errorf("身份%s已存在", id.ID)
+	}
+	if id.CreatedAt.IsZero() {
+		id.CreatedAt = time.Now()
+		if exists {
+			id.CreatedAt = old.CreatedAt
+		}
+	}
+	if err := is.store.Put(storage.BucketIdentities, id.ID, id); err != nil {
+		return fmt.Errorf("保存身份失败：%v", err)
+	}
+	if exists {
+		delete(is.byHash, old.APIKeyHash)
+	}
+	is.byID[id.ID] = id
+	is.byHash[id.APIKeyHash] = id.ID
+	return nil
+}
+
+// Get 按ID查询身份
+func (is *identityStore) Get(id string) (models.Identity, bool) {
+	is.mu.RLock()
+	defer is.mu.RUnlock()
+	ident, ok := is.byID[id]
+	return ident, ok
+}
+
+// Lookup 按API Key查询身份
+func (is *identityStore) Lookup(apiKey string) (models.Identity, bool) {
+	if apiKey == "" {
+		return models.Identity{}, false
+	}
+	is.mu.RLock()
+	defer is.mu.RUnlock()
+	id, ok := is.byHash[hashAPIKey(apiKey)]
+	if !ok {
+		return models.Identity{}, false
+	}
+	return is.byID[id], true
+}
+
+// Delete 删除身份（已签发的令牌随之失效）
+func (is *identityStore) Delete(id string) (bool, error) {
+	is.mu.Lock()
+	defer is.mu.Unlock()
+	ident, ok := is.byID[id]
+	if !ok {
+		return false, nil
+	}
+	if err := is.store.Delete(storage.BucketIdentities, id); err != nil {
+		return true, fmt.Errorf("删除身份失败：%v", err)
+	}
+	delete(is.byHash, ident.APIKeyHash)
+	delete(is.byID, id)
+	return true, nil
+}
+
+// List 列出全部身份
+func (is *identityStore) List() []models.Identity {
+	is.mu.RLock()
+	defer is.mu.RUnlock()
+	result := make([]models.Identity, 0, len(is.byID))
+	for _, ident := range is.byID {
+		result = append(result, ident)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result
+}
+
+// LoadFile 从预置身份文件加载身份（文件不存在时忽略）
+func (is *identityStore) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var file struct {
+		Identities []models.Identity `json:"identities"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("解析身份文件%s失败：%v", path, err)
+	}
+	for _, ident := range file.Identities {
+		if err := is.put(ident, true); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("[AUTH] 从%s加载%d个身份\n", path, len(file.Identities))
+	return nil
+}
+
+// tokenClaims 握手签发的令牌内容
+type tokenClaims struct {
+	Subject   string               `json:"sub"`            // 身份ID
+	Role      string               `json:"role"`           // 角色
+	ExpiresAt int64                `json:"exp"`            // 过期时间（Unix秒）
+	Auth      *models.AuthResponse `json:"auth,omitempty"` // 握手时的Auth_RESPONSE
+}
+
+// authenticator 认证器：API Key握手换取签名令牌，中间件校验令牌/API Key
+type authenticator struct {
+	identities *identityStore
+	secret     []byte
+}
+
+func newAuthenticator(identities *identityStore) *authenticator {
+	secret := []byte(os.Getenv(authSecretEnv))
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		rand.Read(secret)
+		fmt.Printf("[AUTH] 未设置%s，使用随机签名密钥（重启后令牌失效）\n", authSecretEnv)
+	}
+	return &authenticator{identities: identities, secret: secret}
+}
+
+// sign 签发令牌：base64url(claims).base64url(HMAC-SHA256)
+func (a *authenticator) sign(claims tokenClaims) string {
+	payload, _ := json.Marshal(claims)
+	p := base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, a.secret)
+	mac.Write([]byte(p))
+	return p + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// verify 校验令牌签名与有效期
+func (a *authenticator) verify(token string) (tokenClaims, error) {
+	var claims tokenClaims
+	p, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return claims, fmt.Errorf("令牌格式错误")
+	}
+	mac := hmac.New(sha256.New, a.secret)
+	mac.Write([]byte(p))
+	given, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(given, mac.Sum(nil)) {
+		return claims, fmt.Errorf("令牌签名无效")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(p)
+	if err != nil || json.Unmarshal(payload, &claims) != nil {
+		return claims, fmt.Errorf("令牌内容无效")
+	}
+	if time.Now().Unix() > claims.ExpiresAt {
+		return claims, fmt.Errorf("令牌已过期")
+	}
+	return claims, nil
+}
+
+// authenticate 解析请求身份：优先Bearer令牌，其次X-API-Key
+func (a *authenticator) authenticate(c *gin.Context) (models.Identity, *models.AuthResponse, error) {
+	if bearer, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok {
+		claims, err := a.verify(strings.TrimSpace(bearer))
+		if err != nil {
+			return models.Identity{}, nil, err
+		}
+		ident, ok := a.identities.Get(claims.Subject)
+		if !ok {
+			return models.Identity{}, nil, fmt.Errorf("身份%s不存在", claims.Subject)
+		}
+		return ident, claims.Auth, nil
+	}
+	if key := c.GetHeader("X-API-Key"); key != "" {
+		ident, ok := a.identities.Lookup(key)
+		if !ok {
+			return models.Identity{}, nil, fmt.Errorf("API Key无效")
+		}
+		return ident, nil, nil
+	}
+	return models.Identity{}, nil, fmt.Errorf("缺少认证信息（Authorization或X-API-Key）")
+}
+
+// require 认证中间件：校验身份及角色，并把身份与Auth_RESPONSE写入上下文
+// 认证关闭时直接放行（上下文中无身份）
+func (a *authenticator) require(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !config.Cfg.Auth.Enabled {
+			c.Next()
+			return
+		}
+		ident, auth, err := a.authenticate(c)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"success": false, "msg": "认证失败：" + err.Error()})
+			return
+		}
+		allowed := len(roles) == 0
+		for _, role := range roles {
+			if ident.Role == role {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"success": false, "msg": fmt.Sprintf("身份%s（%s）无权访问该接口", ident.ID, ident.Role)})
+			return
+		}
+		c.Set(ctxIdentityKey, ident)
+		if auth != nil {
+			c.Set(ctxAuthResponseKey, auth)
+		}
+		c.Next()
+	}
+}
+
+// requirePrivate 私有数据接口（代码包、数据样本）的认证中间件
+// 与require相同，但认证关闭时拒绝访问而不是放行，避免未认证的请求下载私有数据
+func (a *authenticator) requirePrivate(roles ...string) gin.HandlerFunc {
+	check := a.require(roles...)
+	return func(c *gin.Context) {
+		if !config.Cfg.Auth.Enabled {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"success": false, "msg": "未启用身份认证（CMAS_AUTH_ENABLED），该接口仅限管理员令牌访问"})
+			return
+		}
+		check(c)
+	}
+}
+
+// requestIdentity 取出中间件写入的身份ID与Auth_RESPONSE（未认证时为空）
+func requestIdentity(c *gin.Context) (string, *models.AuthResponse) {
+	var id string
+	if v, ok := c.Get(ctxIdentityKey); ok {
+		id = v.(models.Identity).ID
+	}
+	var auth *models.AuthResponse
+	if v, ok := c.Get(ctxAuthResponseKey); ok {
+		auth = v.(*models.AuthResponse)
+	}
+	return id, auth
+}
+
+// registerAuthRoutes 注册认证接口
+// 握手流程：Registration/Apply → Authentication（携带API Key）→ Auth_RESPONSE（回复IP/域名/主机/端口/参数）→ 签发令牌
+func registerAuthRoutes(r *gin.Engine, a *authenticator) {
+	// 认证握手：X-API-Key + Auth_RESPONSE换取签名令牌
+	r.POST("/api/v1/auth/token", func(c *gin.Context) {
+		ident, ok := a.identities.Lookup(c.GetHeader("X-API-Key"))
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"success": false, "msg": "认证失败：API Key无效"})
+			return
+		}
+		var auth models.AuthResponse
+		if c.Request.ContentLength > 0 {
+			if err := c.ShouldBindJSON(&auth); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"success": false, "msg": "Auth_RESPONSE解析失败：" + err.Error()})
+				return
+			}
+		}
+		if auth.IP == "" {
+			auth.IP = c.ClientIP()
+		}
+		expiresAt := time.Now().Add(config.Cfg.Auth.TokenTTL)
+		token := a.sign(tokenClaims{
+			Subject:   ident.ID,
+			Role:      ident.Role,
+			ExpiresAt: expiresAt.Unix(),
+			Auth:      &auth,
+		})
+		fmt.Printf("[AUTH] 身份%s（%s）握手成功，来自%s\n", ident.ID, ident.Role, auth.IP)
+		c.JSON(http.StatusOK, gin.H{
+			"success":    true,
+			"token":      token,
+			"expires_at": expiresAt,
+			"identity":   ident.ID,
+			"role":       ident.Role,
+			"msg":        "认证成功",
+		})
+	})
+
+	// 身份管理（仅管理员）：登记身份时生成API Key，明文只返回一次
+	admin := r.Group("/api/admin/identities", adminOnly())
+	admin.GET("", func(c *gin.Context) {
+		identities := a.identities.List()
+		c.JSON(http.StatusOK, gin.H{"success": true, "data": identities, "total": len(identities), "msg": "查询成功"})
+	})
+	admin.POST("", func(c *gin.Context) {
+		var req struct {
+			ID   string `json:"id"`
+			Role string `json:"role"`
+			Name string `json:"name"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil || req.ID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "msg": "参数错误：身份ID不能为空"})
+			return
+		}
+		raw := make([]byte, 24)
+		rand.Read(raw)
+		apiKey := "cmas_" + hex.EncodeToString(raw)
+		ident := models.Identity{ID: req.ID, Role: req.Role, Name: req.Name, APIKeyHash: hashAPIKey(apiKey)}
+		if err := a.identities.Add(ident); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "msg": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"success": true, "identity": req.ID, "api_key": apiKey, "msg": "身份登记成功，请妥善保存API Key"})
+	})
+	admin.DELETE("/:id", func(c *gin.Context) {
+		id := c.Param("id")
+		found, err := a.identities.Delete(id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "msg": err.Error()})
+			return
+		}
+		if !found {
+			c.JSON(http.StatusNotFound, gin.H{"success": false, "msg": fmt.Sprintf("身份%s不存在", id)})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"success": true, "identity": id, "msg": "身份已删除"})
+	})
+}
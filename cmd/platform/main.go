@@ -5,13 +5,14 @@ import (
 	"fmt"
 	"net"
 	"os"
-	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"cmas-cats-go/config"
+	"cmas-cats-go/models"
 	"cmas-cats-go/utils"
 	"os/exec"
 )
@@ -21,17 +22,33 @@ const (
 	networkSubnet   = "172.18.0.0/16" // cmas-network子网
 	basePort        = 5000            // 宿主机端口起始值
 	networkName     = "cmas-network"
+	serviceImage    = "cmas-service:v1" // 服务容器基础镜像
 )
 
 func main() {
 	gin.SetMode(gin.DebugMode)
 	r := gin.Default()
 
+	// 加载持久化状态：公共服务表（草案Table 1）、私有样本表（草案Table 2）、部署验证、身份等
+	state, err := openPlatformState()
+	if err != nil {
+		fmt.Printf("Platform状态加载失败：%s\n", err.Error())
+		os.Exit(1)
+	}
+	defer state.store.Close()
+	registry, samples, validations := state.registry, state.samples, state.validations
+
+	// 提供者/Site身份认证
+	if err := state.identities.LoadFile(config.Cfg.Auth.IdentityFile); err != nil {
+		fmt.Printf("[AUTH] 加载身份文件失败：%v\n", err)
+	}
+	auth := newAuthenticator(state.identities)
+
 	// 跨域配置
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"*"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-API-Key", "X-Admin-Token"},
 		ExposeHeaders:    []string{"Content-Length"},
 		AllowCredentials: true,
 	}))
@@ -58,6 +75,9 @@ func main() {
 
 	// 2. 获取可用参数接口（核心：自动检测未使用的ID/IP/端口）
 	r.GET("/api/get-available-params", func(c *gin.Context) {
+		// 释放容器已删除的租约
+		state.leases.Prune(isContainerExist)
+
 		// 检测可用ServiceID（S1→S2→S3→...）
 		availableServiceID, err := getAvailableServiceID()
 		if err != nil {
@@ -66,14 +86,14 @@ func main() {
 		}
 
 		// 检测可用容器IP（172.18.0.2→172.18.0.3→...）
-		availableIP, err := getAvailableContainerIP()
+		availableIP, err := getAvailableContainerIP(state.leases)
 		if err != nil {
 			c.JSON(500, gin.H{"error": "检测可用容器IP失败：" + err.Error()})
 			return
 		}
 
 		// 检测可用宿主机端口（5000→5001→...）
-		availablePort, err := getAvailableHostPort()
+		availablePort, err := getAvailableHostPort(state.leases)
 		if err != nil {
 			c.JSON(500, gin.H{"error": "检测可用宿主机端口失败：" + err.Error()})
 			return
@@ -87,95 +107,10 @@ func main() {
 	})
 
 	// 3. 代码上传接口
-	r.POST("/api/upload/code", func(c *gin.Context) {
-		file, err := c.FormFile("codeFile")
-		if err != nil {
-			c.JSON(400, gin.H{"error": "上传文件失败：" + err.Error()})
-			return
-		}
-
-		fileName := filepath.Base(file.Filename)
-		savePath := filepath.Join(tempCodeDir, fileName)
-		if err := c.SaveUploadedFile(file, savePath); err != nil {
-			c.JSON(500, gin.H{"error": "保存文件失败：" + err.Error()})
-			return
-		}
-
-		// 调试日志：打印文件名和解压路径
-		fmt.Printf("上传的文件名: %s\n", fileName)
-		fmt.Printf("保存路径: %s\n", savePath)
-
-		// 解压到 temp 文件夹
-		unzipPath := "temp/unzipped/"
-		os.MkdirAll(unzipPath, os.ModePerm)
-		cmd := exec.Command("unzip", "-o", savePath, "-d", unzipPath)
-		output, err := cmd.CombinedOutput()
-		fmt.Printf("解压命令输出: %s\n", string(output))
-		if err != nil {
-			fmt.Printf("解压文件失败: %s, 输出: %s\n", err.Error(), string(output))
-			c.JSON(500, gin.H{"error": "解压文件失败: " + err.Error()})
-			return
-		}
-
-		// 检查 requirements.txt 是否存在
-		requirementsPath := filepath.Join(unzipPath, "requirements.txt")
-		if _, err := os.Stat(requirementsPath); os.IsNotExist(err) {
-			fmt.Printf("未找到 requirements.txt 文件\n")
-			c.JSON(400, gin.H{"error": "未找到 requirements.txt 文件"})
-			return
-		}
-
-		// 调试日志：列出解压后的文件
-		files, err := os.ReadDir(unzipPath)
-		if err != nil {
-			fmt.Printf("读取解压目录失败: %s\n", err.Error())
-			c.JSON(500, gin.H{"error": "读取解压目录失败: " + err.Error()})
-			return
-		}
-		fmt.Println("解压后的文件列表:")
-		for _, file := range files {
-			fmt.Println(file.Name())
-		}
-
-		// 安全检查
-		securityResult := utils.CheckModelSecurity(unzipPath)
-		if !securityResult.Pass {
-			os.RemoveAll(unzipPath)
-			c.JSON(403, gin.H{
-				"error":  "模型安全评估不通过，禁止上传",
-				"reason": securityResult.Reason,
-				"threats": securityResult.Threats,
-			})
-			return
-		}
-
-		// 自动选择最佳路径
-		bestServiceID, err := getAvailableServiceID()
-		if err != nil {
-			c.JSON(500, gin.H{"error": "无法获取最佳服务路径: " + err.Error()})
-			return
-		}
-
-		bestPath := filepath.Join("services", strings.ToLower(bestServiceID)+"_service")
-		if err := os.MkdirAll(bestPath, 0777); err != nil {
-			c.JSON(500, gin.H{"error": "无法创建最佳路径: " + err.Error()})
-			return
-		}
-
-		finalPath := filepath.Join(bestPath, fileName)
-		if err := os.Rename(savePath, finalPath); err != nil {
-			c.JSON(500, gin.H{"error": "无法移动文件到最佳路径: " + err.Error()})
-			return
-		}
-
-		c.JSON(200, gin.H{
-			"msg":      "文件已上传到最佳路径",
-			"bestPath": finalPath,
-		})
-	})
+	r.POST("/api/upload/code", auth.require(models.RoleProvider), handleCodeUpload(state))
 
 	// 4. 创建容器接口
-	r.POST("/api/docker/create", func(c *gin.Context) {
+	r.POST("/api/docker/create", auth.require(models.RoleProvider, models.RoleSite), func(c *gin.Context) {
 		type CreateReq struct {
 			ServiceID   string `json:"serviceID"`
 			ContainerIP string `json:"containerIP"`
@@ -209,15 +144,16 @@ func main() {
 			return
 		}
 
-		// 执行docker run
-		dockerRunCmd := exec.Command("docker", "run", "-d",
+		// 执行docker run（带Site标签，C-SMA据此发现该实例）
+		runArgs := []string{"run", "-d",
 			"--name", containerName,
 			"--network", networkName,
 			"--ip", req.ContainerIP,
 			"-p", fmt.Sprintf("%s:5000", req.HostPort),
 			"-v", fmt.Sprintf("%s:/app/uploads", req.UploadDir),
-			"cmas-service:v1",
-		)
+		}
+		runArgs = append(runArgs, utils.SiteLabels([]string{req.ServiceID}, 5000)...)
+		dockerRunCmd := exec.Command("docker", append(runArgs, serviceImage)...)
 		output, err := dockerRunCmd.CombinedOutput()
 		if err != nil {
 			c.JSON(500, gin.H{
@@ -228,6 +164,15 @@ func main() {
 
 		// 上传代码到容器
 		if req.CodePath != "" && fileExists(req.CodePath) {
+			// 复制前复核代码包签名（摘要一致、公钥未吊销）
+			svc, _ := registry.Get(req.ServiceID)
+			if err := verifyBundleFile(state.keys, req.CodePath, svc.BundleSignature); err != nil {
+				exec.Command("docker", "rm", "-f", containerName).Run()
+				c.JSON(403, gin.H{
+					"error": fmt.Sprintf("代码包签名复核失败，已回滚：%s", err.Error()),
+				})
+				return
+			}
 			dockerCpCmd := exec.Command("docker", "cp", req.CodePath, fmt.Sprintf("%s:/app/s_service.py", containerName))
 			cpOutput, err := dockerCpCmd.CombinedOutput()
 			if err != nil {
@@ -239,12 +184,45 @@ func main() {
 			}
 		}
 
+		// 记录IP/端口租约
+		if err := state.leases.Put(models.Lease{ContainerName: containerName, ContainerIP: req.ContainerIP, HostPort: req.HostPort}); err != nil {
+			fmt.Printf("记录容器%s租约失败：%v\n", containerName, err)
+		}
+
+		// 部署验证：通过前C-SMA不会采集该实例
+		deployment := models.InstanceValidation{
+			ServiceID:     req.ServiceID,
+			ContainerName: containerName,
+			CSCIID:        fmt.Sprintf("%s:5000", req.ContainerIP),
+		}
+		deployment.DeployedBy, deployment.DeployerAuth = requestIdentity(c)
+		validation := validations.ValidateAsync(deployment)
+
 		c.JSON(200, gin.H{
-			"msg": fmt.Sprintf("容器%s创建成功，ID：%s", containerName, strings.TrimSpace(string(output))),
+			"msg":        fmt.Sprintf("容器%s创建成功，ID：%s", containerName, strings.TrimSpace(string(output))),
+			"validation": validation.Status,
 		})
 	})
 
-	// 5. 健康检查
+	// 5. 公共服务表接口（草案Table 1）、私有样本表管理接口（草案Table 2）、部署验证接口
+	registerServiceRoutes(r, registry, samples, auth)
+	registerSampleAdminRoutes(r, registry, samples)
+	registerValidationRoutes(r, validations, auth)
+	registerAuthRoutes(r, auth)
+	registerApplyRoutes(r, registry, samples, state.applications, state.keys, auth)
+	registerSuppressionRoutes(r, registry, state.suppressions, auth)
+	registerSecurityAdminRoutes(r, state.rules, state.suppressions)
+	registerScanRoutes(r, state.scans, state.rules, auth)
+	registerKeyRoutes(r, state.keys, auth)
+	registerSBOMRoutes(r, state.uploads, auth)
+
+	// 代码上传记录查询（支持 service_id 过滤）
+	r.GET("/api/v1/uploads", auth.require(), func(c *gin.Context) {
+		records := state.uploads.List(c.Query("service_id"))
+		c.JSON(200, gin.H{"success": true, "data": records, "total": len(records), "msg": "查询成功"})
+	})
+
+	// 6. 健康检查
 	r.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{
 			"status": "ok",
@@ -252,93 +230,8 @@ func main() {
 		})
 	})
 
-	// 6. 代码检查接口
-	r.POST("/api/check/code", func(c *gin.Context) {
-		file, err := c.FormFile("codeFile")
-		if err != nil {
-			c.JSON(400, gin.H{"error": "上传文件失败: " + err.Error()})
-			return
-		}
-
-		fileName := filepath.Base(file.Filename)
-		savePath := filepath.Join(tempCodeDir, fileName)
-		if err := c.SaveUploadedFile(file, savePath); err != nil {
-			c.JSON(500, gin.H{"error": "保存文件失败: " + err.Error()})
-			return
-		}
-
-		// 调试日志：打印文件名和解压路径
-		fmt.Printf("上传的文件名: %s\n", fileName)
-		fmt.Printf("保存路径: %s\n", savePath)
-
-		// 解压到 temp 文件夹
-		unzipPath := "temp/unzipped/"
-		os.MkdirAll(unzipPath, os.ModePerm)
-		cmd := exec.Command("unzip", "-o", savePath, "-d", unzipPath)
-		output, err := cmd.CombinedOutput()
-		fmt.Printf("解压命令输出: %s\n", string(output))
-		if err != nil {
-			fmt.Printf("解压文件失败: %s, 输出: %s\n", err.Error(), string(output))
-			c.JSON(500, gin.H{"error": "解压文件失败: " + err.Error()})
-			return
-		}
-
-		// 检查 requirements.txt 是否存在
-		requirementsPath := filepath.Join(unzipPath, "requirements.txt")
-		if _, err := os.Stat(requirementsPath); os.IsNotExist(err) {
-			fmt.Printf("未找到 requirements.txt 文件\n")
-			c.JSON(400, gin.H{"error": "未找到 requirements.txt 文件"})
-			return
-		}
-
-		// 调试日志：列出解压后的文件
-		files, err := os.ReadDir(unzipPath)
-		if err != nil {
-			fmt.Printf("读取解压目录失败: %s\n", err.Error())
-			c.JSON(500, gin.H{"error": "读取解压目录失败: " + err.Error()})
-			return
-		}
-		fmt.Println("解压后的文件列表:")
-		for _, file := range files {
-			fmt.Println(file.Name())
-		}
-
-		// 安全检查
-		securityResult := utils.CheckModelSecurity(unzipPath)
-		if !securityResult.Pass {
-			os.RemoveAll(unzipPath)
-			c.JSON(403, gin.H{
-				"error":  "模型安全评估不通过，禁止上传",
-				"reason": securityResult.Reason,
-				"threats": securityResult.Threats,
-			})
-			return
-		}
-
-		// 自动选择最佳路径
-		bestServiceID, err := getAvailableServiceID()
-		if err != nil {
-			c.JSON(500, gin.H{"error": "无法获取最佳服务路径: " + err.Error()})
-			return
-		}
-
-		bestPath := filepath.Join("services", strings.ToLower(bestServiceID)+"_service")
-		if err := os.MkdirAll(bestPath, 0777); err != nil {
-			c.JSON(500, gin.H{"error": "无法创建最佳路径: " + err.Error()})
-			return
-		}
-
-		finalPath := filepath.Join(bestPath, fileName)
-		if err := os.Rename(savePath, finalPath); err != nil {
-			c.JSON(500, gin.H{"error": "无法移动文件到最佳路径: " + err.Error()})
-			return
-		}
-
-		c.JSON(200, gin.H{
-			"msg":      "文件已上传到最佳路径",
-			"bestPath": finalPath,
-		})
-	})
+	// 7. 代码检查接口
+	r.POST("/api/check/code", auth.require(models.RoleProvider), handleCodeUpload(state))
 
 
 	// 启动服务（8081端口）
@@ -382,7 +275,7 @@ func getAvailableServiceID() (string, error) {
 }
 
 // 检测可用容器IP（172.18.0.2开始，跳过网关172.18.0.1）
-func getAvailableContainerIP() (string, error) {
+func getAvailableContainerIP(leases *leaseTable) (string, error) {
 	// 获取cmas-network中已使用的IP
 	cmd := exec.Command("docker", "network", "inspect", networkName)
 	output, err := cmd.CombinedOutput()
@@ -405,6 +298,10 @@ func getAvailableContainerIP() (string, error) {
 		}
 	}
 	usedIPs["172.18.0.1"] = true // 跳过网关IP
+	leasedIPs, _ := leases.Used()
+	for ip := range leasedIPs {
+		usedIPs[ip] = true // 跳过已分配但容器尚未加入网络的IP
+	}
 
 	// 遍历子网找可用IP
 	_, subnet, err := net.ParseCIDR(networkSubnet)
@@ -428,7 +325,7 @@ func getAvailableContainerIP() (string, error) {
 }
 
 // 检测可用宿主机端口（从5000开始）
-func getAvailableHostPort() (int, error) {
+func getAvailableHostPort(leases *leaseTable) (int, error) {
 	// 获取已占用的端口
 	cmd := exec.Command("docker", "ps", "--format", "{{.Ports}}")
 	output, err := cmd.CombinedOutput()
@@ -451,6 +348,13 @@ func getAvailableHostPort() (int, error) {
 		}
 	}
 
+	_, leasedPorts := leases.Used()
+	for p := range leasedPorts {
+		if port, err := strconv.Atoi(p); err == nil {
+			usedPorts[port] = true
+		}
+	}
+
 	// 从basePort开始找未使用的端口
 	for port := basePort; ; port++ {
 		if !usedPorts[port] {
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"cmas-cats-go/models"
+	"cmas-cats-go/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// leaseTable 容器IP/宿主机端口租约表
+// 记录Platform分配出去的资源，避免容器尚未启动时被重复分配
+type leaseTable struct {
+	mu     sync.RWMutex
+	store  storage.Store
+	leases map[string]models.Lease
+}
+
+// newLeaseTable 创建租约表并从存储加载
+func newLeaseTable(store storage.Store) (*leaseTable, error) {
+	leases, err := storage.LoadAll[models.Lease](store, storage.BucketLeases)
+	if err != nil {
+		return nil, fmt.Errorf("加载租约表失败：%v", err)
+	}
+	return &leaseTable{store: store, leases: leases}, nil
+}
+
+// Put 记录容器租约
+func (lt *leaseTable) Put(l models.Lease) error {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+	l.CreatedAt = time.Now()
+	if err := lt.store.Put(storage.BucketLeases, l.ContainerName, l); err != nil {
+		return fmt.Errorf("保存租约失败：%v", err)
+	}
+	lt.leases[l.ContainerName] = l
+	return nil
+}
+
+// Used 返回已租出的IP与端口
+func (lt *leaseTable) Used() (ips map[string]bool, ports map[string]bool) {
+	lt.mu.RLock()
+	defer lt.mu.RUnlock()
+	ips, ports = make(map[string]bool), make(map[string]bool)
+	for _, l := range lt.leases {
+		ips[l.ContainerIP] = true
+		ports[l.HostPort] = true
+	}
+	return ips, ports
+}
+
+// Prune 释放容器已不存在的租约
+func (lt *leaseTable) Prune(exists func(containerName string) bool) {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+	for name := range lt.leases {
+		if exists(name) {
+			continue
+		}
+		if err := lt.store.Delete(storage.BucketLeases, name); err != nil {
+			fmt.Printf("释放容器%s租约失败：%v\n", name, err)
+			continue
+		}
+		delete(lt.leases, name)
+		fmt.Printf("容器%s已不存在，释放租约\n", name)
+	}
+}
+
+// uploadTable 代码上传记录表
+type uploadTable struct {
+	mu      sync.Mutex
+	store   storage.Store
+	seq     int
+	records map[string]models.UploadRecord
+}
+
+// newUploadTable 创建上传记录表并从存储加载（ID序号从已有记录的最大值继续）
+func newUploadTable(store storage.Store) (*uploadTable, error) {
+	records, err := storage.LoadAll[models.UploadRecord](store, storage.BucketUploads)
+	if err != nil {
+		return nil, fmt.Errorf("加载上传记录失败：%v", err)
+	}
+	ut := &uploadTable{store: store, records: records}
+	for id := range records {
+		var n int
+		if _, err := fmt.Sscanf(id, "upload-%d", &n); err == nil && n > ut.seq {
+			ut.seq = n
+		}
+	}
+	return ut, nil
+}
+
+// Reserve 预先分配上传ID（通过检查的代码包按上传ID分目录保存，需要在移动文件前确定ID）
+func (ut *uploadTable) Reserve() string {
+	ut.mu.Lock()
+	defer ut.mu.Unlock()
+	ut.seq++
+	return fmt.Sprintf("upload-%d", ut.seq)
+}
+
+// Add 追加上传记录，未通过Reserve预先分配ID时分配ID（持久化失败只记录日志，不影响上传结果）
+func (ut *uploadTable) Add(rec models.UploadRecord) models.UploadRecord {
+	ut.mu.Lock()
+	defer ut.mu.Unlock()
+	if rec.ID == "" {
+		ut.seq++
+		rec.ID = fmt.Sprintf("upload-%d", ut.seq)
+	}
+	rec.CreatedAt = time.Now()
+	if err := ut.store.Put(storage.BucketUploads, rec.ID, rec); err != nil {
+		fmt.Printf("保存上传记录%s失败：%v\n", rec.ID, err)
+	}
+	ut.records[rec.ID] = rec
+	return rec
+}
+
+// List 按服务ID筛选上传记录（空值表示不过滤），按上传时间倒序
+func (ut *uploadTable) List(serviceID string) []models.UploadRecord {
+	ut.mu.Lock()
+	defer ut.mu.Unlock()
+	result := make([]models.UploadRecord, 0, len(ut.records))
+	for _, rec := range ut.records {
+		if serviceID == "" || rec.ServiceID == serviceID {
+			result = append(result, rec)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].CreatedAt.After(result[j].CreatedAt) })
+	return result
+}
+
+// newUploadRecord 根据已保存的上传文件生成上传记录（结论由调用方填写）
+func newUploadRecord(c *gin.Context, fileName, savedPath string) models.UploadRecord {
+	rec := models.UploadRecord{FileName: fileName}
+	rec.UploadedBy, _ = requestIdentity(c)
+	rec.SHA256, rec.Size, _ = fileDigest(savedPath)
+	return rec
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"cmas-cats-go/models"
+	"cmas-cats-go/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	sampleFormField   = "validation_sample" // 样本文件表单字段
+	resultFormField   = "validation_result" // 预期结果文件表单字段
+	maxSampleFileSize = 32 << 20            // 单个样本/结果文件上限（32MB）
+)
+
+// sampleTable 私有数据样本/预期结果表（草案Table 2）
+// 只在Platform内部使用（部署验证），不经公共服务表接口返回
+type sampleTable struct {
+	mu      sync.RWMutex
+	store   storage.Store
+	samples map[string]models.ServiceSample
+}
+
+// newSampleTable 创建样本表并从存储加载
+func newSampleTable(store storage.Store) (*sampleTable, error) {
+	samples, err := storage.LoadAll[models.ServiceSample](store, storage.BucketSamples)
+	if err != nil {
+		return nil, fmt.Errorf("加载样本表失败：%v", err)
+	}
+	return &sampleTable{store: store, samples: samples}, nil
+}
+
+// Put 写入（或整体替换）某服务的样本与预期结果
+func (st *sampleTable) Put(s models.ServiceSample) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	s.UpdatedAt = time.Now()
+	if err := st.store.Put(storage.BucketSamples, s.ServiceID, s); err != nil {
+		return fmt.Errorf("保存样本失败：%v", err)
+	}
+	st.samples[s.ServiceID] = s
+	return nil
+}
+
+// Get 查询某服务的样本与预期结果
+func (st *sampleTable) Get(serviceID string) (models.ServiceSample, bool) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	s, ok := st.samples[serviceID]
+	return s, ok
+}
+
+// Rotate 轮换样本/预期结果，传入nil的部分保持不变
+func (st *sampleTable) Rotate(serviceID string, sample, result *sampleFile) (models.ServiceSample, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	s, ok := st.samples[serviceID]
+	if !ok {
+		if sample == nil || result == nil {
+			return s, fmt.Errorf("服务%s尚无样本记录，需同时提供样本和预期结果", serviceID)
+		}
+		s.ServiceID = serviceID
+	}
+	if sample != nil {
+		s.SampleName, s.Sample = sample.Name, sample.Data
+	}
+	if result != nil {
+		s.ResultName, s.Result = result.Name, result.Data
+	}
+	s.UpdatedAt = time.Now()
+	if err := st.store.Put(storage.BucketSamples, serviceID, s); err != nil {
+		return s, fmt.Errorf("保存样本失败：%v", err)
+	}
+	st.samples[serviceID] = s
+	return s, nil
+}
+
+// Delete 删除某服务的样本记录（服务删除时调用）
+func (st *sampleTable) Delete(serviceID string) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if err := st.store.Delete(storage.BucketSamples, serviceID); err != nil {
+		return fmt.Errorf("删除样本失败：%v", err)
+	}
+	delete(st.samples, serviceID)
+	return nil
+}
+
+// sampleFile 从multipart表单读取的文件
+type sampleFile struct {
+	Name string
+	Data []byte
+}
+
+// readSampleFile 读取表单中的样本/结果文件，字段不存在时返回nil
+func readSampleFile(c *gin.Context, field string) (*sampleFile, error) {
+	fh, err := c.FormFile(field)
+	if err == http.ErrMissingFile {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取%s失败：%v", field, err)
+	}
+	if fh.Size > maxSampleFileSize {
+		return nil, fmt.Errorf("%s超过大小上限%dMB", field, maxSampleFileSize>>20)
+	}
+	f, err := fh.Open()
+	if err != nil {
+		return nil, fmt.Errorf("打开%s失败：%v", field, err)
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, maxSampleFileSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("读取%s失败：%v", field, err)
+	}
+	if len(data) > maxSampleFileSize {
+		return nil, fmt.Errorf("%s超过大小上限%dMB", field, maxSampleFileSize>>20)
+	}
+	return &sampleFile{Name: filepath.Base(fh.Filename), Data: data}, nil
+}
+
+// sampleInfo 样本记录摘要（只返回文件名/大小/哈希，不返回内容）
+func sampleInfo(s models.ServiceSample) gin.H {
+	digest := func(b []byte) string {
+		sum := sha256.Sum256(b)
+		return hex.EncodeToString(sum[:])
+	}
+	return gin.H{
+		"service_id":    s.ServiceID,
+		"sample_name":   s.SampleName,
+		"sample_size":   len(s.Sample),
+		"sample_sha256": digest(s.Sample),
+		"result_name":   s.ResultName,
+		"result_size":   len(s.Result),
+		"result_sha256": digest(s.Result),
+		"updated_at":    s.UpdatedAt,
+	}
+}
+
+// registerSampleAdminRoutes 注册样本表管理接口（仅管理员可用）
+func registerSampleAdminRoutes(r *gin.Engine, registry *serviceRegistry, samples *sampleTable) {
+	admin := r.Group("/api/admin/services", adminOnly())
+
+	// 查看样本摘要
+	admin.GET("/:id/samples", func(c *gin.Context) {
+		id := c.Param("id")
+		s, ok := samples.Get(id)
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"success": false, "service_id": id, "msg": fmt.Sprintf("服务%s无样本记录", id)})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"success": true, "service_id": id, "data": sampleInfo(s), "msg": "查询成功"})
+	})
+
+	// 轮换样本/预期结果（multipart：validation_sample / validation_result，至少提供一个）
+	admin.PUT("/:id/samples", func(c *gin.Context) {
+		id := c.Param("id")
+		if _, ok := registry.Get(id); !ok {
+			c.JSON(http.StatusNotFound, gin.H{"success": false, "service_id": id, "msg": fmt.Sprintf("服务ID %s 不存在", id)})
+			return
+		}
+		sample, err := readSampleFile(c, sampleFormField)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "service_id": id, "msg": err.Error()})
+			return
+		}
+		result, err := readSampleFile(c, resultFormField)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "service_id": id, "msg": err.Error()})
+			return
+		}
+		if sample == nil && result == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "service_id": id, "msg": "未提供样本或预期结果文件"})
+			return
+		}
+
+		s, err := samples.Rotate(id, sample, result)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "service_id": id, "msg": err.Error()})
+			return
+		}
+		if err := registry.SetValidationNames(id, s.SampleName, s.ResultName); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "service_id": id, "msg": err.Error()})
+			return
+		}
+		fmt.Printf("服务%s样本已轮换：sample=%s result=%s\n", id, s.SampleName, s.ResultName)
+		c.JSON(http.StatusOK, gin.H{"success": true, "service_id": id, "data": sampleInfo(s), "msg": "样本轮换成功"})
+	})
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"cmas-cats-go/models"
+	"cmas-cats-go/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sbomPath 上传记录对应的SBOM文件（与代码包同目录的sbom/<上传记录ID>.cdx.json）
+func sbomPath(rec models.UploadRecord) string {
+	return filepath.Join(filepath.Dir(rec.Path), "sbom", rec.ID+".cdx.json")
+}
+
+// writeSBOM 为通过检查的上传生成CycloneDX SBOM，以上传记录ID作为服务版本
+func writeSBOM(registry *serviceRegistry, rec models.UploadRecord, unzipPath string) error {
+	svc, _ := registry.Get(rec.ServiceID)
+	bom, err := utils.GenerateSBOM(utils.SBOMInput{
+		Root:               unzipPath,
+		ServiceID:          rec.ServiceID,
+		ServiceName:        svc.Name,
+		Version:            rec.ID,
+		BundleName:         rec.FileName,
+		BundleSHA256:       rec.SHA256,
+		SoftwareDependency: svc.SoftwareDependency,
+		BaseImage:          serviceImage,
+	})
+	if err != nil {
+		return err
+	}
+	path := sbomPath(rec)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	enc := json.NewEncoder(f)
+	enc.SetEscapeHTML(false) // 版本约束中的<、>原样输出
+	enc.SetIndent("", "  ")
+	return enc.Encode(bom)
+}
+
+// serviceSBOMs 服务已生成SBOM的版本（上传记录），按上传时间倒序
+func serviceSBOMs(uploads *uploadTable, serviceID string) []models.UploadRecord {
+	var result []models.UploadRecord
+	for _, rec := range uploads.List(serviceID) {
+		if rec.Pass && rec.Path != "" && fileExists(sbomPath(rec)) {
+			result = append(result, rec)
+		}
+	}
+	return result
+}
+
+// registerSBOMRoutes 注册服务SBOM查询与下载接口
+func registerSBOMRoutes(r *gin.Engine, uploads *uploadTable, auth *authenticator) {
+	// 服务的SBOM版本列表
+	r.GET("/api/v1/services/:id/sboms", auth.require(), func(c *gin.Context) {
+		id := c.Param("id")
+		versions := make([]gin.H, 0)
+		for _, rec := range serviceSBOMs(uploads, id) {
+			versions = append(versions, gin.H{
+				"version":      rec.ID,
+				"file_name":    rec.FileName,
+				"sha256":       rec.SHA256,
+				"scan_id":      rec.ScanID,
+				"uploaded_by":  rec.UploadedBy,
+				"created_at":   rec.CreatedAt,
+				"download_url": fmt.Sprintf("/api/v1/services/%s/sbom?version=%s", id, rec.ID),
+			})
+		}
+		c.JSON(http.StatusOK, gin.H{"success": true, "service_id": id, "data": versions, "total": len(versions), "msg": "查询成功"})
+	})
+
+	// 下载SBOM（version为上传记录ID，默认最新版本）
+	r.GET("/api/v1/services/:id/sbom", auth.require(), func(c *gin.Context) {
+		id, version := c.Param("id"), c.Query("version")
+		for _, rec := range serviceSBOMs(uploads, id) {
+			if version != "" && rec.ID != version {
+				continue
+			}
+			c.Header("Content-Type", "application/vnd.cyclonedx+json")
+			c.FileAttachment(sbomPath(rec), fmt.Sprintf("%s-%s.cdx.json", id, rec.ID))
+			return
+		}
+		msg := fmt.Sprintf("服务%s没有SBOM", id)
+		if version != "" {
+			msg = fmt.Sprintf("服务%s没有版本%s的SBOM", id, version)
+		}
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "service_id": id, "msg": msg})
+	})
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"cmas-cats-go/storage"
+	"cmas-cats-go/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// scanRecord 一次代码包安全检查的完整报告
+type scanRecord struct {
+	ID            string                     `json:"id"`             // 检查ID
+	ServiceID     string                     `json:"service_id"`     // 归属服务ID
+	FileName      string                     `json:"file_name"`      // 上传文件名
+	SHA256        string                     `json:"sha256"`         // 上传文件SHA-256
+	ScannedBy     string                     `json:"scanned_by"`     // 上传者身份ID
+	Pass          bool                       `json:"pass"`           // 是否通过
+	Reason        string                     `json:"reason"`         // 检查结论
+	RulesVersion  string                     `json:"rules_version"`  // 规则集版本
+	Threshold     string                     `json:"threshold"`      // 不通过阈值
+	FindingCount  int                        `json:"finding_count"`  // 发现总数
+	BlockingCount int                        `json:"blocking_count"` // 导致不通过的发现数
+	CreatedAt     time.Time                  `json:"created_at"`     // 检查时间
+	Result        *utils.SecurityCheckResult `json:"result,omitempty"`
+}
+
+// summary 不含逐文件报告的摘要（列表接口使用）
+func (s scanRecord) summary() scanRecord {
+	s.Result = nil
+	return s
+}
+
+// scanTable 安全检查报告表
+type scanTable struct {
+	mu    sync.RWMutex
+	store storage.Store
+	seq   int
+	scans map[string]scanRecord
+}
+
+// newScanTable 创建检查报告表并从存储加载（ID序号从已有记录的最大值继续）
+func newScanTable(store storage.Store) (*scanTable, error) {
+	scans, err := storage.LoadAll[scanRecord](store, storage.BucketScans)
+	if err != nil {
+		return nil, fmt.Errorf("加载检查报告失败：%v", err)
+	}
+	st := &scanTable{store: store, scans: scans}
+	for id := range scans {
+		var n int
+		if _, err := fmt.Sscanf(id, "scan-%d", &n); err == nil && n > st.seq {
+			st.seq = n
+		}
+	}
+	return st, nil
+}
+
+// Add 保存检查报告并分配ID（持久化失败只记录日志，不影响上传结果）
+func (st *scanTable) Add(rec scanRecord) scanRecord {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.seq++
+	rec.ID = fmt.Sprintf("scan-%d", st.seq)
+	rec.CreatedAt = time.Now()
+	if r := rec.Result; r != nil {
+		rec.Pass, rec.Reason, rec.RulesVersion, rec.Threshold = r.Pass, r.Reason, r.RulesVersion, r.Threshold
+		rec.FindingCount, rec.BlockingCount = len(r.Findings), len(r.Threats)
+	}
+	if err := st.store.Put(storage.BucketScans, rec.ID, rec); err != nil {
+		fmt.Printf("保存检查报告%s失败：%v\n", rec.ID, err)
+	}
+	st.scans[rec.ID] = rec
+	return rec
+}
+
+// AssignService 检查通过后自动选择的服务ID回填到报告
+func (st *scanTable) AssignService(id, serviceID string) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	rec, ok := st.scans[id]
+	if !ok || rec.ServiceID == serviceID {
+		return
+	}
+	rec.ServiceID = serviceID
+	if err := st.store.Put(storage.BucketScans, id, rec); err != nil {
+		fmt.Printf("保存检查报告%s失败：%v\n", id, err)
+	}
+	st.scans[id] = rec
+}
+
+// Get 查询检查报告
+func (st *scanTable) Get(id string) (scanRecord, bool) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	rec, ok := st.scans[id]
+	return rec, ok
+}
+
+// List 按服务ID筛选检查报告摘要（空值表示不过滤），按检查时间倒序
+func (st *scanTable) List(serviceID string) []scanRecord {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	result := make([]scanRecord, 0, len(st.scans))
+	for _, rec := range st.scans {
+		if serviceID == "" || rec.ServiceID == serviceID {
+			result = append(result, rec.summary())
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].CreatedAt.After(result[j].CreatedAt) })
+	return result
+}
+
+// registerScanRoutes 注册检查报告查询/下载接口
+func registerScanRoutes(r *gin.Engine, scans *scanTable, rules *securityRules, auth *authenticator) {
+	// 检查报告列表（支持 service_id 过滤）
+	r.GET("/api/v1/scans", auth.require(), func(c *gin.Context) {
+		list := scans.List(c.Query("service_id"))
+		c.JSON(http.StatusOK, gin.H{"success": true, "data": list, "total": len(list), "msg": "查询成功"})
+	})
+
+	// 某服务的历史检查报告
+	r.GET("/api/v1/services/:id/scans", auth.require(), func(c *gin.Context) {
+		id := c.Param("id")
+		list := scans.List(id)
+		c.JSON(http.StatusOK, gin.H{"success": true, "service_id": id, "data": list, "total": len(list), "msg": "查询成功"})
+	})
+
+	// 完整检查报告
+	r.GET("/api/v1/scans/:id", auth.require(), func(c *gin.Context) {
+		rec, ok := scans.Get(c.Param("id"))
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"success": false, "msg": fmt.Sprintf("检查报告%s不存在", c.Param("id"))})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"success": true, "service_id": rec.ServiceID, "data": rec, "msg": "查询成功"})
+	})
+
+	// 下载检查报告（format=json|sarif，默认sarif）
+	r.GET("/api/v1/scans/:id/report", auth.require(), func(c *gin.Context) {
+		rec, ok := scans.Get(c.Param("id"))
+		if !ok || rec.Result == nil {
+			c.JSON(http.StatusNotFound, gin.H{"success": false, "msg": fmt.Sprintf("检查报告%s不存在", c.Param("id"))})
+			return
+		}
+		switch format := c.DefaultQuery("format", "sarif"); format {
+		case "sarif":
+			c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.sarif", rec.ID))
+			c.Header("Content-Type", "application/sarif+json")
+			c.JSON(http.StatusOK, utils.ToSARIF(rec.Result, rules.Get()))
+		case "json":
+			c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.json", rec.ID))
+			c.JSON(http.StatusOK, rec)
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "msg": fmt.Sprintf("不支持的报告格式%q（json/sarif）", format)})
+		}
+	})
+}
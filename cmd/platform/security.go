@@ -0,0 +1,313 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"cmas-cats-go/config"
+	"cmas-cats-go/models"
+	"cmas-cats-go/storage"
+	"cmas-cats-go/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// securityRules 当前生效的安全检查规则集与离线漏洞库（支持管理员热加载）
+type securityRules struct {
+	mu    sync.RWMutex
+	rules *utils.RuleSet
+	vulns *utils.VulnDB
+}
+
+// newSecurityRules 按配置加载规则集
+func newSecurityRules() (*securityRules, error) {
+	sr := &securityRules{}
+	if err := sr.Reload(); err != nil {
+		return nil, err
+	}
+	return sr, nil
+}
+
+// Reload 重新加载规则包文件与离线漏洞库（失败时保留原数据）
+func (sr *securityRules) Reload() error {
+	rules, err := utils.LoadRuleSet(config.Cfg.Security.RuleFiles)
+	if err != nil {
+		return fmt.Errorf("加载安全规则失败：%v", err)
+	}
+	var vulns *utils.VulnDB
+	if file := config.Cfg.Security.Dependencies.VulnDB; file != "" {
+		if vulns, err = utils.LoadVulnDB(file); errors.Is(err, os.ErrNotExist) {
+			vulns = nil
+		} else if err != nil {
+			return fmt.Errorf("加载离线漏洞库失败：%v", err)
+		}
+	}
+	sr.mu.Lock()
+	sr.rules, sr.vulns = rules, vulns
+	sr.mu.Unlock()
+	fmt.Printf("[SECURITY] 安全规则已加载（版本%s，共%d条），离线漏洞库%d条\n", rules.Version(), len(rules.Rules()), vulns.Count())
+	return nil
+}
+
+// Get 当前规则集
+func (sr *securityRules) Get() *utils.RuleSet {
+	sr.mu.RLock()
+	defer sr.mu.RUnlock()
+	return sr.rules
+}
+
+// Vulns 当前离线漏洞库（未配置时为空）
+func (sr *securityRules) Vulns() *utils.VulnDB {
+	sr.mu.RLock()
+	defer sr.mu.RUnlock()
+	return sr.vulns
+}
+
+// suppressionTable 安全检查抑制表
+// 提供者申请（代码内注释、清单或接口）为pending，管理员批准后在后续检查中生效
+type suppressionTable struct {
+	mu           sync.RWMutex
+	store        storage.Store
+	seq          int
+	suppressions map[string]models.Suppression
+}
+
+// newSuppressionTable 创建抑制表并从存储加载（ID序号从已有记录的最大值继续）
+func newSuppressionTable(store storage.Store) (*suppressionTable, error) {
+	suppressions, err := storage.LoadAll[models.Suppression](store, storage.BucketSuppressions)
+	if err != nil {
+		return nil, fmt.Errorf("加载抑制表失败：%v", err)
+	}
+	st := &suppressionTable{store: store, suppressions: suppressions}
+	for id := range suppressions {
+		var n int
+		if _, err := fmt.Sscanf(id, "sup-%d", &n); err == nil && n > st.seq {
+			st.seq = n
+		}
+	}
+	return st, nil
+}
+
+// Add 新增抑制并分配ID
+func (st *suppressionTable) Add(s models.Suppression) (models.Suppression, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.add(s)
+}
+
+func (st *suppressionTable) add(s models.Suppression) (models.Suppression, error) {
+	st.seq++
+	s.ID = fmt.Sprintf("sup-%d", st.seq)
+	s.CreatedAt = time.Now()
+	if err := st.store.Put(storage.BucketSuppressions, s.ID, s); err != nil {
+		return s, fmt.Errorf("保存抑制失败：%v", err)
+	}
+	st.suppressions[s.ID] = s
+	return s, nil
+}
+
+// Request 提交待审批的抑制申请；同一服务、规则、文件与指纹已有未驳回的记录时返回已有记录
+func (st *suppressionTable) Request(s models.Suppression) (models.Suppression, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	for _, old := range st.suppressions {
+		if old.ServiceID == s.ServiceID && old.Rule == s.Rule && old.File == s.File &&
+			old.Fingerprint == s.Fingerprint && old.Status != models.SuppressionRejected {
+			return old, nil
+		}
+	}
+	s.Status = models.SuppressionPending
+	return st.add(s)
+}
+
+// Review 审批抑制申请（approve为false表示驳回）
+func (st *suppressionTable) Review(id string, approve bool, reviewer, note string) (models.Suppression, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	s, ok := st.suppressions[id]
+	if !ok {
+		return s, fmt.Errorf("抑制%s不存在", id)
+	}
+	s.Status = models.SuppressionRejected
+	if approve {
+		s.Status = models.SuppressionApproved
+	}
+	s.ReviewedBy, s.ReviewNote, s.ReviewedAt = reviewer, note, time.Now()
+	if err := st.store.Put(storage.BucketSuppressions, id, s); err != nil {
+		return s, fmt.Errorf("保存抑制失败：%v", err)
+	}
+	st.suppressions[id] = s
+	return s, nil
+}
+
+// Delete 删除抑制
+func (st *suppressionTable) Delete(id string) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if _, ok := st.suppressions[id]; !ok {
+		return fmt.Errorf("抑制%s不存在", id)
+	}
+	if err := st.store.Delete(storage.BucketSuppressions, id); err != nil {
+		return fmt.Errorf("删除抑制失败：%v", err)
+	}
+	delete(st.suppressions, id)
+	return nil
+}
+
+// List 按状态与服务ID筛选（空值表示不过滤），按创建时间倒序
+func (st *suppressionTable) List(status, serviceID string) []models.Suppression {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	result := make([]models.Suppression, 0, len(st.suppressions))
+	for _, s := range st.suppressions {
+		if (status == "" || s.Status == status) && (serviceID == "" || s.ServiceID == serviceID) {
+			result = append(result, s)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].CreatedAt.After(result[j].CreatedAt) })
+	return result
+}
+
+// Approved 对某服务生效的已批准抑制（含全局抑制）
+func (st *suppressionTable) Approved(serviceID string) []models.Suppression {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	var result []models.Suppression
+	for _, s := range st.suppressions {
+		if s.Status == models.SuppressionApproved && (s.ServiceID == "" || s.ServiceID == serviceID) {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+// suppressionRequestBody 抑制申请/创建请求体
+type suppressionRequestBody struct {
+	ServiceID   string `json:"service_id"` // 仅管理员创建时使用（空表示全局抑制）
+	Rule        string `json:"rule" binding:"required"`
+	File        string `json:"file"`
+	Fingerprint string `json:"fingerprint"`
+	Reason      string `json:"reason" binding:"required"`
+}
+
+// registerSuppressionRoutes 注册提供者的抑制申请接口
+func registerSuppressionRoutes(r *gin.Engine, registry *serviceRegistry, suppressions *suppressionTable, auth *authenticator) {
+	// 提交抑制申请（待管理员审批）
+	r.POST("/api/v1/services/:id/suppressions", auth.require(models.RoleProvider), func(c *gin.Context) {
+		id := c.Param("id")
+		if _, ok := registry.Get(id); !ok {
+			c.JSON(http.StatusNotFound, gin.H{"success": false, "service_id": id, "msg": fmt.Sprintf("服务ID %s 不存在", id)})
+			return
+		}
+		if !ownsService(c, registry, id) {
+			return
+		}
+		var body suppressionRequestBody
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "service_id": id, "msg": "请求参数错误：" + err.Error()})
+			return
+		}
+		requester, _ := requestIdentity(c)
+		s, err := suppressions.Request(models.Suppression{
+			ServiceID: id, Rule: body.Rule, File: body.File, Fingerprint: body.Fingerprint, Reason: body.Reason,
+			Source: models.SuppressionSourceAPI, RequestedBy: requester,
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "service_id": id, "msg": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"success": true, "service_id": id, "data": s, "msg": "抑制申请已提交，等待管理员审批"})
+	})
+
+	// 查询服务的抑制（含状态）
+	r.GET("/api/v1/services/:id/suppressions", auth.require(), func(c *gin.Context) {
+		id := c.Param("id")
+		list := suppressions.List(c.Query("status"), id)
+		c.JSON(http.StatusOK, gin.H{"success": true, "service_id": id, "data": list, "total": len(list), "msg": "查询成功"})
+	})
+}
+
+// registerSecurityAdminRoutes 注册安全规则与抑制审批的管理接口
+func registerSecurityAdminRoutes(r *gin.Engine, rules *securityRules, suppressions *suppressionTable) {
+	admin := r.Group("/api/admin", adminOnly())
+
+	// 查看当前规则集
+	admin.GET("/security/rules", func(c *gin.Context) {
+		rs := rules.Get()
+		list := rs.Rules()
+		c.JSON(http.StatusOK, gin.H{"success": true, "data": gin.H{
+			"version":   rs.Version(),
+			"threshold": config.Cfg.Security.FailSeverity,
+			"rules":     list,
+		}, "total": len(list), "msg": "查询成功"})
+	})
+
+	// 重新加载规则包文件
+	admin.POST("/security/rules/reload", func(c *gin.Context) {
+		if err := rules.Reload(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "msg": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"success": true, "data": gin.H{
+			"version": rules.Get().Version(),
+			"vulns":   rules.Vulns().Count(),
+		}, "msg": "规则已重新加载"})
+	})
+
+	// 查询抑制（支持 status / service_id 过滤）
+	admin.GET("/suppressions", func(c *gin.Context) {
+		list := suppressions.List(c.Query("status"), c.Query("service_id"))
+		c.JSON(http.StatusOK, gin.H{"success": true, "data": list, "total": len(list), "msg": "查询成功"})
+	})
+
+	// 管理员直接创建已批准的抑制（service_id为空表示全局生效）
+	admin.POST("/suppressions", func(c *gin.Context) {
+		var body suppressionRequestBody
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "msg": "请求参数错误：" + err.Error()})
+			return
+		}
+		s, err := suppressions.Add(models.Suppression{
+			ServiceID: body.ServiceID, Rule: body.Rule, File: body.File, Fingerprint: body.Fingerprint, Reason: body.Reason,
+			Source: models.SuppressionSourceAPI, Status: models.SuppressionApproved,
+			RequestedBy: "admin", ReviewedBy: "admin", ReviewedAt: time.Now(),
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "msg": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"success": true, "service_id": s.ServiceID, "data": s, "msg": "抑制已创建"})
+	})
+
+	// 审批：批准/驳回（可附说明 {"note": "..."}）
+	review := func(approve bool) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			var body struct {
+				Note string `json:"note"`
+			}
+			_ = c.ShouldBindJSON(&body)
+			s, err := suppressions.Review(c.Param("id"), approve, "admin", body.Note)
+			if err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"success": false, "msg": err.Error()})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"success": true, "service_id": s.ServiceID, "data": s, "msg": "审批完成：" + s.Status})
+		}
+	}
+	admin.POST("/suppressions/:id/approve", review(true))
+	admin.POST("/suppressions/:id/reject", review(false))
+
+	// 删除抑制
+	admin.DELETE("/suppressions/:id", func(c *gin.Context) {
+		if err := suppressions.Delete(c.Param("id")); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"success": false, "msg": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"success": true, "msg": "抑制已删除"})
+	})
+}
@@ -0,0 +1,375 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"cmas-cats-go/models"
+	"cmas-cats-go/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// serviceIDPattern 平台分配的服务ID格式（S1、S2、S3...）
+var serviceIDPattern = regexp.MustCompile(`^S(\d+)$`)
+
+// serviceRegistry 公共服务表（草案Table 1）
+// 由Platform维护，供Provider注册、Site/Client查询
+type serviceRegistry struct {
+	mu       sync.RWMutex
+	store    storage.Store
+	services map[string]models.Service
+}
+
+// serviceRecord 服务表持久化记录（补齐Service中不参与JSON序列化的私有字段）
+type serviceRecord struct {
+	Service          models.Service `json:"service"`
+	ValidationSample string         `json:"validation_sample"`
+	ValidationResult string         `json:"validation_result"`
+	CodeBundle       string         `json:"code_bundle"`
+}
+
+// serviceFilter 服务列表查询条件（均为可选，空值表示不过滤）
+type serviceFilter struct {
+	Name               string // 服务名称（模糊匹配，忽略大小写）
+	InputFormat        string // 输入格式（精确匹配，忽略大小写）
+	SoftwareDependency string // 软件依赖（包含任一匹配项即可，忽略大小写）
+}
+
+// newServiceRegistry 创建服务表并从存储加载已注册的服务
+func newServiceRegistry(store storage.Store) (*serviceRegistry, error) {
+	records, err := storage.LoadAll[serviceRecord](store, storage.BucketServices)
+	if err != nil {
+		return nil, fmt.Errorf("加载服务表失败：%v", err)
+	}
+	sr := &serviceRegistry{store: store, services: make(map[string]models.Service, len(records))}
+	for id, rec := range records {
+		svc := rec.Service
+		svc.ID = id
+		svc.ValidationSample, svc.ValidationResult, svc.CodeBundle = rec.ValidationSample, rec.ValidationResult, rec.CodeBundle
+		sr.services[id] = svc
+	}
+	return sr, nil
+}
+
+// save 持久化服务记录（调用方需持有写锁）
+func (sr *serviceRegistry) save(svc models.Service) error {
+	return sr.store.Put(storage.BucketServices, svc.ID, serviceRecord{
+		Service:          svc,
+		ValidationSample: svc.ValidationSample,
+		ValidationResult: svc.ValidationResult,
+		CodeBundle:       svc.CodeBundle,
+	})
+}
+
+// nextID 分配下一个未使用的服务ID（调用方需持有写锁）
+func (sr *serviceRegistry) nextID() string {
+	for i := 1; ; i++ {
+		id := fmt.Sprintf("S%d", i)
+		if _, ok := sr.services[id]; !ok {
+			return id
+		}
+	}
+}
+
+// Create 注册服务，ID为空时自动分配，ID已存在时返回错误
+func (sr *serviceRegistry) Create(svc models.Service) (string, error) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	if svc.ID == "" {
+		svc.ID = sr.nextID()
+	} else if _, ok := sr.services[svc.ID]; ok {
+		return "", fmt.Errorf("服务ID %s 已存在", svc.ID)
+	}
+	svc.BundleSignature = nil // 签名只能随代码上传校验后写入
+	if err := sr.save(svc); err != nil {
+		return "", fmt.Errorf("保存服务失败：%v", err)
+	}
+	sr.services[svc.ID] = svc
+	return svc.ID, nil
+}
+
+// Get 按ID查询服务
+func (sr *serviceRegistry) Get(id string) (models.Service, bool) {
+	sr.mu.RLock()
+	defer sr.mu.RUnlock()
+	svc, ok := sr.services[id]
+	return svc, ok
+}
+
+// Update 覆盖更新已存在的服务（ID以参数为准，私有的样本/代码包字段保持不变；未携带身份时沿用原提供者）
+func (sr *serviceRegistry) Update(id string, svc models.Service) error {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	old, ok := sr.services[id]
+	if !ok {
+		return fmt.Errorf("服务ID %s 不存在", id)
+	}
+	svc.ID = id
+	svc.ValidationSample, svc.ValidationResult = old.ValidationSample, old.ValidationResult
+	svc.CodeBundle, svc.BundleSignature = old.CodeBundle, old.BundleSignature
+	if svc.ProviderID == "" {
+		svc.ProviderID, svc.ProviderAuth = old.ProviderID, old.ProviderAuth
+	}
+	if err := sr.save(svc); err != nil {
+		return fmt.Errorf("保存服务失败：%v", err)
+	}
+	sr.services[id] = svc
+	return nil
+}
+
+// SetCodeBundle 记录服务代码包路径及其签名（代码上传通过安全检查后调用，未签名时sig为nil）
+func (sr *serviceRegistry) SetCodeBundle(id, path string, sig *models.BundleSignature) error {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	svc, ok := sr.services[id]
+	if !ok {
+		return nil
+	}
+	svc.CodeBundle, svc.BundleSignature = path, sig
+	if err := sr.save(svc); err != nil {
+		return fmt.Errorf("保存服务失败：%v", err)
+	}
+	sr.services[id] = svc
+	return nil
+}
+
+// SetValidationNames 更新服务的样本/预期结果文件名（样本轮换后调用）
+func (sr *serviceRegistry) SetValidationNames(id, sampleName, resultName string) error {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	svc, ok := sr.services[id]
+	if !ok {
+		return nil
+	}
+	svc.ValidationSample, svc.ValidationResult = sampleName, resultName
+	if err := sr.save(svc); err != nil {
+		return fmt.Errorf("保存服务失败：%v", err)
+	}
+	sr.services[id] = svc
+	return nil
+}
+
+// Delete 删除服务
+func (sr *serviceRegistry) Delete(id string) error {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	if _, ok := sr.services[id]; !ok {
+		return fmt.Errorf("服务ID %s 不存在", id)
+	}
+	if err := sr.store.Delete(storage.BucketServices, id); err != nil {
+		return fmt.Errorf("删除服务失败：%v", err)
+	}
+	delete(sr.services, id)
+	return nil
+}
+
+// List 按条件筛选服务，结果按服务ID排序（S2排在S10之前）
+func (sr *serviceRegistry) List(f serviceFilter) []models.Service {
+	sr.mu.RLock()
+	defer sr.mu.RUnlock()
+
+	result := make([]models.Service, 0, len(sr.services))
+	for _, svc := range sr.services {
+		if f.match(svc) {
+			result = append(result, svc)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return serviceIDLess(result[i].ID, result[j].ID)
+	})
+	return result
+}
+
+// match 判断服务是否满足查询条件
+func (f serviceFilter) match(svc models.Service) bool {
+	if f.Name != "" && !strings.Contains(strings.ToLower(svc.Name), strings.ToLower(f.Name)) {
+		return false
+	}
+	if f.InputFormat != "" && !strings.EqualFold(svc.InputFormat, f.InputFormat) {
+		return false
+	}
+	if f.SoftwareDependency != "" {
+		found := false
+		for _, dep := range svc.SoftwareDependency {
+			if strings.EqualFold(dep, f.SoftwareDependency) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// serviceIDLess 服务ID排序：S<n>格式按数字比较，其余按字典序
+func serviceIDLess(a, b string) bool {
+	ma, mb := serviceIDPattern.FindStringSubmatch(a), serviceIDPattern.FindStringSubmatch(b)
+	if ma != nil && mb != nil {
+		na, _ := strconv.Atoi(ma[1])
+		nb, _ := strconv.Atoi(mb[1])
+		return na < nb
+	}
+	return a < b
+}
+
+// registerServiceRoutes 注册公共服务表接口（/api/v1/services）
+// 所有接口统一返回 {success, service_id, msg} 格式，与client保持一致
+// 样本与预期结果写入私有的samples表，查询接口不会返回
+// 注册/更新/删除需提供者身份，查询接口公开
+func registerServiceRoutes(r *gin.Engine, registry *serviceRegistry, samples *sampleTable, auth *authenticator) {
+	api := r.Group("/api/v1/services")
+
+	// 注册服务
+	// JSON请求体：仅注册Table 1信息
+	// multipart表单：service字段为JSON，validation_sample/validation_result为样本与预期结果文件
+	api.POST("", auth.require(models.RoleProvider), func(c *gin.Context) {
+		var svc models.Service
+		var sample, result *sampleFile
+		if c.ContentType() == "multipart/form-data" {
+			if err := json.Unmarshal([]byte(c.PostForm("service")), &svc); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"success": false, "msg": "service字段解析失败：" + err.Error()})
+				return
+			}
+			var err error
+			if sample, err = readSampleFile(c, sampleFormField); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"success": false, "msg": err.Error()})
+				return
+			}
+			if result, err = readSampleFile(c, resultFormField); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"success": false, "msg": err.Error()})
+				return
+			}
+			if (sample == nil) != (result == nil) {
+				c.JSON(http.StatusBadRequest, gin.H{"success": false, "msg": "样本与预期结果需同时提供"})
+				return
+			}
+		} else if err := c.ShouldBindJSON(&svc); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "msg": "参数解析失败：" + err.Error()})
+			return
+		}
+		if strings.TrimSpace(svc.Name) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "msg": "服务名称不能为空"})
+			return
+		}
+		if sample != nil {
+			svc.ValidationSample, svc.ValidationResult = sample.Name, result.Name
+		}
+		svc.ProviderID, svc.ProviderAuth = requestIdentity(c)
+
+		id, err := registry.Create(svc)
+		if err != nil {
+			c.JSON(http.StatusConflict, gin.H{"success": false, "service_id": svc.ID, "msg": err.Error()})
+			return
+		}
+		if sample != nil {
+			err := samples.Put(models.ServiceSample{
+				ServiceID:  id,
+				SampleName: sample.Name,
+				Sample:     sample.Data,
+				ResultName: result.Name,
+				Result:     result.Data,
+			})
+			if err != nil {
+				// 样本保存失败时撤销注册，避免留下一个永远无法通过部署验证的服务
+				if delErr := registry.Delete(id); delErr != nil {
+					fmt.Printf("撤销服务%s注册失败：%v\n", id, delErr)
+				}
+				c.JSON(http.StatusInternalServerError, gin.H{"success": false, "service_id": id, "msg": err.Error() + "，已撤销注册"})
+				return
+			}
+		}
+		fmt.Printf("服务注册成功：%s（%s）\n", id, svc.Name)
+		c.JSON(http.StatusOK, gin.H{"success": true, "service_id": id, "msg": "服务注册成功"})
+	})
+
+	// 查询服务列表（支持 name / input_format / software_dependency 过滤）
+	api.GET("", func(c *gin.Context) {
+		services := registry.List(serviceFilter{
+			Name:               c.Query("name"),
+			InputFormat:        c.Query("input_format"),
+			SoftwareDependency: c.Query("software_dependency"),
+		})
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data":    services,
+			"total":   len(services),
+			"msg":     "查询成功",
+		})
+	})
+
+	// 按ID查询服务
+	api.GET("/:id", func(c *gin.Context) {
+		id := c.Param("id")
+		svc, ok := registry.Get(id)
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"success": false, "service_id": id, "msg": fmt.Sprintf("服务ID %s 不存在", id)})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"success": true, "service_id": id, "data": svc, "msg": "查询成功"})
+	})
+
+	// 更新服务
+	api.PUT("/:id", auth.require(models.RoleProvider), func(c *gin.Context) {
+		id := c.Param("id")
+		var svc models.Service
+		if err := c.ShouldBindJSON(&svc); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "service_id": id, "msg": "参数解析失败：" + err.Error()})
+			return
+		}
+		if strings.TrimSpace(svc.Name) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "service_id": id, "msg": "服务名称不能为空"})
+			return
+		}
+		if !ownsService(c, registry, id) {
+			return
+		}
+		svc.ProviderID, svc.ProviderAuth = requestIdentity(c)
+		if err := registry.Update(id, svc); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"success": false, "service_id": id, "msg": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"success": true, "service_id": id, "msg": "服务更新成功"})
+	})
+
+	// 删除服务
+	api.DELETE("/:id", auth.require(models.RoleProvider), func(c *gin.Context) {
+		id := c.Param("id")
+		if !ownsService(c, registry, id) {
+			return
+		}
+		if err := registry.Delete(id); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"success": false, "service_id": id, "msg": err.Error()})
+			return
+		}
+		if err := samples.Delete(id); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "service_id": id, "msg": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"success": true, "service_id": id, "msg": "服务删除成功"})
+	})
+}
+
+// ownsService 校验请求方是否为服务的注册者（服务不存在或未记录提供者时放行，由后续逻辑处理）
+func ownsService(c *gin.Context, registry *serviceRegistry, id string) bool {
+	requester, _ := requestIdentity(c)
+	svc, ok := registry.Get(id)
+	if !ok || svc.ProviderID == "" || requester == "" || svc.ProviderID == requester {
+		return true
+	}
+	c.JSON(http.StatusForbidden, gin.H{"success": false, "service_id": id, "msg": fmt.Sprintf("服务%s由提供者%s注册，无权修改", id, svc.ProviderID)})
+	return false
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"cmas-cats-go/config"
+	"cmas-cats-go/models"
+	"cmas-cats-go/storage"
+	"cmas-cats-go/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// keyTable 提供者代码包签名公钥表
+// 公钥ID由公钥指纹生成，同一公钥重复登记返回已有记录；吊销后不再用于校验（含已上传代码包的部署前复核）
+type keyTable struct {
+	mu    sync.RWMutex
+	store storage.Store
+	keys  map[string]models.SigningKey
+}
+
+// newKeyTable 创建公钥表并从存储加载
+func newKeyTable(store storage.Store) (*keyTable, error) {
+	keys, err := storage.LoadAll[models.SigningKey](store, storage.BucketSigningKeys)
+	if err != nil {
+		return nil, fmt.Errorf("加载签名公钥表失败：%v", err)
+	}
+	return &keyTable{store: store, keys: keys}, nil
+}
+
+// Add 登记提供者公钥（base64原始公钥或PEM），同一公钥已由其他提供者登记时返回错误
+func (kt *keyTable) Add(providerID, publicKey, comment string) (models.SigningKey, error) {
+	pub, err := utils.ParseEd25519PublicKey(publicKey)
+	if err != nil {
+		return models.SigningKey{}, err
+	}
+	key := models.SigningKey{
+		ID:         "key-" + utils.KeyFingerprint(pub),
+		ProviderID: providerID,
+		Algorithm:  utils.SignatureAlgorithm,
+		PublicKey:  base64.StdEncoding.EncodeToString(pub),
+		Comment:    comment,
+		CreatedAt:  time.Now(),
+	}
+
+	kt.mu.Lock()
+	defer kt.mu.Unlock()
+	if old, ok := kt.keys[key.ID]; ok {
+		if old.ProviderID != providerID {
+			return old, fmt.Errorf("公钥%s已由其他提供者登记", key.ID)
+		}
+		if !old.Revoked {
+			return old, nil
+		}
+		return old, fmt.Errorf("公钥%s已吊销，不能重新登记", key.ID)
+	}
+	if err := kt.store.Put(storage.BucketSigningKeys, key.ID, key); err != nil {
+		return key, fmt.Errorf("保存公钥失败：%v", err)
+	}
+	kt.keys[key.ID] = key
+	return key, nil
+}
+
+// Revoke 吊销公钥（providerID非空时只能吊销自己的公钥）
+func (kt *keyTable) Revoke(id, providerID string) (models.SigningKey, error) {
+	kt.mu.Lock()
+	defer kt.mu.Unlock()
+	key, ok := kt.keys[id]
+	if !ok || (providerID != "" && key.ProviderID != providerID) {
+		return key, fmt.Errorf("公钥%s不存在", id)
+	}
+	if key.Revoked {
+		return key, nil
+	}
+	key.Revoked, key.RevokedAt = true, time.Now()
+	if err := kt.store.Put(storage.BucketSigningKeys, id, key); err != nil {
+		return key, fmt.Errorf("保存公钥失败：%v", err)
+	}
+	kt.keys[id] = key
+	return key, nil
+}
+
+// Get 按ID查询公钥
+func (kt *keyTable) Get(id string) (models.SigningKey, bool) {
+	kt.mu.RLock()
+	defer kt.mu.RUnlock()
+	key, ok := kt.keys[id]
+	return key, ok
+}
+
+// List 按提供者筛选（空值表示全部），按登记时间倒序
+func (kt *keyTable) List(providerID string) []models.SigningKey {
+	kt.mu.RLock()
+	defer kt.mu.RUnlock()
+	result := make([]models.SigningKey, 0, len(kt.keys))
+	for _, key := range kt.keys {
+		if providerID == "" || key.ProviderID == providerID {
+			result = append(result, key)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].CreatedAt.After(result[j].CreatedAt) })
+	return result
+}
+
+// Verify 用提供者的有效公钥校验代码包摘要签名（keyID为空时依次尝试该提供者的所有有效公钥）
+// 只接受属于providerID的公钥；providerID为空（无法确定签名方）时直接拒绝，不会退化为尝试任意提供者的公钥
+func (kt *keyTable) Verify(providerID, keyID, digest, signature string) (models.SigningKey, error) {
+	if providerID == "" {
+		return models.SigningKey{}, errors.New("无法确定签名方（服务未指定提供者且请求未认证）")
+	}
+	var candidates []models.SigningKey
+	if keyID != "" {
+		key, ok := kt.Get(keyID)
+		if !ok || key.ProviderID != providerID {
+			return key, fmt.Errorf("签名公钥%s不存在", keyID)
+		}
+		candidates = append(candidates, key)
+	} else {
+		candidates = kt.List(providerID)
+	}
+	err := fmt.Errorf("提供者%s没有登记有效的签名公钥", providerID)
+	for _, key := range candidates {
+		if key.Revoked {
+			if keyID != "" {
+				return key, fmt.Errorf("签名公钥%s已于%s吊销", key.ID, key.RevokedAt.Format(time.RFC3339))
+			}
+			continue
+		}
+		pub, perr := utils.ParseEd25519PublicKey(key.PublicKey)
+		if perr != nil {
+			err = perr
+			continue
+		}
+		if err = utils.VerifyBundleSignature(pub, digest, signature); err == nil {
+			return key, nil
+		}
+	}
+	return models.SigningKey{}, err
+}
+
+// errBundleUnsigned 要求签名时代码包未签名
+var errBundleUnsigned = errors.New("平台要求代码包携带提供者签名")
+
+// verifyBundleFile 部署前复核代码包：文件摘要须与签名摘要一致，签名公钥仍有效且签名正确
+// sig为nil时按配置决定是否放行
+func verifyBundleFile(keys *keyTable, path string, sig *models.BundleSignature) error {
+	if sig == nil {
+		if config.Cfg.Security.RequireSignedBundles {
+			return errBundleUnsigned
+		}
+		return nil
+	}
+	digest, _, err := fileDigest(path)
+	if err != nil {
+		return fmt.Errorf("计算代码包摘要失败：%v", err)
+	}
+	if !strings.EqualFold(digest, sig.Digest) {
+		return fmt.Errorf("代码包摘要%s与签名摘要%s不一致", digest, sig.Digest)
+	}
+	if _, err := keys.Verify(sig.SignedBy, sig.KeyID, sig.Digest, sig.Signature); err != nil {
+		return fmt.Errorf("代码包签名校验失败：%v", err)
+	}
+	return nil
+}
+
+// keyRequestBody 公钥登记请求体
+type keyRequestBody struct {
+	PublicKey string `json:"public_key" binding:"required"` // base64编码的32字节ed25519公钥或PEM
+	Comment   string `json:"comment"`
+}
+
+// registerKeyRoutes 注册提供者签名公钥接口
+func registerKeyRoutes(r *gin.Engine, keys *keyTable, auth *authenticator) {
+	api := r.Group("/api/v1/keys", auth.require(models.RoleProvider))
+
+	// 登记公钥
+	api.POST("", func(c *gin.Context) {
+		var body keyRequestBody
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "msg": "请求参数错误：" + err.Error()})
+			return
+		}
+		requester, _ := requestIdentity(c)
+		key, err := keys.Add(requester, body.PublicKey, body.Comment)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "msg": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"success": true, "data": key, "msg": "公钥已登记"})
+	})
+
+	// 查询自己登记的公钥
+	api.GET("", func(c *gin.Context) {
+		requester, _ := requestIdentity(c)
+		list := keys.List(requester)
+		c.JSON(http.StatusOK, gin.H{"success": true, "data": list, "total": len(list), "msg": "查询成功"})
+	})
+
+	// 吊销公钥
+	api.DELETE("/:id", func(c *gin.Context) {
+		requester, _ := requestIdentity(c)
+		key, err := keys.Revoke(c.Param("id"), requester)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"success": false, "msg": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"success": true, "data": key, "msg": "公钥已吊销"})
+	})
+
+	admin := r.Group("/api/admin", adminOnly())
+
+	// 查询所有公钥（支持 provider_id 过滤）
+	admin.GET("/keys", func(c *gin.Context) {
+		list := keys.List(c.Query("provider_id"))
+		c.JSON(http.StatusOK, gin.H{"success": true, "data": list, "total": len(list), "msg": "查询成功"})
+	})
+
+	// 管理员吊销公钥
+	admin.DELETE("/keys/:id", func(c *gin.Context) {
+		key, err := keys.Revoke(c.Param("id"), "")
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"success": false, "msg": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"success": true, "data": key, "msg": "公钥已吊销"})
+	})
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+
+	"cmas-cats-go/config"
+	"cmas-cats-go/storage"
+	"cmas-cats-go/utils"
+)
+
+// platformState Platform的全部持久化状态
+type platformState struct {
+	store        storage.Store
+	registry     *serviceRegistry
+	samples      *sampleTable
+	validations  *validator
+	identities   *identityStore
+	applications *applicationTable
+	leases       *leaseTable
+	uploads      *uploadTable
+	rules        *securityRules
+	suppressions *suppressionTable
+	scans        *scanTable
+	keys         *keyTable
+	staging      *utils.StagingBudget // 解压暂存区磁盘配额（进程内共享）
+}
+
+// openPlatformState 按配置打开存储（含schema迁移），并加载各张表
+func openPlatformState() (*platformState, error) {
+	store, err := storage.Open(config.Cfg.Storage.Driver, config.Cfg.Storage.Path)
+	if err != nil {
+		return nil, fmt.Errorf("打开存储失败：%v", err)
+	}
+	st := &platformState{store: store, staging: utils.NewStagingBudget(config.Cfg.Upload.StagingBudget)}
+	fail := func(err error) (*platformState, error) {
+		store.Close()
+		return nil, err
+	}
+
+	if st.registry, err = newServiceRegistry(store); err != nil {
+		return fail(err)
+	}
+	if st.samples, err = newSampleTable(store); err != nil {
+		return fail(err)
+	}
+	validationTable, err := newValidationTable(store)
+	if err != nil {
+		return fail(err)
+	}
+	st.validations = newValidator(st.registry, st.samples, validationTable, config.Cfg.Validation.Tolerance)
+	if st.identities, err = newIdentityStore(store); err != nil {
+		return fail(err)
+	}
+	if st.applications, err = newApplicationTable(store); err != nil {
+		return fail(err)
+	}
+	if st.leases, err = newLeaseTable(store); err != nil {
+		return fail(err)
+	}
+	if st.uploads, err = newUploadTable(store); err != nil {
+		return fail(err)
+	}
+	if st.suppressions, err = newSuppressionTable(store); err != nil {
+		return fail(err)
+	}
+	if st.scans, err = newScanTable(store); err != nil {
+		return fail(err)
+	}
+	if st.keys, err = newKeyTable(store); err != nil {
+		return fail(err)
+	}
+	if st.rules, err = newSecurityRules(); err != nil {
+		return fail(err)
+	}
+	fmt.Printf("[STORAGE] Platform状态已加载（%s：%s）\n", config.Cfg.Storage.Driver, config.Cfg.Storage.Path)
+	return st, nil
+}
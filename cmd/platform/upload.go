@@ -0,0 +1,284 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"cmas-cats-go/config"
+	"cmas-cats-go/models"
+	"cmas-cats-go/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// uploadExtractLimits 按配置生成解压限制（暂存区配额由所有上传共享）
+func uploadExtractLimits(state *platformState) utils.ExtractLimits {
+	return utils.ExtractLimits{
+		MaxTotalSize: config.Cfg.Upload.MaxTotalSize,
+		MaxFiles:     config.Cfg.Upload.MaxFiles,
+		MaxRatio:     config.Cfg.Upload.MaxRatio,
+		RatioFloor:   config.Cfg.Upload.RatioFloor,
+		MaxDepth:     config.Cfg.Upload.MaxDepth,
+		Budget:       state.staging,
+	}
+}
+
+// uploadSecurityPolicy 按配置生成安全检查策略（当前规则集、阈值与该服务已批准的抑制）
+func uploadSecurityPolicy(state *platformState, serviceID string) utils.SecurityPolicy {
+	policy := utils.DefaultSecurityPolicy
+	if len(config.Cfg.Security.PickleAllowlist) > 0 {
+		policy.PickleAllowlist = config.Cfg.Security.PickleAllowlist
+	}
+	policy.Rules = state.rules.Get()
+	policy.FailSeverity = config.Cfg.Security.FailSeverity
+	policy.Suppressions = state.suppressions.Approved(serviceID)
+	policy.ScannerFailure = config.Cfg.Security.ScannerFailure
+	policy.SecretAction = config.Cfg.Security.SecretAction
+	deps := config.Cfg.Security.Dependencies
+	policy.Dependencies = utils.DependencyPolicy{
+		Allow: deps.Allow, Deny: deps.Deny, RequirePins: deps.RequirePins, Vulns: state.rules.Vulns(),
+	}
+	if clamd := config.Cfg.Security.Clamd; clamd.Enabled {
+		policy.Scanners = append(policy.Scanners, utils.NewClamdClient(clamd.Network, clamd.Address, clamd.Timeout))
+	}
+	return policy
+}
+
+// requestSuppressions 把代码中的抑制注释/清单登记为待审批申请（未指定服务时不登记）
+func requestSuppressions(c *gin.Context, state *platformState, serviceID string, result *utils.SecurityCheckResult) {
+	if serviceID == "" {
+		return
+	}
+	requester, _ := requestIdentity(c)
+	for i, s := range result.SuppressionRequests {
+		s.ServiceID, s.RequestedBy = serviceID, requester
+		saved, err := state.suppressions.Request(s)
+		if err != nil {
+			fmt.Printf("登记抑制申请失败：%v\n", err)
+			continue
+		}
+		result.SuppressionRequests[i] = saved
+	}
+}
+
+// bundleSignature 校验上传表单中的代码包签名（signature为base64签名，key_id可选）
+// 签名方为服务的提供者（服务未指定提供者时为已认证的上传者，两者都没有时拒绝签名）；未签名时返回nil，按配置决定是否放行
+func bundleSignature(c *gin.Context, state *platformState, serviceID, savePath string) (*models.BundleSignature, error) {
+	signature := strings.TrimSpace(c.PostForm("signature"))
+	if signature == "" {
+		if config.Cfg.Security.RequireSignedBundles {
+			return nil, errBundleUnsigned
+		}
+		return nil, nil
+	}
+	signer, _ := requestIdentity(c)
+	if svc, ok := state.registry.Get(serviceID); ok && svc.ProviderID != "" {
+		signer = svc.ProviderID
+	}
+	digest, _, err := fileDigest(savePath)
+	if err != nil {
+		return nil, fmt.Errorf("计算代码包摘要失败：%v", err)
+	}
+	key, err := state.keys.Verify(signer, c.PostForm("key_id"), digest, signature)
+	if err != nil {
+		return nil, fmt.Errorf("代码包签名校验失败：%v", err)
+	}
+	return &models.BundleSignature{
+		Digest: digest, Signature: signature, KeyID: key.ID, Algorithm: key.Algorithm,
+		SignedBy: key.ProviderID, VerifiedAt: time.Now(),
+	}, nil
+}
+
+// handleCodeUpload 代码包上传与检查（/api/upload/code、/api/check/code共用）
+// 流程：保存到独立临时目录 → Go原生安全解压到独立暂存目录 → 安全检查 → 移动到服务目录
+// 每次上传使用单独的临时/暂存目录，请求结束后统一清理
+func handleCodeUpload(state *platformState) gin.HandlerFunc {
+	registry := state.registry
+	return func(c *gin.Context) {
+		// 指定了已注册的服务ID时，代码包归属该服务（供Site通过Apply获取），并应用该服务已批准的抑制
+		serviceID := c.PostForm("service_id")
+		if serviceID != "" {
+			if _, ok := registry.Get(serviceID); !ok {
+				c.JSON(404, gin.H{"error": fmt.Sprintf("服务ID %s 不存在", serviceID)})
+				return
+			}
+			if !ownsService(c, registry, serviceID) {
+				return
+			}
+		}
+
+		file, err := c.FormFile("codeFile")
+		if err != nil {
+			c.JSON(400, gin.H{"error": "上传文件失败：" + err.Error()})
+			return
+		}
+
+		uploadDir, err := os.MkdirTemp(tempCodeDir, "upload-*")
+		if err != nil {
+			c.JSON(500, gin.H{"error": "创建临时目录失败：" + err.Error()})
+			return
+		}
+		defer os.RemoveAll(uploadDir)
+
+		fileName := filepath.Base(file.Filename)
+		savePath := filepath.Join(uploadDir, fileName)
+		if err := c.SaveUploadedFile(file, savePath); err != nil {
+			c.JSON(500, gin.H{"error": "保存文件失败：" + err.Error()})
+			return
+		}
+
+		// 校验提供者签名（签名对象为上传的代码包文件本身）
+		signature, err := bundleSignature(c, state, serviceID, savePath)
+		if err != nil {
+			upload := newUploadRecord(c, fileName, savePath)
+			upload.ServiceID, upload.Pass, upload.Reason = serviceID, false, err.Error()
+			state.uploads.Add(upload)
+			c.JSON(403, gin.H{"error": err.Error()})
+			return
+		}
+
+		// 调试日志：打印文件名和保存路径
+		fmt.Printf("上传的文件名: %s\n", fileName)
+		fmt.Printf("保存路径: %s\n", savePath)
+
+		// 解压到独立暂存目录（拒绝路径穿越/链接，限制大小、文件数、压缩比与嵌套层数，占用全局暂存配额）
+		unzipPath, err := utils.ExtractToStaging(savePath, config.Cfg.Upload.StagingDir, uploadExtractLimits(state))
+		if err != nil {
+			fmt.Printf("解压文件失败: %s\n", err.Error())
+			upload := newUploadRecord(c, fileName, savePath)
+			upload.ServiceID, upload.Pass, upload.Reason = serviceID, false, "解压失败："+err.Error()
+			var violation *utils.ArchiveViolation
+			switch {
+			case errors.As(err, &violation):
+				// 压缩炸弹/嵌套过深作为威胁记入检查报告
+				result := utils.ArchiveViolationResult(fileName, violation, state.rules.Get())
+				result.Threshold = config.Cfg.Security.FailSeverity
+				scan := state.scans.Add(scanRecord{
+					ServiceID: serviceID, FileName: fileName, SHA256: upload.SHA256, ScannedBy: upload.UploadedBy, Result: result,
+				})
+				upload.ScanID, upload.Reason = scan.ID, result.Reason
+				state.uploads.Add(upload)
+				c.JSON(403, gin.H{
+					"error":         "模型安全评估不通过，禁止上传",
+					"scan_id":       scan.ID,
+					"reason":        result.Reason,
+					"threats":       result.Threats,
+					"findings":      result.Findings,
+					"rules_version": result.RulesVersion,
+				})
+			case errors.Is(err, utils.ErrStagingBudget):
+				state.uploads.Add(upload)
+				c.JSON(503, gin.H{"error": err.Error()})
+			default:
+				state.uploads.Add(upload)
+				c.JSON(400, gin.H{"error": "解压文件失败: " + err.Error()})
+			}
+			return
+		}
+		defer state.staging.Remove(unzipPath)
+		fmt.Printf("解压目录: %s\n", unzipPath)
+
+		// 检查 requirements.txt 是否存在
+		requirementsPath := filepath.Join(unzipPath, "requirements.txt")
+		if _, err := os.Stat(requirementsPath); os.IsNotExist(err) {
+			fmt.Printf("未找到 requirements.txt 文件\n")
+			c.JSON(400, gin.H{"error": "未找到 requirements.txt 文件"})
+			return
+		}
+
+		// 调试日志：列出解压后的文件
+		files, err := os.ReadDir(unzipPath)
+		if err != nil {
+			fmt.Printf("读取解压目录失败: %s\n", err.Error())
+			c.JSON(500, gin.H{"error": "读取解压目录失败: " + err.Error()})
+			return
+		}
+		fmt.Println("解压后的文件列表:")
+		for _, file := range files {
+			fmt.Println(file.Name())
+		}
+
+		// 安全检查
+		securityResult := utils.CheckModelSecurityWithPolicy(unzipPath, uploadSecurityPolicy(state, serviceID))
+		requestSuppressions(c, state, serviceID, securityResult)
+		upload := newUploadRecord(c, fileName, savePath)
+		scan := state.scans.Add(scanRecord{
+			ServiceID: serviceID, FileName: fileName, SHA256: upload.SHA256, ScannedBy: upload.UploadedBy, Result: securityResult,
+		})
+		upload.ServiceID, upload.ScanID = serviceID, scan.ID
+		if !securityResult.Pass {
+			upload.Pass, upload.Reason = false, securityResult.Reason
+			state.uploads.Add(upload)
+			c.JSON(403, gin.H{
+				"error":                "模型安全评估不通过，禁止上传",
+				"scan_id":              scan.ID,
+				"reason":               securityResult.Reason,
+				"threats":              securityResult.Threats,
+				"findings":             securityResult.Findings,
+				"rules_version":        securityResult.RulesVersion,
+				"suppression_requests": securityResult.SuppressionRequests,
+			})
+			return
+		}
+
+		// 未指定服务ID时自动选择最佳路径
+		bestServiceID := serviceID
+		if bestServiceID == "" {
+			if bestServiceID, err = getAvailableServiceID(); err != nil {
+				c.JSON(500, gin.H{"error": "无法获取最佳服务路径: " + err.Error()})
+				return
+			}
+		}
+
+		bestPath := filepath.Join("services", strings.ToLower(bestServiceID)+"_service")
+		if err := os.MkdirAll(bestPath, 0777); err != nil {
+			c.JSON(500, gin.H{"error": "无法创建最佳路径: " + err.Error()})
+			return
+		}
+
+		// 每次通过检查的上传单独存放（services/<服务>/<上传ID>/<文件名>），
+		// 重新上传同名文件不会覆盖旧版本，各版本的上传记录与SBOM始终指向自己的代码包
+		upload.ID = state.uploads.Reserve()
+		versionPath := filepath.Join(bestPath, upload.ID)
+		if err := os.MkdirAll(versionPath, 0777); err != nil {
+			c.JSON(500, gin.H{"error": "无法创建版本目录: " + err.Error()})
+			return
+		}
+		finalPath := filepath.Join(versionPath, fileName)
+		if err := os.Rename(savePath, finalPath); err != nil {
+			c.JSON(500, gin.H{"error": "无法移动文件到最佳路径: " + err.Error()})
+			return
+		}
+		if err := registry.SetCodeBundle(bestServiceID, finalPath, signature); err != nil {
+			c.JSON(500, gin.H{"error": "记录服务代码包失败: " + err.Error()})
+			return
+		}
+		upload.Path, upload.ServiceID, upload.Pass, upload.Reason = finalPath, bestServiceID, true, securityResult.Reason
+		state.scans.AssignService(scan.ID, bestServiceID)
+		upload = state.uploads.Add(upload)
+
+		// 生成SBOM（失败不影响上传结果）
+		var sbomURL string
+		if err := writeSBOM(registry, upload, unzipPath); err != nil {
+			fmt.Printf("生成服务%s的SBOM失败：%v\n", bestServiceID, err)
+		} else {
+			sbomURL = fmt.Sprintf("/api/v1/services/%s/sbom?version=%s", bestServiceID, upload.ID)
+		}
+
+		c.JSON(200, gin.H{
+			"msg":                  "文件已上传到最佳路径",
+			"bestPath":             finalPath,
+			"scan_id":              scan.ID,
+			"rules_version":        securityResult.RulesVersion,
+			"findings":             securityResult.Findings,
+			"suppression_requests": securityResult.SuppressionRequests,
+			"signature":            signature,
+			"version":              upload.ID,
+			"sbom_url":             sbomURL,
+		})
+	}
+}
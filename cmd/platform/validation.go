@@ -0,0 +1,288 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"cmas-cats-go/config"
+	"cmas-cats-go/models"
+	"cmas-cats-go/storage"
+	"cmas-cats-go/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// validationTable 部署实例验证记录表（按容器名索引）
+type validationTable struct {
+	mu       sync.RWMutex
+	store    storage.Store
+	records  map[string]models.InstanceValidation
+	attempts map[string]uint64 // 容器名 → 最近一次开始的验证序号（只在内存中，用于丢弃过期的验证结果）
+}
+
+// newValidationTable 创建验证记录表并从存储加载
+func newValidationTable(store storage.Store) (*validationTable, error) {
+	records, err := storage.LoadAll[models.InstanceValidation](store, storage.BucketDeployments)
+	if err != nil {
+		return nil, fmt.Errorf("加载部署记录失败：%v", err)
+	}
+	return &validationTable{store: store, records: records, attempts: map[string]uint64{}}, nil
+}
+
+// Begin 开始一次新的验证：写入记录并返回本次验证的序号，此前未完成的验证随之作废
+func (vt *validationTable) Begin(rec models.InstanceValidation) uint64 {
+	vt.mu.Lock()
+	defer vt.mu.Unlock()
+	vt.attempts[rec.ContainerName]++
+	vt.put(rec)
+	return vt.attempts[rec.ContainerName]
+}
+
+// Finish 写入序号为attempt的验证结果；期间已开始新的验证（重新部署或再次验证）时丢弃该结果并返回false
+func (vt *validationTable) Finish(rec models.InstanceValidation, attempt uint64) bool {
+	vt.mu.Lock()
+	defer vt.mu.Unlock()
+	if vt.attempts[rec.ContainerName] != attempt {
+		return false
+	}
+	vt.put(rec)
+	return true
+}
+
+// put 写入验证记录（调用方持有写锁；持久化失败只记录日志，内存中的状态仍然生效）
+func (vt *validationTable) put(rec models.InstanceValidation) {
+	rec.UpdatedAt = time.Now()
+	if err := vt.store.Put(storage.BucketDeployments, rec.ContainerName, rec); err != nil {
+		fmt.Printf("[VALIDATION] 保存实例%s验证记录失败：%v\n", rec.ContainerName, err)
+	}
+	vt.records[rec.ContainerName] = rec
+}
+
+// Get 按容器名查询验证记录
+func (vt *validationTable) Get(containerName string) (models.InstanceValidation, bool) {
+	vt.mu.RLock()
+	defer vt.mu.RUnlock()
+	rec, ok := vt.records[containerName]
+	return rec, ok
+}
+
+// List 列出验证记录，status为空时返回全部
+func (vt *validationTable) List(status string) []models.InstanceValidation {
+	vt.mu.RLock()
+	defer vt.mu.RUnlock()
+
+	result := make([]models.InstanceValidation, 0, len(vt.records))
+	for _, rec := range vt.records {
+		if status == "" || rec.Status == status {
+			result = append(result, rec)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ContainerName < result[j].ContainerName })
+	return result
+}
+
+// validator 部署验证器：向新实例的/run发送样本，比对输出与预期结果
+type validator struct {
+	registry  *serviceRegistry
+	samples   *sampleTable
+	table     *validationTable
+	client    *http.Client
+	tolerance float64 // 服务未声明容差时numeric/json比对使用的数值容差
+}
+
+func newValidator(registry *serviceRegistry, samples *sampleTable, table *validationTable, tolerance float64) *validator {
+	return &validator{
+		registry:  registry,
+		samples:   samples,
+		table:     table,
+		client:    &http.Client{Timeout: config.Cfg.Validation.Timeout},
+		tolerance: tolerance,
+	}
+}
+
+// ValidateAsync 标记实例为验证中，并在后台执行验证
+// rec需填写服务ID、容器名、实例地址及部署方信息；同一实例只保留最近一次开始的验证的结果
+func (v *validator) ValidateAsync(rec models.InstanceValidation) models.InstanceValidation {
+	rec.Status, rec.Detail = models.ValidationPending, "等待实例就绪"
+	attempt := v.table.Begin(rec)
+	go func() {
+		if !v.table.Finish(v.validate(rec), attempt) {
+			fmt.Printf("[VALIDATION] 实例%s已开始新的验证，丢弃第%d次验证的结果\n", rec.ContainerName, attempt)
+		}
+	}()
+	return rec
+}
+
+// validate 执行一次完整验证，实例未就绪时按配置重试
+func (v *validator) validate(rec models.InstanceValidation) models.InstanceValidation {
+	sample, ok := v.samples.Get(rec.ServiceID)
+	if !ok {
+		rec.Status, rec.Detail = models.ValidationFailed, fmt.Sprintf("服务%s未登记验证样本", rec.ServiceID)
+		fmt.Printf("[VALIDATION] 实例%s验证失败：%s\n", rec.ContainerName, rec.Detail)
+		return rec
+	}
+
+	// 比对方式由服务在Table 1中声明
+	var spec models.ComparatorSpec
+	if svc, ok := v.registry.Get(rec.ServiceID); ok {
+		spec = svc.Comparator
+	}
+	if spec.Tolerance == 0 && spec.Type != models.ComparatorImage {
+		spec.Tolerance = v.tolerance
+	}
+
+	var output []byte
+	var err error
+	for i := 0; i <= config.Cfg.Validation.Retries; i++ {
+		if i > 0 {
+			time.Sleep(config.Cfg.Validation.RetryInterval)
+		}
+		output, err = v.runSample(rec.CSCIID, rec.ServiceID, sample)
+		if err == nil {
+			break
+		}
+		fmt.Printf("[VALIDATION] 第%d次调用实例%s失败：%v\n", i+1, rec.ContainerName, err)
+	}
+	if err != nil {
+		rec.Status, rec.Detail = models.ValidationFailed, "调用实例失败："+err.Error()
+		fmt.Printf("[VALIDATION] 实例%s验证失败：%s\n", rec.ContainerName, rec.Detail)
+		return rec
+	}
+
+	// 图片类服务返回图片地址，需取回图片内容再比对
+	if spec.Type == models.ComparatorImage {
+		if output, err = v.fetchImage(rec.CSCIID, output); err != nil {
+			rec.Status, rec.Detail = models.ValidationFailed, "获取实例输出图片失败："+err.Error()
+			fmt.Printf("[VALIDATION] 实例%s验证失败：%s\n", rec.ContainerName, rec.Detail)
+			return rec
+		}
+	}
+
+	if err := utils.CompareResult(sample.Result, output, spec); err != nil {
+		rec.Status, rec.Detail = models.ValidationFailed, "实例输出与预期结果不一致："+err.Error()
+		fmt.Printf("[VALIDATION] 实例%s验证失败：%s\n", rec.ContainerName, rec.Detail)
+		return rec
+	}
+	rec.Status, rec.Detail = models.ValidationValidated, "实例输出与预期结果一致"
+	fmt.Printf("[VALIDATION] 实例%s验证通过\n", rec.ContainerName)
+	return rec
+}
+
+// runSample 向实例/run发送样本：文本样本以JSON发送，二进制样本以表单文件发送
+func (v *validator) runSample(csciID, serviceID string, sample models.ServiceSample) ([]byte, error) {
+	runURL := "http://" + csciID + "/run"
+	var resp *http.Response
+	var err error
+	if bytes.IndexByte(sample.Sample, 0) < 0 {
+		body, _ := json.Marshal(map[string]string{"input": string(sample.Sample), "service_id": serviceID})
+		resp, err = v.client.Post(runURL, "application/json", bytes.NewReader(body))
+	} else {
+		var buf bytes.Buffer
+		mw := multipart.NewWriter(&buf)
+		mw.WriteField("service_id", serviceID)
+		fw, _ := mw.CreateFormFile("file", sample.SampleName)
+		fw.Write(sample.Sample)
+		mw.Close()
+		resp, err = v.client.Post(runURL, mw.FormDataContentType(), &buf)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Success bool            `json:"success"`
+		Result  json.RawMessage `json:"result"`
+		Msg     string          `json:"msg"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("解析实例响应失败：%v", err)
+	}
+	if !result.Success {
+		return nil, fmt.Errorf("实例返回失败：%s", result.Msg)
+	}
+	// 字符串结果去掉JSON引号，其余结果保留原始JSON
+	var text string
+	if err := json.Unmarshal(result.Result, &text); err == nil {
+		return []byte(text), nil
+	}
+	return result.Result, nil
+}
+
+// fetchImage 从实例输出中取出image_url并下载图片
+// 实例返回的地址可能是容器内地址（如0.0.0.0），统一改用实例访问地址
+func (v *validator) fetchImage(csciID string, output []byte) ([]byte, error) {
+	var result struct {
+		ImageURL string `json:"image_url"`
+	}
+	if err := json.Unmarshal(output, &result); err != nil || result.ImageURL == "" {
+		return nil, fmt.Errorf("实例输出中没有image_url")
+	}
+	u, err := url.Parse(result.ImageURL)
+	if err != nil {
+		return nil, err
+	}
+	u.Scheme, u.Host = "http", csciID
+	resp, err := v.client.Get(u.String())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("下载图片返回%d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxSampleFileSize))
+}
+
+// registerValidationRoutes 注册部署验证接口（C-SMA据此过滤未通过验证的实例）
+func registerValidationRoutes(r *gin.Engine, v *validator, auth *authenticator) {
+	api := r.Group("/api/v1/deployments")
+
+	// 查询验证记录（支持 status 过滤）
+	api.GET("", func(c *gin.Context) {
+		records := v.table.List(c.Query("status"))
+		c.JSON(http.StatusOK, gin.H{"success": true, "data": records, "total": len(records), "msg": "查询成功"})
+	})
+
+	// 按容器名查询验证记录
+	api.GET("/:name", func(c *gin.Context) {
+		name := c.Param("name")
+		rec, ok := v.table.Get(name)
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"success": false, "msg": fmt.Sprintf("实例%s无验证记录", name)})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"success": true, "data": rec, "msg": "查询成功"})
+	})
+
+	// 重新验证已部署的实例：服务ID与实例地址取自部署记录，只允许管理员或该实例的部署方调用
+	api.POST("/:name/validate", adminOr(auth.require(models.RoleProvider, models.RoleSite)), func(c *gin.Context) {
+		name := c.Param("name")
+		rec, ok := v.table.Get(name)
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"success": false, "msg": fmt.Sprintf("实例%s无部署记录，只能重新验证已部署的实例", name)})
+			return
+		}
+		if config.Cfg.Auth.Enabled && !c.GetBool(ctxAdminKey) {
+			requester, _ := requestIdentity(c)
+			if rec.DeployedBy == "" || requester != rec.DeployedBy {
+				c.JSON(http.StatusForbidden, gin.H{"success": false, "msg": fmt.Sprintf("实例%s不是由%s部署的，无权重新验证", name, requester)})
+				return
+			}
+		}
+		rec = v.ValidateAsync(rec)
+		c.JSON(http.StatusOK, gin.H{"success": true, "data": rec, "msg": "已开始验证"})
+	})
+}
@@ -25,18 +25,91 @@ type DockerSiteConfig struct {
 type GlobalConfig struct {
 	Platform struct {
 		IP   string // Platform模块IP
-		Port int    // Platform模块端口（8080）
-		URL  string // Platform模块完整URL（http://127.0.0.1:8080）
+		Port int    // Platform模块端口（8081）
+		URL  string // Platform模块完整URL（http://127.0.0.1:8081）
 	}
 	CSMA struct {
-		IP   string // CSMA模块IP
-		Port int    // CSMA模块端口（8083）
-		URL  string // CSMA模块完整URL（http://127.0.0.1:8083）
+		IP                string // CSMA模块IP
+		Port              int    // CSMA模块端口（8083）
+		URL               string // CSMA模块完整URL（http://127.0.0.1:8083）
+		RequireValidation bool   // 是否只采集通过部署验证的实例
+		// Scrape 轮询采集容器/metrics
+		Scrape struct {
+			Interval    time.Duration // 采集周期
+			Jitter      float64       // 周期与退避时间的随机抖动比例（0.2表示±20%）
+			Timeout     time.Duration // 单个Site的请求超时
+			Workers     int           // 并发采集的worker数
+			BackoffBase time.Duration // 连续失败后的首次退避时间（之后每次翻倍）
+			BackoffMax  time.Duration // 退避时间上限
+		}
+		StaleAfter time.Duration // 条目超过该时间未更新即标记为陈旧（仍保留最近一次成功的数据）
+		MetricTTL  time.Duration // 条目超过该时间未更新即过期并从指标表移除
+		// Discovery Site发现
+		Discovery struct {
+			Sources       []string // 启用的发现方式：docker-labels、inventory、static
+			Reach         string   // 访问容器Site的方式：host（宿主机地址+映射端口）或container（容器IP+容器端口）
+			HostIP        string   // Reach=host时的宿主机地址（环境变量CMAS_SITE_HOST可覆盖）
+			NamePrefix    string   // docker-labels同时按容器名前缀发现未打标签的容器（为空则只按标签）
+			InventoryFile string   // inventory读取的Site清单（修改后自动重新加载）
+			StaticSites   []string // static方式的远端Site指标地址（环境变量CMAS_STATIC_SITES，逗号分隔）
+		}
 	}
 	CPS struct {
-		IP   string // CPS模块IP
-		Port int    // CPS模块端口（8084）
-		URL  string // CPS模块完整URL（http://127.0.0.1:8084）
+		IP          string // CPS模块IP
+		Port        int    // CPS模块端口（8084）
+		URL         string // CPS模块完整URL（http://127.0.0.1:8084）
+		StalePolicy string // 陈旧条目的使用策略（never/fallback/always）
+	}
+	// Validation 部署验证配置（向新实例发送样本并比对预期结果）
+	Validation struct {
+		Tolerance     float64       // 数值结果允许的误差
+		Timeout       time.Duration // 单次/run请求超时
+		Retries       int           // 实例未就绪时的重试次数
+		RetryInterval time.Duration // 重试间隔
+	}
+	// Auth 提供者/Site身份认证配置
+	Auth struct {
+		Enabled      bool          // 是否启用认证（关闭时所有接口匿名可用）
+		TokenTTL     time.Duration // 握手签发令牌的有效期
+		IdentityFile string        // 预置身份文件（可选）
+	}
+	// Storage Platform状态存储配置
+	Storage struct {
+		Driver string // 存储驱动（file/memory）
+		Path   string // 文件存储目录
+	}
+	// Upload 代码包上传/解压配置
+	Upload struct {
+		StagingDir   string  // 解压暂存根目录（每次上传单独建子目录）
+		MaxTotalSize int64   // 解压后总大小上限（字节）
+		MaxFiles     int     // 文件数上限
+		MaxRatio     float64 // 压缩比上限
+		RatioFloor   int64   // 解压后大小不超过该值（字节）时不检查压缩比
+		MaxDepth     int     // 嵌套压缩包展开层数上限（0表示不展开）
+		StagingBudget int64  // 解压暂存区全局磁盘配额（字节，所有并发上传共享，0表示不限制）
+	}
+	// Security 代码包安全检查配置
+	Security struct {
+		PickleAllowlist []string // pickle/joblib允许引用的模块前缀或完整限定名（为空时使用内置默认白名单）
+		RuleFiles       []string // 规则包文件（JSON/YAML，按顺序覆盖内置规则，不存在的文件跳过）
+		FailSeverity    string   // 不通过阈值（info/low/medium/high/critical）
+		ScannerFailure  string   // 外部扫描器不可用时的策略：open放行 / closed拒绝
+		SecretAction    string   // 密钥泄露的处理方式：block拒绝 / warn只报告
+		RequireSignedBundles bool // 要求代码包携带提供者ed25519签名（未签名的上传与部署被拒绝）
+		// Dependencies requirements.txt依赖检查
+		Dependencies struct {
+			Allow       []string // 允许名单（非空时只允许名单中的包）
+			Deny        []string // 禁止名单
+			RequirePins bool     // 要求固定版本
+			VulnDB      string   // 离线漏洞库（OSV导出的JSON文件、目录或all.zip，不存在时跳过）
+		}
+		// Clamd clamd杀毒引擎（INSTREAM协议）
+		Clamd struct {
+			Enabled bool
+			Network string        // unix或tcp
+			Address string        // 套接字路径或host:port
+			Timeout time.Duration // 连接及单个文件送检超时
+		}
 	}
 	DockerSites []DockerSiteConfig `json:"sites"` // 所有Docker Site的配置
 }
@@ -78,18 +151,81 @@ func init() {
 	// ===================== 1. 基础模块配置 =====================
 	// Platform模块配置
 	Cfg.Platform.IP = "127.0.0.1"
-	Cfg.Platform.Port = 8080
+	Cfg.Platform.Port = 8081
 	Cfg.Platform.URL = fmt.Sprintf("http://%s:%d", Cfg.Platform.IP, Cfg.Platform.Port)
 
 	// CSMA模块配置
 	Cfg.CSMA.IP = "127.0.0.1"
 	Cfg.CSMA.Port = 8083
 	Cfg.CSMA.URL = fmt.Sprintf("http://%s:%d", Cfg.CSMA.IP, Cfg.CSMA.Port)
+	Cfg.CSMA.RequireValidation = false // 开启前需确保所有Site都经Platform部署验证（见readme）
+	Cfg.CSMA.Scrape.Interval = 5 * time.Second
+	Cfg.CSMA.Scrape.Jitter = 0.2
+	Cfg.CSMA.Scrape.Timeout = 3 * time.Second
+	Cfg.CSMA.Scrape.Workers = 8
+	Cfg.CSMA.Scrape.BackoffBase = 5 * time.Second
+	Cfg.CSMA.Scrape.BackoffMax = 2 * time.Minute
+	Cfg.CSMA.StaleAfter = 15 * time.Second
+	Cfg.CSMA.MetricTTL = 2 * time.Minute
+	Cfg.CSMA.Discovery.Sources = []string{"docker-labels", "inventory"}
+	Cfg.CSMA.Discovery.Reach = "host"
+	Cfg.CSMA.Discovery.HostIP = "127.0.0.1"
+	if host := os.Getenv("CMAS_SITE_HOST"); host != "" {
+		Cfg.CSMA.Discovery.HostIP = host
+	}
+	Cfg.CSMA.Discovery.NamePrefix = "cmas-"
+	Cfg.CSMA.Discovery.InventoryFile = "config/docker_sites.json"
+	for _, site := range strings.Split(os.Getenv("CMAS_STATIC_SITES"), ",") {
+		if site = strings.TrimSpace(site); site != "" {
+			Cfg.CSMA.Discovery.StaticSites = append(Cfg.CSMA.Discovery.StaticSites, site)
+		}
+	}
+	if len(Cfg.CSMA.Discovery.StaticSites) > 0 {
+		Cfg.CSMA.Discovery.Sources = append(Cfg.CSMA.Discovery.Sources, "static")
+	}
 
 	// CPS模块配置
 	Cfg.CPS.IP = "127.0.0.1"
 	Cfg.CPS.Port = 8084
 	Cfg.CPS.URL = fmt.Sprintf("http://%s:%d", Cfg.CPS.IP, Cfg.CPS.Port)
+	Cfg.CPS.StalePolicy = "fallback" // models.StalePolicyFallback
+
+	// 部署验证配置
+	Cfg.Validation.Tolerance = 1e-6
+	Cfg.Validation.Timeout = 10 * time.Second
+	Cfg.Validation.Retries = 5
+	Cfg.Validation.RetryInterval = 3 * time.Second
+
+	// 认证配置（令牌签名密钥从环境变量CMAS_AUTH_SECRET读取）
+	// 默认关闭：自带的前端页面不携带认证信息，需要时设置CMAS_AUTH_ENABLED=true开启（见readme）
+	Cfg.Auth.Enabled = os.Getenv("CMAS_AUTH_ENABLED") == "true"
+	Cfg.Auth.TokenTTL = 12 * time.Hour
+	Cfg.Auth.IdentityFile = "config/identities.json"
+
+	// 存储配置（Platform重启后服务表、样本表、部署记录等不丢失）
+	Cfg.Storage.Driver = "file"
+	Cfg.Storage.Path = "data/platform"
+
+	// 代码包解压配置
+	Cfg.Upload.StagingDir = "temp/unzipped"
+	Cfg.Upload.MaxTotalSize = 512 << 20
+	Cfg.Upload.MaxFiles = 10000
+	Cfg.Upload.MaxRatio = 100
+	Cfg.Upload.RatioFloor = 1 << 20
+	Cfg.Upload.MaxDepth = 3
+	Cfg.Upload.StagingBudget = 2 << 30
+
+	// 安全检查配置
+	Cfg.Security.RuleFiles = []string{"config/security_rules.yaml"}
+	Cfg.Security.FailSeverity = "medium"
+	Cfg.Security.ScannerFailure = "closed"
+	Cfg.Security.SecretAction = "block"
+	Cfg.Security.RequireSignedBundles = false
+	Cfg.Security.Dependencies.VulnDB = "config/osv-pypi.zip"
+	Cfg.Security.Clamd.Enabled = false
+	Cfg.Security.Clamd.Network = "unix"
+	Cfg.Security.Clamd.Address = "/var/run/clamav/clamd.ctl"
+	Cfg.Security.Clamd.Timeout = 30 * time.Second
 
 	// ===================== 2. Docker Site配置 =====================
 	// 优先读取配置文件（config/docker_sites.json）
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 // Service 对应草案Table 1（公共服务平台的服务表）
 type Service struct {
     ID                 string   `json:"id"`                  // 服务ID（S1/S2/S3）
@@ -12,6 +14,27 @@ type Service struct {
     SoftwareDependency []string `json:"software_dependency"` // 软件依赖
     ValidationSample   string   `json:"-"`                  // 验证样本（私有）
     ValidationResult   string   `json:"-"`                  // 预期结果（私有）
+    Comparator         ComparatorSpec `json:"comparator"`   // 验证结果比对方式
+    ProviderID         string   `json:"provider_id"`         // 注册该服务的提供者身份ID
+    ProviderAuth       *AuthResponse `json:"provider_auth,omitempty"` // 注册时提供者的Auth_RESPONSE
+    CodeBundle         string   `json:"-"`                  // 已上传代码包在Platform上的路径（私有）
+    BundleSignature    *BundleSignature `json:"bundle_signature,omitempty"` // 已上传代码包的提供者签名
+}
+
+// 内置结果比对器类型
+const (
+    ComparatorExact   = "exact"   // 文本完全一致（忽略首尾空白）
+    ComparatorNumeric = "numeric" // 数值误差在容差内（非数值时退化为文本比对）
+    ComparatorJSON    = "json"    // JSON结构比对（支持忽略字段、按字段设置误差）
+    ComparatorImage   = "image"   // 图片感知哈希距离
+)
+
+// ComparatorSpec 部署验证时实例输出与预期结果的比对方式
+type ComparatorSpec struct {
+    Type         string             `json:"type"`                    // 比对器类型，空值按numeric处理
+    Tolerance    float64            `json:"tolerance"`               // 数值误差（numeric/json）或最大哈希距离（image），0表示使用默认值
+    IgnoreFields []string           `json:"ignore_fields,omitempty"` // json：忽略的字段（字段名或a.b路径）
+    FieldEpsilon map[string]float64 `json:"field_epsilon,omitempty"` // json：按字段设置数值误差
 }
 
 // ServiceInstanceInfo 对应草案Table 3（Site的服务模型表）
@@ -23,9 +46,210 @@ type ServiceInstanceInfo struct {
     Delay     int    `json:"delay"`      // 延迟（ms）
 }
 
+// 服务模型表通告类型（Site → C-SMA）
+const (
+    AnnounceSnapshot = "snapshot" // 全量快照：Site连接后首先发送完整的Table 3
+    AnnounceDelta    = "delta"    // 增量：之后只发送变化的条目
+)
+
+// 增量操作
+const (
+    DeltaAdd    = "add"    // 新增实例（新部署或扩容）
+    DeltaUpdate = "update" // 实例信息变化（可用实例数、价格、延迟等）
+    DeltaRemove = "remove" // 实例下线
+)
+
+// TableAnnouncement Site向C-SMA推送的服务模型表通告
+// 序号按Site单调递增，快照同样占用序号；C-SMA发现序号不连续时要求Site重发快照
+type TableAnnouncement struct {
+    SiteID    string                `json:"site_id"`             // Site标识
+    Seq       uint64                `json:"seq"`                 // 通告序号
+    Type      string                `json:"type"`                // snapshot/delta
+    Instances []ServiceInstanceInfo `json:"instances,omitempty"` // snapshot：完整的服务模型表
+    Changes   []InstanceDelta       `json:"changes,omitempty"`   // delta：变化的条目
+}
+
+// InstanceDelta 单条增量（实例以ServiceID+CSCIID标识，remove只需这两个字段）
+type InstanceDelta struct {
+    Op       string              `json:"op"`       // add/update/remove
+    Instance ServiceInstanceInfo `json:"instance"` // 实例信息
+}
+
+// AnnounceAck C-SMA对通告的回复
+type AnnounceAck struct {
+    SiteID   string `json:"site_id"`  // Site标识
+    Accepted bool   `json:"accepted"` // 通告是否已应用（重复的旧序号也视为已接受）
+    Seq      uint64 `json:"seq"`      // C-SMA已应用的最新序号
+    Resync   bool   `json:"resync"`   // 需要Site重新发送全量快照
+    Msg      string `json:"msg"`      // 说明
+}
+
+// 指标条目的新鲜度（C-SMA按最近一次采集/通告的时间计算）
+const (
+    MetricFresh   = "fresh"   // 在StaleAfter内更新过
+    MetricStale   = "stale"   // 超过StaleAfter未更新，保留最近一次成功的数据
+    MetricExpired = "expired" // 超过TTL未更新，已从指标表中移除
+)
+
+// C-PS对陈旧条目的使用策略
+const (
+    StalePolicyNever    = "never"    // 从不使用陈旧条目
+    StalePolicyFallback = "fallback" // 没有新鲜的候选实例时才使用陈旧条目
+    StalePolicyAlways   = "always"   // 陈旧条目与新鲜条目同等对待
+)
+
+// InstanceFreshness 实例指标的新鲜度（C-SMA /sync返回）
+type InstanceFreshness struct {
+    ServiceID string    `json:"service_id"` // 服务ID
+    CSCIID    string    `json:"csci_id"`    // 访问地址
+    Source    string    `json:"source"`     // 来源（poll或site:<id>）
+    UpdatedAt time.Time `json:"updated_at"` // 最近一次成功采集/通告的时间
+    AgeMs     int64     `json:"age_ms"`     // 距今毫秒数
+    State     string    `json:"state"`      // fresh/stale
+}
+
 // ClientRequest 客户端请求结构（草案Section 8）
 type ClientRequest struct {
     ServiceID     string `json:"service_id"`     // 目标服务ID
     MaxAcceptCost int    `json:"max_accept_cost"`// 最大可接受成本
     MaxAcceptDelay int   `json:"max_accept_delay"`// 最大可接受延迟（ms）
 }
+
+// ServiceSample 对应草案Table 2（Platform私有的数据样本/预期结果表）
+// 仅用于部署验证，不通过公共服务表接口对外暴露
+type ServiceSample struct {
+    ServiceID  string    `json:"service_id"`  // 服务ID
+    SampleName string    `json:"sample_name"` // 样本文件名
+    Sample     []byte    `json:"sample"`      // 样本内容
+    ResultName string    `json:"result_name"` // 预期结果文件名
+    Result     []byte    `json:"result"`      // 预期结果内容
+    UpdatedAt  time.Time `json:"updated_at"`  // 最近一次更新（轮换）时间
+}
+
+// 部署实例验证状态（草案Figure 2：验证通过后才可向C-SMA通告）
+const (
+    ValidationPending   = "pending"   // 验证中
+    ValidationValidated = "validated" // 验证通过
+    ValidationFailed    = "failed"    // 验证失败
+)
+
+// InstanceValidation 部署实例的验证记录
+type InstanceValidation struct {
+    ServiceID     string    `json:"service_id"`     // 服务ID
+    ContainerName string    `json:"container_name"` // 容器名（cmas-前缀）
+    CSCIID        string    `json:"csci_id"`        // 实例访问地址（IP:端口）
+    Status        string    `json:"status"`         // 验证状态（pending/validated/failed）
+    Detail        string    `json:"detail"`         // 验证说明（失败原因等）
+    UpdatedAt     time.Time `json:"updated_at"`     // 状态更新时间
+    DeployedBy    string    `json:"deployed_by"`    // 发起部署的身份ID
+    DeployerAuth  *AuthResponse `json:"deployer_auth,omitempty"` // 部署方的Auth_RESPONSE
+}
+
+// 身份角色（草案中的服务提供者与Site）
+const (
+    RoleProvider = "provider" // 服务提供者
+    RoleSite     = "site"     // 部署服务的Site
+)
+
+// Identity Platform登记的提供者/Site身份
+type Identity struct {
+    ID         string    `json:"id"`             // 身份ID
+    Role       string    `json:"role"`           // 角色（provider/site）
+    Name       string    `json:"name"`           // 名称
+    APIKeyHash string    `json:"api_key_sha256"` // API Key的SHA-256（不保存明文）
+    CreatedAt  time.Time `json:"created_at"`     // 创建时间
+}
+
+// SigningKey 提供者登记的代码包签名公钥
+type SigningKey struct {
+    ID         string    `json:"id"`                   // 公钥ID（key-加公钥指纹）
+    ProviderID string    `json:"provider_id"`          // 所属提供者身份ID
+    Algorithm  string    `json:"algorithm"`            // 签名算法（ed25519）
+    PublicKey  string    `json:"public_key"`           // base64编码的原始公钥
+    Comment    string    `json:"comment,omitempty"`    // 备注
+    Revoked    bool      `json:"revoked"`              // 是否已吊销
+    CreatedAt  time.Time `json:"created_at"`           // 登记时间
+    RevokedAt  time.Time `json:"revoked_at,omitempty"` // 吊销时间
+}
+
+// BundleSignature 代码包签名（对代码包SHA-256十六进制摘要签名）
+type BundleSignature struct {
+    Digest     string    `json:"digest"`      // 代码包SHA-256
+    Signature  string    `json:"signature"`   // base64编码的签名
+    KeyID      string    `json:"key_id"`      // 签名公钥ID
+    Algorithm  string    `json:"algorithm"`   // 签名算法（ed25519）
+    SignedBy   string    `json:"signed_by"`   // 签名提供者身份ID
+    VerifiedAt time.Time `json:"verified_at"` // Platform上传时校验通过的时间
+}
+
+// AuthResponse 认证握手中对方回复的Auth_RESPONSE（草案Figure 1/2）
+type AuthResponse struct {
+    IP     string            `json:"ip"`               // 对方IP
+    Domain string            `json:"domain"`           // 域名
+    Host   string            `json:"host"`             // 主机名
+    Port   int               `json:"port"`             // 端口
+    Params map[string]string `json:"params,omitempty"` // 其他参数
+}
+
+// Application Site通过Apply(service id)申请部署服务的记录（草案Figure 2）
+type Application struct {
+    ID        string        `json:"id"`                  // 申请记录ID
+    ServiceID string        `json:"service_id"`          // 申请的服务ID
+    SiteID    string        `json:"site_id"`             // 申请方Site身份ID
+    SiteAuth  *AuthResponse `json:"site_auth,omitempty"` // 申请方的Auth_RESPONSE
+    AppliedAt time.Time     `json:"applied_at"`          // 申请时间
+}
+
+// Lease Platform分配给容器的IP/宿主机端口租约
+type Lease struct {
+    ContainerName string    `json:"container_name"` // 容器名
+    ContainerIP   string    `json:"container_ip"`   // 容器IP（cmas-network）
+    HostPort      string    `json:"host_port"`      // 宿主机端口
+    CreatedAt     time.Time `json:"created_at"`     // 分配时间
+}
+
+// UploadRecord 代码上传记录
+type UploadRecord struct {
+    ID         string    `json:"id"`          // 上传记录ID
+    FileName   string    `json:"file_name"`   // 上传文件名
+    Path       string    `json:"path"`        // 保存路径（未通过检查时为空）
+    ServiceID  string    `json:"service_id"`  // 归属服务ID
+    UploadedBy string    `json:"uploaded_by"` // 上传者身份ID
+    SHA256     string    `json:"sha256"`      // 文件SHA-256
+    Size       int64     `json:"size"`        // 文件大小
+    Pass       bool      `json:"pass"`        // 是否通过安全检查
+    Reason     string    `json:"reason"`      // 检查结论
+    ScanID     string    `json:"scan_id"`     // 安全检查报告ID（解压失败时为空）
+    CreatedAt  time.Time `json:"created_at"`  // 上传时间
+}
+
+// 安全检查抑制状态
+const (
+    SuppressionPending  = "pending"  // 待管理员审批
+    SuppressionApproved = "approved" // 已批准，匹配的发现不再阻断上传
+    SuppressionRejected = "rejected" // 已驳回
+)
+
+// 抑制来源
+const (
+    SuppressionSourceInline   = "inline"   // 代码中的cmas-ignore注释
+    SuppressionSourceManifest = "manifest" // 代码包中的cmas-suppressions清单
+    SuppressionSourceAPI      = "api"      // 通过接口提交
+)
+
+// Suppression 安全检查抑制（提供者申请，管理员审批后生效）
+type Suppression struct {
+    ID          string    `json:"id"`                    // 抑制ID
+    ServiceID   string    `json:"service_id"`            // 适用的服务（空表示全局，仅管理员可创建）
+    Rule        string    `json:"rule"`                  // 规则ID
+    File        string    `json:"file"`                  // 文件路径或glob（空表示任意文件）
+    Fingerprint string    `json:"fingerprint,omitempty"` // 指定某一条发现（空表示匹配文件中该规则的所有发现）
+    Reason      string    `json:"reason"`                // 申请理由
+    Source      string    `json:"source"`                // 来源（inline/manifest/api）
+    Status      string    `json:"status"`                // 审批状态
+    RequestedBy string    `json:"requested_by"`          // 申请者身份ID
+    ReviewedBy  string    `json:"reviewed_by,omitempty"` // 审批人
+    ReviewNote  string    `json:"review_note,omitempty"` // 审批意见
+    CreatedAt   time.Time `json:"created_at"`            // 申请时间
+    ReviewedAt  time.Time `json:"reviewed_at,omitempty"` // 审批时间
+}
@@ -0,0 +1,120 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const recordExt = ".json"
+
+// FileStore 嵌入式文件存储（无需外部数据库）
+// 目录结构：<root>/<bucket>/<转义后的key>.json，每条记录一个文件
+// 写入先写临时文件再rename，进程崩溃不会留下半条记录
+type FileStore struct {
+	mu   sync.RWMutex
+	root string
+}
+
+// OpenFileStore 打开（必要时创建）文件存储目录
+func OpenFileStore(root string) (*FileStore, error) {
+	if root == "" {
+		return nil, fmt.Errorf("存储目录不能为空")
+	}
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, fmt.Errorf("创建存储目录%s失败：%v", root, err)
+	}
+	return &FileStore{root: root}, nil
+}
+
+// recordPath 记录文件路径（key经过转义，避免路径穿越）
+func (f *FileStore) recordPath(bucket, key string) string {
+	return filepath.Join(f.root, url.PathEscape(bucket), url.PathEscape(key)+recordExt)
+}
+
+func (f *FileStore) Get(bucket, key string, v interface{}) (bool, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	data, err := os.ReadFile(f.recordPath(bucket, key))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, json.Unmarshal(data, v)
+}
+
+func (f *FileStore) Put(bucket, key string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	path := f.recordPath(bucket, key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // rename成功后为空操作
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (f *FileStore) Delete(bucket, key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	err := os.Remove(f.recordPath(bucket, key))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func (f *FileStore) Keys(bucket string) ([]string, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	entries, err := os.ReadDir(filepath.Join(f.root, url.PathEscape(bucket)))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(entries))
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, recordExt) || strings.HasPrefix(name, ".tmp-") {
+			continue
+		}
+		key, err := url.PathUnescape(strings.TrimSuffix(name, recordExt))
+		if err != nil {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (f *FileStore) Close() error { return nil }
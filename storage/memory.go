@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// MemoryStore 内存存储（测试用）
+// 与文件存储一样按JSON编码保存，保证读写得到的是副本
+type MemoryStore struct {
+	mu      sync.RWMutex
+	buckets map[string]map[string][]byte
+}
+
+// NewMemoryStore 创建内存存储
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]map[string][]byte)}
+}
+
+func (m *MemoryStore) Get(bucket, key string, v interface{}) (bool, error) {
+	m.mu.RLock()
+	data, ok := m.buckets[bucket][key]
+	m.mu.RUnlock()
+	if !ok {
+		return false, nil
+	}
+	return true, json.Unmarshal(data, v)
+}
+
+func (m *MemoryStore) Put(bucket, key string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.buckets[bucket] == nil {
+		m.buckets[bucket] = make(map[string][]byte)
+	}
+	m.buckets[bucket][key] = data
+	return nil
+}
+
+func (m *MemoryStore) Delete(bucket, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.buckets[bucket], key)
+	return nil
+}
+
+func (m *MemoryStore) Keys(bucket string) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return sortedKeys(m.buckets[bucket]), nil
+}
+
+func (m *MemoryStore) Close() error { return nil }
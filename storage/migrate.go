@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const schemaVersionKey = "schema_version"
+
+// migration 一次schema迁移（version为迁移完成后的版本号）
+type migration struct {
+	version int
+	desc    string
+	up      func(s Store) error
+}
+
+// migrations 按版本顺序排列，只能追加，不能修改已发布的迁移
+var migrations = []migration{
+	{1, "初始schema", func(s Store) error { return nil }},
+	{2, "服务比对方式显式化（空值补为numeric）", backfillComparatorType},
+}
+
+// SchemaVersion 当前代码支持的最新schema版本
+func SchemaVersion() int {
+	return migrations[len(migrations)-1].version
+}
+
+// Migrate 把存储升级到最新schema版本
+// 存储版本高于代码版本时拒绝打开，避免旧版本程序写坏新数据
+func Migrate(s Store) error {
+	var current int
+	if _, err := s.Get(BucketMeta, schemaVersionKey, &current); err != nil {
+		return fmt.Errorf("读取schema版本失败：%v", err)
+	}
+	if current > SchemaVersion() {
+		return fmt.Errorf("存储schema版本%d高于程序支持的版本%d", current, SchemaVersion())
+	}
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		if err := m.up(s); err != nil {
+			return fmt.Errorf("schema迁移到v%d（%s）失败：%v", m.version, m.desc, err)
+		}
+		if err := s.Put(BucketMeta, schemaVersionKey, m.version); err != nil {
+			return err
+		}
+		fmt.Printf("[STORAGE] schema已迁移到v%d：%s\n", m.version, m.desc)
+	}
+	return nil
+}
+
+// backfillComparatorType v2：服务记录中comparator.type为空的补为numeric
+// 记录按原始JSON处理，避免依赖会继续演进的models结构
+func backfillComparatorType(s Store) error {
+	records, err := LoadAll[map[string]json.RawMessage](s, BucketServices)
+	if err != nil {
+		return err
+	}
+	for key, rec := range records {
+		raw, ok := rec["service"]
+		if !ok {
+			continue
+		}
+		var svc map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &svc); err != nil {
+			return err
+		}
+		var cmp map[string]interface{}
+		if c, ok := svc["comparator"]; ok {
+			json.Unmarshal(c, &cmp)
+		}
+		if cmp == nil {
+			cmp = map[string]interface{}{}
+		}
+		if t, _ := cmp["type"].(string); t != "" {
+			continue
+		}
+		cmp["type"] = "numeric"
+		svc["comparator"], _ = json.Marshal(cmp)
+		rec["service"], _ = json.Marshal(svc)
+		if err := s.Put(BucketServices, key, rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
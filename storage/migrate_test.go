@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMigrateBackfillsComparatorType(t *testing.T) {
+	s := NewMemoryStore()
+	records := map[string]string{
+		"S1": `{"service":{"id":"S1"}}`,                                // 没有comparator
+		"S2": `{"service":{"id":"S2","comparator":{"tolerance":0.5}}}`, // type为空
+		"S3": `{"service":{"id":"S3","comparator":{"type":"image"}}}`,  // 已声明，保持不变
+		"S4": `{"other":true}`,                                         // 没有service字段，跳过
+	}
+	for key, raw := range records {
+		if err := s.Put(BucketServices, key, json.RawMessage(raw)); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+	}
+
+	if err := Migrate(s); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	var version int
+	if _, err := s.Get(BucketMeta, schemaVersionKey, &version); err != nil || version != SchemaVersion() {
+		t.Fatalf("schema version = %d, %v, want %d", version, err, SchemaVersion())
+	}
+
+	comparator := func(key string) map[string]interface{} {
+		var rec struct {
+			Service struct {
+				Comparator map[string]interface{} `json:"comparator"`
+			} `json:"service"`
+		}
+		if _, err := s.Get(BucketServices, key, &rec); err != nil {
+			t.Fatalf("Get %s: %v", key, err)
+		}
+		return rec.Service.Comparator
+	}
+	if c := comparator("S1"); c["type"] != "numeric" {
+		t.Errorf("S1 comparator = %v, want type numeric", c)
+	}
+	if c := comparator("S2"); c["type"] != "numeric" || c["tolerance"] != 0.5 {
+		t.Errorf("S2 comparator = %v, want type numeric with tolerance kept", c)
+	}
+	if c := comparator("S3"); c["type"] != "image" {
+		t.Errorf("S3 comparator = %v, want type image", c)
+	}
+	var other map[string]interface{}
+	s.Get(BucketServices, "S4", &other)
+	if _, ok := other["service"]; ok {
+		t.Errorf("S4 gained a service field: %v", other)
+	}
+
+	// 已是最新版本时再次迁移不做任何事
+	if err := Migrate(s); err != nil {
+		t.Fatalf("second Migrate: %v", err)
+	}
+}
+
+func TestMigrateRejectsNewerSchema(t *testing.T) {
+	s := NewMemoryStore()
+	s.Put(BucketMeta, schemaVersionKey, SchemaVersion()+1)
+	if err := Migrate(s); err == nil {
+		t.Fatalf("Migrate accepted a newer schema version")
+	}
+}
@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+)
+
+// 存储桶（每类Platform状态一个桶）
+const (
+	BucketMeta         = "meta"         // 元数据（schema版本等）
+	BucketServices     = "services"     // 公共服务表（草案Table 1）
+	BucketSamples      = "samples"      // 私有样本/预期结果表（草案Table 2）
+	BucketDeployments  = "deployments"  // 部署实例及验证状态
+	BucketLeases       = "leases"       // 容器IP/宿主机端口租约
+	BucketUploads      = "uploads"      // 代码上传记录
+	BucketIdentities   = "identities"   // 提供者/Site身份
+	BucketApplications = "applications" // Site申请记录
+	BucketSuppressions = "suppressions" // 安全检查抑制申请
+	BucketScans        = "scans"        // 安全检查报告
+	BucketSigningKeys  = "signing_keys" // 提供者代码包签名公钥
+)
+
+// Store Platform状态存储接口
+// 值以JSON编码保存，Get/Put传入的v与encoding/json用法一致
+type Store interface {
+	// Get 读取记录到v，记录不存在时返回false
+	Get(bucket, key string, v interface{}) (bool, error)
+	// Put 写入（覆盖）记录
+	Put(bucket, key string, v interface{}) error
+	// Delete 删除记录，记录不存在时不报错
+	Delete(bucket, key string) error
+	// Keys 列出桶内所有键（已排序）
+	Keys(bucket string) ([]string, error)
+	// Close 关闭存储
+	Close() error
+}
+
+// 存储驱动
+const (
+	DriverFile   = "file"   // 嵌入式文件存储（默认）
+	DriverMemory = "memory" // 内存存储（测试用，重启即丢失）
+)
+
+// Open 按驱动打开存储并执行schema迁移
+func Open(driver, path string) (Store, error) {
+	var s Store
+	var err error
+	switch driver {
+	case DriverFile, "":
+		s, err = OpenFileStore(path)
+	case DriverMemory:
+		s = NewMemoryStore()
+	default:
+		return nil, fmt.Errorf("未知的存储驱动：%s", driver)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := Migrate(s); err != nil {
+		s.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// LoadAll 读取桶内全部记录
+func LoadAll[T any](s Store, bucket string) (map[string]T, error) {
+	keys, err := s.Keys(bucket)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]T, len(keys))
+	for _, key := range keys {
+		var v T
+		ok, err := s.Get(bucket, key, &v)
+		if err != nil {
+			return nil, fmt.Errorf("读取%s/%s失败：%v", bucket, key, err)
+		}
+		if ok {
+			result[key] = v
+		}
+	}
+	return result, nil
+}
+
+// sortedKeys 返回map键的有序列表
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
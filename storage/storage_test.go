@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"reflect"
+	"testing"
+)
+
+type testRecord struct {
+	Name  string            `json:"name"`
+	Count int               `json:"count"`
+	Tags  map[string]string `json:"tags"`
+}
+
+// testStores 两种存储各一份（文件存储使用临时目录）
+func testStores(t *testing.T) map[string]Store {
+	t.Helper()
+	fs, err := OpenFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("OpenFileStore: %v", err)
+	}
+	return map[string]Store{DriverFile: fs, DriverMemory: NewMemoryStore()}
+}
+
+func TestStoreRoundTrip(t *testing.T) {
+	for driver, s := range testStores(t) {
+		t.Run(driver, func(t *testing.T) {
+			want := testRecord{Name: "S1", Count: 3, Tags: map[string]string{"k": "v"}}
+			if err := s.Put(BucketServices, "S1", want); err != nil {
+				t.Fatalf("Put: %v", err)
+			}
+			// 含路径分隔符的键也只落在桶内
+			if err := s.Put(BucketServices, "../S2", testRecord{Name: "S2"}); err != nil {
+				t.Fatalf("Put: %v", err)
+			}
+
+			var got testRecord
+			ok, err := s.Get(BucketServices, "S1", &got)
+			if err != nil || !ok {
+				t.Fatalf("Get = %v, %v", ok, err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("Get = %+v, want %+v", got, want)
+			}
+
+			// 读出的是副本，修改不影响存储内容
+			got.Tags["k"] = "changed"
+			var again testRecord
+			s.Get(BucketServices, "S1", &again)
+			if again.Tags["k"] != "v" {
+				t.Fatalf("stored record was modified through a read copy")
+			}
+
+			keys, err := s.Keys(BucketServices)
+			if err != nil {
+				t.Fatalf("Keys: %v", err)
+			}
+			if want := []string{"../S2", "S1"}; !reflect.DeepEqual(keys, want) {
+				t.Fatalf("Keys = %v, want %v", keys, want)
+			}
+
+			all, err := LoadAll[testRecord](s, BucketServices)
+			if err != nil || len(all) != 2 || all["../S2"].Name != "S2" {
+				t.Fatalf("LoadAll = %v, %v", all, err)
+			}
+
+			if err := s.Delete(BucketServices, "S1"); err != nil {
+				t.Fatalf("Delete: %v", err)
+			}
+			if err := s.Delete(BucketServices, "missing"); err != nil {
+				t.Fatalf("Delete missing key: %v", err)
+			}
+			if ok, err := s.Get(BucketServices, "S1", &got); ok || err != nil {
+				t.Fatalf("Get after Delete = %v, %v", ok, err)
+			}
+			if keys, _ := s.Keys(BucketSamples); len(keys) != 0 {
+				t.Fatalf("Keys of empty bucket = %v", keys)
+			}
+		})
+	}
+}
+
+func TestFileStorePersists(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(DriverFile, dir)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if err := s.Put(BucketLeases, "cmas-S1", testRecord{Name: "lease"}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	s.Close()
+
+	reopened, err := Open(DriverFile, dir)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	var got testRecord
+	if ok, err := reopened.Get(BucketLeases, "cmas-S1", &got); !ok || err != nil || got.Name != "lease" {
+		t.Fatalf("Get after reopen = %+v, %v, %v", got, ok, err)
+	}
+}
+
+func TestOpenUnknownDriver(t *testing.T) {
+	if _, err := Open("bolt", t.TempDir()); err == nil {
+		t.Fatalf("Open with unknown driver succeeded")
+	}
+}
@@ -0,0 +1,475 @@
+package utils
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// ExtractLimits 解压限制（防止压缩炸弹耗尽磁盘）
+type ExtractLimits struct {
+	MaxTotalSize int64          // 解压后总大小上限（字节，含嵌套压缩包展开的内容）
+	MaxFiles     int            // 文件数上限（含嵌套压缩包中的文件）
+	MaxRatio     float64        // 压缩比上限（解压后大小/压缩包大小，逐层及整体检查）
+	RatioFloor   int64          // 解压后大小不超过该值时不检查压缩比（避免误伤全零权重等小而高度可压缩的文件）
+	MaxDepth     int            // 嵌套压缩包展开层数上限（0表示不展开嵌套压缩包）
+	Budget       *StagingBudget // 暂存区全局磁盘配额（为空表示不限制）
+}
+
+// DefaultExtractLimits 默认解压限制
+var DefaultExtractLimits = ExtractLimits{
+	MaxTotalSize: 512 << 20, // 512MB
+	MaxFiles:     10000,
+	MaxRatio:     100,
+	RatioFloor:   1 << 20, // 1MB
+	MaxDepth:     3,
+}
+
+// ErrUnsupportedArchive 不支持的压缩包格式
+var ErrUnsupportedArchive = errors.New("不支持的压缩包格式（仅支持.zip、.tar和.tar.gz）")
+
+// ErrStagingBudget 暂存区磁盘配额已用尽
+var ErrStagingBudget = errors.New("解压暂存区磁盘配额已用尽，请稍后重试")
+
+// 压缩包类型
+const (
+	ArchiveZip   = "zip"
+	ArchiveTar   = "tar"
+	ArchiveTarGz = "tar.gz"
+)
+
+// nestedSuffix 嵌套压缩包展开目录的后缀（a.zip展开到a.zip.contents/）
+const nestedSuffix = ".contents"
+
+// ArchiveViolation 解压时发现的压缩炸弹或嵌套过深，作为安全检查威胁上报
+type ArchiveViolation struct {
+	Rule    string // archive-bomb / archive-nesting
+	Entry   string // 触发的条目（嵌套时为 外层/内层.zip!条目 形式）
+	Message string
+}
+
+func (v *ArchiveViolation) Error() string {
+	if v.Entry == "" {
+		return v.Message
+	}
+	return fmt.Sprintf("%s（%s）", v.Message, v.Entry)
+}
+
+// ArchiveViolationResult 解压被中止时的安全检查结果（违规不受阈值与抑制影响，始终不通过）
+func ArchiveViolationResult(fileName string, v *ArchiveViolation, rules *RuleSet) *SecurityCheckResult {
+	if rules == nil {
+		rules = DefaultRuleSet()
+	}
+	f := SecurityFinding{File: v.Entry, Rule: v.Rule, Severity: rules.Severity(v.Rule), Message: v.Message, Blocking: true}
+	f.Fingerprint = FindingFingerprint(f)
+	return &SecurityCheckResult{
+		Pass:         false,
+		Reason:       f.String(),
+		Threats:      []string{f.String()},
+		FileName:     fileName,
+		FileType:     "archive",
+		Files:        []FileReport{{Path: v.Entry, Kind: FileKindBinary, Findings: []SecurityFinding{f}}},
+		Findings:     []SecurityFinding{f},
+		RulesVersion: rules.Version(),
+		Threshold:    DefaultFailSeverity,
+	}
+}
+
+// StagingBudget 解压暂存区的全局磁盘配额，所有并发上传共享
+// 解压时按实际写入的字节计入所在暂存目录，Remove删除目录时归还
+type StagingBudget struct {
+	mu    sync.Mutex
+	limit int64
+	used  int64
+	dirs  map[string]int64
+}
+
+// NewStagingBudget 创建暂存区配额（limit<=0表示不限制）
+func NewStagingBudget(limit int64) *StagingBudget {
+	return &StagingBudget{limit: limit, dirs: map[string]int64{}}
+}
+
+// Used 已占用的字节数
+func (b *StagingBudget) Used() int64 {
+	if b == nil {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.used
+}
+
+// reserve 为暂存目录占用n字节
+func (b *StagingBudget) reserve(dir string, n int64) error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.limit > 0 && b.used+n > b.limit {
+		return ErrStagingBudget
+	}
+	b.used += n
+	b.dirs[dir] += n
+	return nil
+}
+
+// Remove 删除暂存目录并归还其占用的配额
+func (b *StagingBudget) Remove(dir string) error {
+	err := os.RemoveAll(dir)
+	if b != nil {
+		b.mu.Lock()
+		b.used -= b.dirs[dir]
+		delete(b.dirs, dir)
+		b.mu.Unlock()
+	}
+	return err
+}
+
+// DetectArchive 按文件头识别压缩包类型（不依赖扩展名）
+func DetectArchive(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(f, head)
+	head = head[:n]
+	switch {
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")), bytes.HasPrefix(head, []byte("PK\x05\x06")):
+		return ArchiveZip, nil
+	case bytes.HasPrefix(head, []byte{0x1f, 0x8b}):
+		return ArchiveTarGz, nil
+	case isTarHeader(head):
+		return ArchiveTar, nil
+	}
+	return "", ErrUnsupportedArchive
+}
+
+// isTarHeader 是否为ustar格式的tar头
+func isTarHeader(head []byte) bool {
+	return len(head) >= 263 && bytes.Equal(head[257:262], []byte("ustar"))
+}
+
+// ExtractToStaging 把压缩包解压到stagingRoot下独立的临时目录
+// 每次上传使用单独目录，并发上传互不覆盖；解压失败时自动清理该目录并归还配额
+// 调用方用完后应通过limits.Budget.Remove删除目录
+func ExtractToStaging(archivePath, stagingRoot string, limits ExtractLimits) (string, error) {
+	if err := os.MkdirAll(stagingRoot, 0755); err != nil {
+		return "", fmt.Errorf("创建解压目录失败：%v", err)
+	}
+	dir, err := os.MkdirTemp(stagingRoot, "upload-*")
+	if err != nil {
+		return "", fmt.Errorf("创建解压目录失败：%v", err)
+	}
+	if err := ExtractArchive(archivePath, dir, limits); err != nil {
+		limits.Budget.Remove(dir)
+		return "", err
+	}
+	return dir, nil
+}
+
+// ExtractArchive 解压.zip/.tar/.tar.gz到destDir，并在MaxDepth层内展开嵌套的压缩包
+// 拒绝路径穿越、绝对路径、符号链接/硬链接及设备文件；
+// 总大小、文件数与压缩比超限或嵌套过深时返回*ArchiveViolation
+func ExtractArchive(archivePath, destDir string, limits ExtractLimits) error {
+	info, err := os.Stat(archivePath)
+	if err != nil {
+		return err
+	}
+	kind, err := DetectArchive(archivePath)
+	if err != nil {
+		return err
+	}
+	st := &extractState{limits: limits, budgetDir: destDir, rootSize: info.Size()}
+	x := &extractor{state: st, dest: destDir, archiveSize: info.Size()}
+	return x.extract(archivePath, kind)
+}
+
+// extractState 一次解压（含所有嵌套层）共享的状态
+type extractState struct {
+	limits    ExtractLimits
+	budgetDir string // 计入配额的暂存目录
+	rootSize  int64  // 最外层压缩包大小
+	totalSize int64  // 累计写出的字节数
+	files     int    // 累计文件数
+}
+
+// extractor 单层压缩包的解压状态
+type extractor struct {
+	state       *extractState
+	dest        string
+	archiveSize int64
+	written     int64  // 本层写出的字节数
+	depth       int    // 嵌套层数（最外层为0）
+	prefix      string // 本层压缩包在最外层中的位置（最外层为空）
+}
+
+// entryName 条目在最外层压缩包中的位置
+func (x *extractor) entryName(name string) string {
+	if x.prefix == "" {
+		return name
+	}
+	return x.prefix + "!" + name
+}
+
+// bomb 压缩炸弹违规
+func (x *extractor) bomb(name, format string, args ...any) error {
+	return &ArchiveViolation{Rule: "archive-bomb", Entry: x.entryName(name), Message: fmt.Sprintf(format, args...)}
+}
+
+func (x *extractor) extract(archivePath, kind string) error {
+	switch kind {
+	case ArchiveZip:
+		return x.extractZip(archivePath)
+	case ArchiveTar:
+		f, err := os.Open(archivePath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return x.extractTar(f)
+	default:
+		return x.extractGzip(archivePath)
+	}
+}
+
+// target 计算条目的落盘路径，拒绝绝对路径和穿越到destDir之外的路径
+func (x *extractor) target(name string) (string, error) {
+	name = strings.ReplaceAll(name, "\\", "/")
+	if name == "" || strings.HasPrefix(name, "/") || filepath.IsAbs(name) || filepath.VolumeName(name) != "" {
+		return "", fmt.Errorf("压缩包条目路径非法：%q", x.entryName(name))
+	}
+	clean := filepath.Clean(filepath.FromSlash(name))
+	if clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("压缩包条目存在路径穿越：%q", x.entryName(name))
+	}
+	return filepath.Join(x.dest, clean), nil
+}
+
+// addFile 登记一个文件并检查文件数上限
+func (x *extractor) addFile(name string) error {
+	x.state.files++
+	if max := x.state.limits.MaxFiles; max > 0 && x.state.files > max {
+		return x.bomb(name, "压缩包文件数超过上限%d", max)
+	}
+	return nil
+}
+
+// checkSize 检查累计解压大小、本层压缩比与整体压缩比
+func (x *extractor) checkSize(name string) error {
+	st := x.state
+	if st.limits.MaxTotalSize > 0 && st.totalSize > st.limits.MaxTotalSize {
+		return x.bomb(name, "解压后总大小超过上限%dMB", st.limits.MaxTotalSize>>20)
+	}
+	if max := st.limits.MaxRatio; max > 0 {
+		floor := st.limits.RatioFloor
+		if x.archiveSize > 0 && x.written > floor && float64(x.written)/float64(x.archiveSize) > max {
+			return x.bomb(name, "压缩比超过上限%.0f", max)
+		}
+		if st.rootSize > 0 && st.totalSize > floor && float64(st.totalSize)/float64(st.rootSize) > max {
+			return x.bomb(name, "整体压缩比超过上限%.0f", max)
+		}
+	}
+	return nil
+}
+
+// writeFile 写出单个文件，边写边统计实际大小（不信任压缩包头中声明的大小），写完后展开嵌套压缩包
+func (x *extractor) writeFile(path, name string, r io.Reader) error {
+	if err := x.copyFile(path, name, r); err != nil {
+		return err
+	}
+	return x.nested(path, name)
+}
+
+func (x *extractor) copyFile(path, name string, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// 最多多读1字节，用于判断是否超限
+	st := x.state
+	if st.limits.MaxTotalSize > 0 {
+		r = io.LimitReader(r, st.limits.MaxTotalSize-st.totalSize+1)
+	}
+	buf := make([]byte, 32*1024)
+	for {
+		n, rerr := r.Read(buf)
+		if n > 0 {
+			if err := st.limits.Budget.reserve(st.budgetDir, int64(n)); err != nil {
+				return err
+			}
+			if _, err := f.Write(buf[:n]); err != nil {
+				return err
+			}
+			st.totalSize += int64(n)
+			x.written += int64(n)
+			if err := x.checkSize(name); err != nil {
+				return err
+			}
+		}
+		if rerr == io.EOF {
+			return nil
+		}
+		if rerr != nil {
+			return rerr
+		}
+	}
+}
+
+// nested 把嵌套的压缩包展开到同级的<文件名>.contents/目录，使其内容参与安全检查
+// 能按扩展名识别的模型文件（如.keras）由模型检查处理，不在此展开
+func (x *extractor) nested(path, name string) error {
+	if x.state.limits.MaxDepth <= 0 || detectModelFormat(name, nil) != "" {
+		return nil
+	}
+	kind, err := DetectArchive(path)
+	if err != nil {
+		return nil // 不是压缩包
+	}
+	entry := x.entryName(name)
+	if x.depth+1 > x.state.limits.MaxDepth {
+		return &ArchiveViolation{Rule: "archive-nesting", Entry: entry,
+			Message: fmt.Sprintf("压缩包嵌套超过%d层", x.state.limits.MaxDepth)}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	dest := path + nestedSuffix
+	if err := os.Mkdir(dest, 0755); err != nil {
+		return fmt.Errorf("展开嵌套压缩包%q失败：%v", entry, err)
+	}
+	child := &extractor{state: x.state, dest: dest, archiveSize: info.Size(), depth: x.depth + 1, prefix: entry}
+	return child.extract(path, kind)
+}
+
+func (x *extractor) extractZip(archivePath string) error {
+	zr, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return fmt.Errorf("读取zip失败：%v", err)
+	}
+	defer zr.Close()
+
+	for _, zf := range zr.File {
+		path, err := x.target(zf.Name)
+		if err != nil {
+			return err
+		}
+		mode := zf.Mode()
+		switch {
+		case mode&os.ModeSymlink != 0:
+			return fmt.Errorf("压缩包包含符号链接：%q", x.entryName(zf.Name))
+		case mode.IsDir():
+			if err := os.MkdirAll(path, 0755); err != nil {
+				return err
+			}
+			continue
+		case !mode.IsRegular():
+			return fmt.Errorf("压缩包包含非普通文件：%q", x.entryName(zf.Name))
+		}
+		if err := x.addFile(zf.Name); err != nil {
+			return err
+		}
+		// 单个条目声明的压缩比过高时直接拒绝，无需解压（声明大小不超过下限的小文件不检查）
+		if max := x.state.limits.MaxRatio; max > 0 && zf.CompressedSize64 > 0 &&
+			zf.UncompressedSize64 > uint64(x.state.limits.RatioFloor) &&
+			float64(zf.UncompressedSize64)/float64(zf.CompressedSize64) > max {
+			return x.bomb(zf.Name, "条目压缩比超过上限%.0f", max)
+		}
+		rc, err := zf.Open()
+		if err != nil {
+			return fmt.Errorf("读取条目%q失败：%v", x.entryName(zf.Name), err)
+		}
+		err = x.writeFile(path, zf.Name, rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extractGzip 解压gzip：内容为tar时按tar解包，否则作为单个文件（去掉.gz后缀）写出
+func (x *extractor) extractGzip(archivePath string) error {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("读取gzip失败：%v", err)
+	}
+	defer gz.Close()
+
+	br := bufio.NewReaderSize(gz, 512)
+	head, _ := br.Peek(512)
+	if isTarHeader(head) {
+		return x.extractTar(br)
+	}
+	base := filepath.Base(archivePath)
+	name := strings.TrimSuffix(base, ".gz")
+	if name == base || name == "" {
+		name = base + ".out"
+	}
+	if err := x.addFile(name); err != nil {
+		return err
+	}
+	target, err := x.target(name)
+	if err != nil {
+		return err
+	}
+	return x.writeFile(target, name, br)
+}
+
+func (x *extractor) extractTar(r io.Reader) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("读取tar失败：%v", err)
+		}
+		path, err := x.target(hdr.Name)
+		if err != nil {
+			return err
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(path, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := x.addFile(hdr.Name); err != nil {
+				return err
+			}
+			if err := x.writeFile(path, hdr.Name, tr); err != nil {
+				return err
+			}
+		case tar.TypeSymlink, tar.TypeLink:
+			return fmt.Errorf("压缩包包含链接：%q", x.entryName(hdr.Name))
+		case tar.TypeXGlobalHeader, tar.TypeXHeader:
+			// PAX扩展头，tar.Reader已处理
+		default:
+			return fmt.Errorf("压缩包包含非普通文件：%q", x.entryName(hdr.Name))
+		}
+	}
+}
@@ -0,0 +1,285 @@
+package utils
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeZip 按给定条目写出zip压缩包（Deflate压缩）
+func writeZip(t *testing.T, path string, entries map[string][]byte) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	for name, data := range entries {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExtractBenignSparseFile(t *testing.T) {
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "service.zip")
+	writeZip(t, archive, map[string][]byte{
+		"requirements.txt":  []byte("numpy==1.26.4\n"),
+		"app.py":            []byte("import numpy as np\n\nweights = np.load('weights/zeros.npy')\n"),
+		"weights/zeros.npy": make([]byte, 20<<10),
+	})
+
+	dest := filepath.Join(dir, "out")
+	if err := ExtractArchive(archive, dest, DefaultExtractLimits); err != nil {
+		t.Fatalf("benign archive rejected: %v", err)
+	}
+	info, err := os.Stat(filepath.Join(dest, "weights", "zeros.npy"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != 20<<10 {
+		t.Errorf("zeros.npy size = %d, want %d", info.Size(), 20<<10)
+	}
+}
+
+func TestExtractRejectsRatioBomb(t *testing.T) {
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "bomb.zip")
+	writeZip(t, archive, map[string][]byte{
+		"app.py":    []byte("print('hello')\n"),
+		"zeros.bin": make([]byte, 8<<20),
+	})
+
+	err := ExtractArchive(archive, filepath.Join(dir, "out"), DefaultExtractLimits)
+	var v *ArchiveViolation
+	if !errors.As(err, &v) || v.Rule != "archive-bomb" {
+		t.Fatalf("ExtractArchive = %v, want archive-bomb violation", err)
+	}
+}
+
+// archiveEntry 按顺序写入测试压缩包的条目（Link非空时写成链接）
+type archiveEntry struct {
+	Name     string
+	Data     []byte
+	Link     string
+	Hardlink bool
+}
+
+// writeZipEntries 按顺序写出zip压缩包，Link非空的条目写成符号链接
+func writeZipEntries(t *testing.T, path string, entries []archiveEntry) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	for _, e := range entries {
+		hdr := &zip.FileHeader{Name: e.Name, Method: zip.Store}
+		hdr.SetMode(0644)
+		data := e.Data
+		if e.Link != "" {
+			hdr.SetMode(os.ModeSymlink | 0777)
+			data = []byte(e.Link)
+		}
+		w, err := zw.CreateHeader(hdr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// writeTar 按顺序写出tar（gz为true时写出tar.gz）压缩包
+func writeTar(t *testing.T, path string, gz bool, entries []archiveEntry) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var w io.Writer = f
+	var zw *gzip.Writer
+	if gz {
+		zw = gzip.NewWriter(f)
+		w = zw
+	}
+	tw := tar.NewWriter(w)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.Name, Mode: 0644, Size: int64(len(e.Data)), Typeflag: tar.TypeReg, Format: tar.FormatPAX}
+		if e.Link != "" {
+			hdr.Typeflag, hdr.Linkname, hdr.Size = tar.TypeSymlink, e.Link, 0
+			if e.Hardlink {
+				hdr.Typeflag = tar.TypeLink
+			}
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if e.Link == "" {
+			if _, err := tw.Write(e.Data); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if zw != nil {
+		if err := zw.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// unsafeEntries 路径穿越、绝对路径与链接条目，每个都应使解压失败
+var unsafeEntries = []struct {
+	name  string
+	entry archiveEntry
+}{
+	{"parent", archiveEntry{Name: "../evil.txt", Data: []byte("x")}},
+	{"nested-parent", archiveEntry{Name: "app/../../evil.txt", Data: []byte("x")}},
+	{"backslash-parent", archiveEntry{Name: `app\..\..\evil.txt`, Data: []byte("x")}},
+	{"absolute", archiveEntry{Name: "/tmp/evil.txt", Data: []byte("x")}},
+	{"symlink", archiveEntry{Name: "link", Link: "/etc/passwd"}},
+	{"relative-symlink", archiveEntry{Name: "link", Link: "../../evil.txt"}},
+	{"hardlink", archiveEntry{Name: "link", Link: "/etc/passwd", Hardlink: true}},
+}
+
+func TestExtractRejectsUnsafeEntries(t *testing.T) {
+	for _, kind := range []string{ArchiveZip, ArchiveTar, ArchiveTarGz} {
+		for _, c := range unsafeEntries {
+			if kind == ArchiveZip && c.entry.Hardlink {
+				continue // zip没有硬链接条目
+			}
+			t.Run(kind+"/"+c.name, func(t *testing.T) {
+				dir := t.TempDir()
+				archive := filepath.Join(dir, "upload")
+				entries := []archiveEntry{{Name: "app.py", Data: []byte("print('hello')\n")}, c.entry}
+				if kind == ArchiveZip {
+					writeZipEntries(t, archive, entries)
+				} else {
+					writeTar(t, archive, kind == ArchiveTarGz, entries)
+				}
+				dest := filepath.Join(dir, "a", "b", "out")
+				if err := ExtractArchive(archive, dest, DefaultExtractLimits); err == nil {
+					t.Fatalf("entry %q extracted without error", c.entry.Name)
+				}
+				for _, p := range []string{filepath.Join(dir, "a", "b", "evil.txt"), filepath.Join(dir, "a", "evil.txt"), filepath.Join(dest, "link")} {
+					if _, err := os.Lstat(p); err == nil {
+						t.Errorf("%s was written", p)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestExtractNestedTarGzDepth(t *testing.T) {
+	dir := t.TempDir()
+	inner := filepath.Join(dir, "inner.tar.gz")
+	writeTar(t, inner, true, []archiveEntry{{Name: "hello.txt", Data: []byte("hello\n")}})
+	innerData, err := os.ReadFile(inner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	middle := filepath.Join(dir, "middle.tar.gz")
+	writeTar(t, middle, true, []archiveEntry{{Name: "inner.tar.gz", Data: innerData}})
+	middleData, err := os.ReadFile(middle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	outer := filepath.Join(dir, "outer.tar.gz")
+	writeTar(t, outer, true, []archiveEntry{{Name: "app.py", Data: []byte("print('hello')\n")}, {Name: "middle.tar.gz", Data: middleData}})
+
+	limits := DefaultExtractLimits
+	limits.MaxDepth = 2
+	dest := filepath.Join(dir, "depth2")
+	if err := ExtractArchive(outer, dest, limits); err != nil {
+		t.Fatalf("MaxDepth=2: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "middle.tar.gz.contents", "inner.tar.gz.contents", "hello.txt")); err != nil {
+		t.Errorf("nested file not expanded: %v", err)
+	}
+
+	limits.MaxDepth = 1
+	err = ExtractArchive(outer, filepath.Join(dir, "depth1"), limits)
+	var v *ArchiveViolation
+	if !errors.As(err, &v) || v.Rule != "archive-nesting" {
+		t.Fatalf("MaxDepth=1: err = %v, want archive-nesting violation", err)
+	}
+	if v.Entry != "middle.tar.gz!inner.tar.gz" {
+		t.Errorf("violation entry = %q", v.Entry)
+	}
+
+	limits.MaxDepth = 0
+	dest = filepath.Join(dir, "depth0")
+	if err := ExtractArchive(outer, dest, limits); err != nil {
+		t.Fatalf("MaxDepth=0: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "middle.tar.gz.contents")); err == nil {
+		t.Errorf("nested archive expanded with MaxDepth=0")
+	}
+}
+
+func TestExtractToStagingCleansUpOnFailure(t *testing.T) {
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "upload.zip")
+	// 先写出一个较大的文件占用配额，再遇到非法条目
+	writeZipEntries(t, archive, []archiveEntry{
+		{Name: "weights.bin", Data: make([]byte, 256<<10)},
+		{Name: "../evil.txt", Data: []byte("x")},
+	})
+
+	for _, c := range []struct {
+		name  string
+		limit int64
+	}{
+		{"invalid-entry", 0},
+		{"budget-exhausted", 64 << 10},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			root := filepath.Join(dir, c.name)
+			budget := NewStagingBudget(c.limit)
+			limits := DefaultExtractLimits
+			limits.Budget = budget
+			staging, err := ExtractToStaging(archive, root, limits)
+			if err == nil {
+				budget.Remove(staging)
+				t.Fatal("extraction succeeded")
+			}
+			if c.limit > 0 && !errors.Is(err, ErrStagingBudget) {
+				t.Errorf("err = %v, want ErrStagingBudget", err)
+			}
+			left, err := os.ReadDir(root)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(left) != 0 {
+				t.Errorf("staging directories left behind: %v", left)
+			}
+			if used := budget.Used(); used != 0 {
+				t.Errorf("budget still holds %d bytes", used)
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package utils
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// clamd客户端：通过unix/TCP套接字使用INSTREAM协议送检文件内容
+// 协议：发送"zINSTREAM\0"，随后是若干个[4字节大端长度][数据]块，以长度0结束；
+// clamd回复以\0结尾的一行："stream: OK"、"stream: <特征名> FOUND"或"<原因> ERROR"
+
+const (
+	defaultClamdTimeout   = 30 * time.Second
+	defaultClamdChunkSize = 64 << 10
+)
+
+// ClamdClient clamd INSTREAM客户端
+type ClamdClient struct {
+	Network     string        // unix或tcp
+	Address     string        // 套接字路径或host:port
+	DialTimeout time.Duration // 连接超时
+	Timeout     time.Duration // 单个文件的送检超时（含等待结果）
+	ChunkSize   int           // 每个数据块的大小
+}
+
+// NewClamdClient 创建clamd客户端（timeout为0时使用默认值）
+func NewClamdClient(network, address string, timeout time.Duration) *ClamdClient {
+	if timeout <= 0 {
+		timeout = defaultClamdTimeout
+	}
+	return &ClamdClient{Network: network, Address: address, DialTimeout: timeout, Timeout: timeout, ChunkSize: defaultClamdChunkSize}
+}
+
+// Name 扫描器名称
+func (c *ClamdClient) Name() string {
+	return "clamd"
+}
+
+// dial 连接clamd并设置整体截止时间
+func (c *ClamdClient) dial(ctx context.Context) (net.Conn, error) {
+	d := net.Dialer{Timeout: c.DialTimeout}
+	conn, err := d.DialContext(ctx, c.Network, c.Address)
+	if err != nil {
+		return nil, fmt.Errorf("%w：连接clamd %s失败：%v", ErrScannerUnavailable, c.Address, err)
+	}
+	deadline := time.Now().Add(c.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+	return conn, nil
+}
+
+// command 发送命令并读取以\0结尾的回复
+func (c *ClamdClient) command(ctx context.Context, cmd string, body io.Reader) (string, error) {
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	// ctx取消时立即中断读写
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	if _, err := conn.Write([]byte("z" + cmd + "\x00")); err != nil {
+		return "", fmt.Errorf("%w：发送命令失败：%v", ErrScannerUnavailable, err)
+	}
+	if body != nil {
+		if err := c.stream(conn, body); err != nil {
+			return "", err
+		}
+	}
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil {
+		return "", fmt.Errorf("%w：读取clamd回复失败：%v", ErrScannerUnavailable, err)
+	}
+	return strings.TrimRight(reply, "\x00\n"), nil
+}
+
+// stream 按INSTREAM分块格式发送数据
+func (c *ClamdClient) stream(conn net.Conn, body io.Reader) error {
+	size := c.ChunkSize
+	if size <= 0 {
+		size = defaultClamdChunkSize
+	}
+	buf := make([]byte, 4+size)
+	for {
+		n, err := io.ReadFull(body, buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf[:4], uint32(n))
+			if _, werr := conn.Write(buf[:4+n]); werr != nil {
+				// clamd超过StreamMaxLength时会回复错误并关闭连接，由读取回复处理
+				if isConnReset(werr) {
+					return nil
+				}
+				return fmt.Errorf("%w：发送数据失败：%v", ErrScannerUnavailable, werr)
+			}
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("读取待检数据失败：%v", err)
+		}
+	}
+	if _, err := conn.Write([]byte{0, 0, 0, 0}); err != nil && !isConnReset(err) {
+		return fmt.Errorf("%w：发送结束标记失败：%v", ErrScannerUnavailable, err)
+	}
+	return nil
+}
+
+// isConnReset clamd提前关闭连接（写入被拒绝）
+func isConnReset(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "broken pipe") || strings.Contains(msg, "connection reset")
+}
+
+// Ping 检查clamd是否可用
+func (c *ClamdClient) Ping(ctx context.Context) error {
+	reply, err := c.command(ctx, "PING", nil)
+	if err != nil {
+		return err
+	}
+	if reply != "PONG" {
+		return fmt.Errorf("%w：clamd回复异常：%s", ErrScannerUnavailable, reply)
+	}
+	return nil
+}
+
+// Scan 通过INSTREAM送检文件内容
+func (c *ClamdClient) Scan(ctx context.Context, name string, r io.Reader) ([]ScanMatch, error) {
+	reply, err := c.command(ctx, "INSTREAM", r)
+	if err != nil {
+		return nil, err
+	}
+	return parseClamdReply(reply)
+}
+
+// parseClamdReply 解析INSTREAM回复
+func parseClamdReply(reply string) ([]ScanMatch, error) {
+	// 回复格式"stream: ..."，部分版本带请求编号前缀"1: stream: ..."
+	result := reply
+	if i := strings.LastIndex(reply, ": "); i >= 0 {
+		result = reply[i+2:]
+	}
+	switch {
+	case result == "OK":
+		return nil, nil
+	case strings.HasSuffix(result, " FOUND"):
+		sig := strings.TrimSuffix(result, " FOUND")
+		return []ScanMatch{{Signature: sig}}, nil
+	case strings.HasSuffix(reply, " ERROR"):
+		return nil, fmt.Errorf("clamd返回错误：%s", strings.TrimSuffix(reply, " ERROR"))
+	}
+	return nil, fmt.Errorf("%w：无法解析clamd回复：%s", ErrScannerUnavailable, reply)
+}
+
+var _ Scanner = (*ClamdClient)(nil)
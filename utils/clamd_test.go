@@ -0,0 +1,180 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClamd 在本地TCP端口上模拟clamd的INSTREAM协议，reply根据收到的内容决定回复（返回空串表示不回复）
+func fakeClamd(t *testing.T, reply func(data []byte) string) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serveClamd(conn, reply)
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func serveClamd(conn net.Conn, reply func(data []byte) string) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	cmd, err := r.ReadString(0)
+	if err != nil || cmd != "zINSTREAM\x00" {
+		return
+	}
+	var data []byte
+	for {
+		var size uint32
+		if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+			return
+		}
+		if size == 0 {
+			break
+		}
+		chunk := make([]byte, size)
+		if _, err := io.ReadFull(r, chunk); err != nil {
+			return
+		}
+		data = append(data, chunk...)
+	}
+	msg := reply(data)
+	if msg == "" {
+		// 模拟clamd卡住：不回复，直到客户端超时断开
+		io.Copy(io.Discard, r)
+		return
+	}
+	conn.Write([]byte(msg + "\x00"))
+}
+
+// eicarReply 内容包含测试特征时报告命中，否则回复OK
+func eicarReply(data []byte) string {
+	if bytes.Contains(data, []byte("EICAR-TEST")) {
+		return "stream: Eicar-Test-Signature FOUND"
+	}
+	return "stream: OK"
+}
+
+func TestClamdInstream(t *testing.T) {
+	addr := fakeClamd(t, eicarReply)
+	client := NewClamdClient("tcp", addr, 2*time.Second)
+	client.ChunkSize = 16 // 强制分成多个数据块
+
+	matches, err := client.Scan(context.Background(), "app.py", strings.NewReader("print('hello world')\n"))
+	if err != nil || len(matches) != 0 {
+		t.Fatalf("clean file: matches=%v err=%v", matches, err)
+	}
+
+	matches, err = client.Scan(context.Background(), "payload.bin", strings.NewReader("padding padding EICAR-TEST padding"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 1 || matches[0].Signature != "Eicar-Test-Signature" {
+		t.Fatalf("infected file: matches=%v", matches)
+	}
+}
+
+func TestClamdTimeout(t *testing.T) {
+	addr := fakeClamd(t, func([]byte) string { return "" })
+	client := NewClamdClient("tcp", addr, 200*time.Millisecond)
+
+	start := time.Now()
+	_, err := client.Scan(context.Background(), "app.py", strings.NewReader("print('hello')\n"))
+	if !errors.Is(err, ErrScannerUnavailable) {
+		t.Fatalf("err = %v, want ErrScannerUnavailable", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("scan took %v, timeout not applied", elapsed)
+	}
+}
+
+// unreachableClamd 一个已关闭的端口（连接被拒绝）
+func unreachableClamd(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	return addr
+}
+
+func TestClamdInSecurityCheck(t *testing.T) {
+	dir := t.TempDir()
+	for name, data := range map[string]string{
+		"app.py":           "print('hello')\n",
+		"requirements.txt": "flask==3.0.0\n",
+		"payload.sh":       "echo EICAR-TEST\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rules := func(r *SecurityCheckResult) map[string]int {
+		count := map[string]int{}
+		for _, f := range r.Findings {
+			count[f.Rule]++
+		}
+		return count
+	}
+
+	t.Run("found", func(t *testing.T) {
+		policy := DefaultSecurityPolicy
+		policy.Scanners = []Scanner{NewClamdClient("tcp", fakeClamd(t, eicarReply), 2*time.Second)}
+		result := CheckModelSecurityWithPolicy(dir, policy)
+		if result.Pass {
+			t.Errorf("infected upload passed: %v", result.Findings)
+		}
+		var hit bool
+		for _, f := range result.Findings {
+			if f.Rule == "external" && f.File == "payload.sh" && f.Snippet == "Eicar-Test-Signature" {
+				hit = true
+			}
+		}
+		if !hit {
+			t.Errorf("clamd match not reported: %v", result.Findings)
+		}
+	})
+
+	for _, tc := range []struct {
+		mode string
+		rule string
+		pass bool
+	}{
+		{ScannerFailOpen, "scanner-skipped", true},
+		{ScannerFailClosed, "scanner-unavailable", false},
+	} {
+		t.Run("fail-"+tc.mode, func(t *testing.T) {
+			policy := DefaultSecurityPolicy
+			policy.Scanners = []Scanner{NewClamdClient("tcp", unreachableClamd(t), time.Second)}
+			policy.ScannerFailure = tc.mode
+			result := CheckModelSecurityWithPolicy(dir, policy)
+			if result.Pass != tc.pass {
+				t.Errorf("Pass = %v, want %v: %v", result.Pass, tc.pass, result.Findings)
+			}
+			if n := rules(result)[tc.rule]; n == 0 {
+				t.Errorf("no %s finding: %v", tc.rule, result.Findings)
+			}
+		})
+	}
+}
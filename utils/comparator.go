@@ -0,0 +1,300 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	_ "image/gif"  // 注册GIF解码器
+	_ "image/jpeg" // 注册JPEG解码器
+	_ "image/png"  // 注册PNG解码器
+	"math"
+	"math/bits"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"cmas-cats-go/models"
+)
+
+// Comparator 部署验证结果比对器
+// Compare 返回nil表示实例输出与预期结果一致，否则返回不一致的原因
+type Comparator interface {
+	Name() string
+	Compare(expected, actual []byte, spec models.ComparatorSpec) error
+}
+
+// 默认容差（ComparatorSpec.Tolerance为0时使用）
+var (
+	DefaultNumericTolerance = 1e-6 // 数值误差
+	DefaultImageDistance    = 5    // 感知哈希最大汉明距离（64位）
+)
+
+var (
+	comparatorsMu sync.RWMutex
+	comparators   = map[string]Comparator{}
+)
+
+func init() {
+	RegisterComparator(exactComparator{})
+	RegisterComparator(numericComparator{})
+	RegisterComparator(jsonComparator{})
+	RegisterComparator(imageComparator{})
+}
+
+// RegisterComparator 注册比对器（同名覆盖）
+func RegisterComparator(c Comparator) {
+	comparatorsMu.Lock()
+	defer comparatorsMu.Unlock()
+	comparators[c.Name()] = c
+}
+
+// GetComparator 按类型获取比对器，类型为空时返回numeric
+func GetComparator(name string) (Comparator, error) {
+	if name == "" {
+		name = models.ComparatorNumeric
+	}
+	comparatorsMu.RLock()
+	defer comparatorsMu.RUnlock()
+	c, ok := comparators[name]
+	if !ok {
+		return nil, fmt.Errorf("未知的比对器类型：%s", name)
+	}
+	return c, nil
+}
+
+// CompareResult 按服务声明的比对方式比对实例输出与预期结果
+func CompareResult(expected, actual []byte, spec models.ComparatorSpec) error {
+	c, err := GetComparator(spec.Type)
+	if err != nil {
+		return err
+	}
+	return c.Compare(expected, actual, spec)
+}
+
+// numericTolerance 取数值容差，未设置时使用默认值
+func numericTolerance(spec models.ComparatorSpec) float64 {
+	if spec.Tolerance > 0 {
+		return spec.Tolerance
+	}
+	return DefaultNumericTolerance
+}
+
+// ------------------------ exact：文本完全一致 ------------------------
+type exactComparator struct{}
+
+func (exactComparator) Name() string { return models.ComparatorExact }
+
+func (exactComparator) Compare(expected, actual []byte, _ models.ComparatorSpec) error {
+	if !bytes.Equal(bytes.TrimSpace(expected), bytes.TrimSpace(actual)) {
+		return fmt.Errorf("文本不一致：期望%q，实际%q", truncate(expected), truncate(actual))
+	}
+	return nil
+}
+
+// ------------------------ numeric：数值误差在容差内 ------------------------
+type numericComparator struct{}
+
+func (numericComparator) Name() string { return models.ComparatorNumeric }
+
+func (numericComparator) Compare(expected, actual []byte, spec models.ComparatorSpec) error {
+	e, a := strings.TrimSpace(string(expected)), strings.TrimSpace(string(actual))
+	if e == a {
+		return nil
+	}
+	ev, err1 := strconv.ParseFloat(e, 64)
+	av, err2 := strconv.ParseFloat(a, 64)
+	if err1 != nil || err2 != nil {
+		return fmt.Errorf("文本不一致：期望%q，实际%q", truncate(expected), truncate(actual))
+	}
+	if tol := numericTolerance(spec); math.Abs(ev-av) > tol {
+		return fmt.Errorf("数值误差超出容差：期望%v，实际%v，容差%v", ev, av, tol)
+	}
+	return nil
+}
+
+// ------------------------ json：结构比对 ------------------------
+type jsonComparator struct{}
+
+func (jsonComparator) Name() string { return models.ComparatorJSON }
+
+func (jsonComparator) Compare(expected, actual []byte, spec models.ComparatorSpec) error {
+	var ev, av interface{}
+	if err := json.Unmarshal(expected, &ev); err != nil {
+		return fmt.Errorf("预期结果不是合法JSON：%v", err)
+	}
+	if err := json.Unmarshal(actual, &av); err != nil {
+		return fmt.Errorf("实例输出不是合法JSON：%v", err)
+	}
+	ignore := make(map[string]bool, len(spec.IgnoreFields))
+	for _, f := range spec.IgnoreFields {
+		ignore[f] = true
+	}
+	return jsonEqual("", ev, av, ignore, spec)
+}
+
+// jsonEqual 递归比对JSON值，path为a.b形式的字段路径（数组元素沿用父路径）
+func jsonEqual(path string, ev, av interface{}, ignore map[string]bool, spec models.ComparatorSpec) error {
+	where := path
+	if where == "" {
+		where = "$"
+	}
+	switch e := ev.(type) {
+	case map[string]interface{}:
+		a, ok := av.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("字段%s类型不一致：期望对象", where)
+		}
+		keys := make([]string, 0, len(e)+len(a))
+		for k := range e {
+			keys = append(keys, k)
+		}
+		for k := range a {
+			if _, ok := e[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			child := k
+			if path != "" {
+				child = path + "." + k
+			}
+			if ignore[k] || ignore[child] {
+				continue
+			}
+			ec, eok := e[k]
+			ac, aok := a[k]
+			if !eok {
+				return fmt.Errorf("字段%s为多余字段", child)
+			}
+			if !aok {
+				return fmt.Errorf("字段%s缺失", child)
+			}
+			if err := jsonEqual(child, ec, ac, ignore, spec); err != nil {
+				return err
+			}
+		}
+		return nil
+	case []interface{}:
+		a, ok := av.([]interface{})
+		if !ok {
+			return fmt.Errorf("字段%s类型不一致：期望数组", where)
+		}
+		if len(e) != len(a) {
+			return fmt.Errorf("字段%s数组长度不一致：期望%d，实际%d", where, len(e), len(a))
+		}
+		for i := range e {
+			if err := jsonEqual(path, e[i], a[i], ignore, spec); err != nil {
+				return err
+			}
+		}
+		return nil
+	case float64:
+		a, ok := av.(float64)
+		if !ok {
+			return fmt.Errorf("字段%s类型不一致：期望数值", where)
+		}
+		tol := numericTolerance(spec)
+		if eps, ok := fieldEpsilon(path, spec.FieldEpsilon); ok {
+			tol = eps
+		}
+		if math.Abs(e-a) > tol {
+			return fmt.Errorf("字段%s数值误差超出容差：期望%v，实际%v，容差%v", where, e, a, tol)
+		}
+		return nil
+	default:
+		if ev != av {
+			return fmt.Errorf("字段%s不一致：期望%v，实际%v", where, ev, av)
+		}
+		return nil
+	}
+}
+
+// fieldEpsilon 查找字段的数值误差：先按完整路径，再按字段名
+func fieldEpsilon(path string, eps map[string]float64) (float64, bool) {
+	if v, ok := eps[path]; ok {
+		return v, true
+	}
+	if i := strings.LastIndex(path, "."); i >= 0 {
+		v, ok := eps[path[i+1:]]
+		return v, ok
+	}
+	return 0, false
+}
+
+// ------------------------ image：感知哈希距离 ------------------------
+type imageComparator struct{}
+
+func (imageComparator) Name() string { return models.ComparatorImage }
+
+func (imageComparator) Compare(expected, actual []byte, spec models.ComparatorSpec) error {
+	eh, err := ImageHash(expected)
+	if err != nil {
+		return fmt.Errorf("解析预期结果图片失败：%v", err)
+	}
+	ah, err := ImageHash(actual)
+	if err != nil {
+		return fmt.Errorf("解析实例输出图片失败：%v", err)
+	}
+	maxDist := DefaultImageDistance
+	if spec.Tolerance > 0 {
+		maxDist = int(spec.Tolerance)
+	}
+	if dist := bits.OnesCount64(eh ^ ah); dist > maxDist {
+		return fmt.Errorf("图片感知哈希距离%d超出容差%d", dist, maxDist)
+	}
+	return nil
+}
+
+// ImageHash 计算图片的差值哈希（dHash）：缩放为9x8灰度图，逐行比较相邻像素
+func ImageHash(data []byte) (uint64, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return 0, err
+	}
+	const w, h = 9, 8
+	var gray [h][w]float64
+	b := img.Bounds()
+	for y := 0; y < h; y++ {
+		y0 := b.Min.Y + y*b.Dy()/h
+		y1 := max(b.Min.Y+(y+1)*b.Dy()/h, y0+1)
+		for x := 0; x < w; x++ {
+			x0 := b.Min.X + x*b.Dx()/w
+			x1 := max(b.Min.X+(x+1)*b.Dx()/w, x0+1)
+			// 区域平均灰度
+			var sum float64
+			var n int
+			for py := y0; py < y1 && py < b.Max.Y; py++ {
+				for px := x0; px < x1 && px < b.Max.X; px++ {
+					r, g, bl, _ := img.At(px, py).RGBA()
+					sum += 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(bl)
+					n++
+				}
+			}
+			if n > 0 {
+				gray[y][x] = sum / float64(n)
+			}
+		}
+	}
+	var hash uint64
+	for y := 0; y < h; y++ {
+		for x := 0; x < w-1; x++ {
+			hash <<= 1
+			if gray[y][x] > gray[y][x+1] {
+				hash |= 1
+			}
+		}
+	}
+	return hash, nil
+}
+
+// truncate 截断过长的输出，便于写入日志/验证记录
+func truncate(b []byte) string {
+	const limit = 200
+	if len(b) > limit {
+		return string(b[:limit]) + "..."
+	}
+	return string(b)
+}
@@ -41,8 +41,9 @@ func CreateServiceContainer(serviceID, containerIP, hostPort, codePath, uploadDi
 		"-p", fmt.Sprintf("%s:5000", hostPort),
 		"-v", fmt.Sprintf("%s:/app/uploads", uploadDir),
 		"-e", fmt.Sprintf("SERVICE_IP=%s", containerIP),
-		"cmas-service:v1", // 复用之前构建的镜像
 	}
+	args = append(args, SiteLabels([]string{serviceID}, 5000)...)
+	args = append(args, "cmas-service:v1") // 复用之前构建的镜像
 	// 执行docker run命令
 	containerID, err := DockerCmd(args...)
 	if err != nil {
@@ -0,0 +1,126 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Site容器标签（Platform创建容器时写入，C-SMA按标签发现Site）
+const (
+	LabelSite        = "cmas.site"         // 存在即表示该容器是CMAS Site
+	LabelServices    = "cmas.services"     // 提供的服务ID，逗号分隔（为空表示以/metrics返回为准）
+	LabelMetricsPath = "cmas.metrics.path" // 指标接口路径（默认/metrics）
+	LabelMetricsPort = "cmas.metrics.port" // 指标接口的容器内端口（默认5000）
+)
+
+// ContainerInfo docker inspect中C-SMA关心的容器信息
+type ContainerInfo struct {
+	ID      string
+	Name    string
+	Running bool
+	Labels  map[string]string
+	IP      string         // 容器IP（多个网络时取网络名排序后的第一个非空IP）
+	Ports   map[int]string // 容器内TCP端口 → 宿主机映射端口
+}
+
+// SiteLabels 创建Site容器时的标签参数（docker run --label）
+func SiteLabels(serviceIDs []string, metricsPort int) []string {
+	return []string{
+		"--label", LabelSite + "=true",
+		"--label", LabelServices + "=" + strings.Join(serviceIDs, ","),
+		"--label", LabelMetricsPath + "=/metrics",
+		"--label", fmt.Sprintf("%s=%d", LabelMetricsPort, metricsPort),
+	}
+}
+
+// ListContainerIDs 列出匹配过滤条件的运行中容器ID（docker ps --filter，多个条件同时满足）
+func ListContainerIDs(filters ...string) ([]string, error) {
+	args := []string{"ps", "-q", "--no-trunc"}
+	for _, f := range filters {
+		args = append(args, "--filter", f)
+	}
+	output, err := DockerCmd(args...)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(output), nil
+}
+
+// InspectContainers 查询容器信息；不存在的容器被忽略（docker inspect对其报错但仍输出其余容器）
+func InspectContainers(refs ...string) ([]ContainerInfo, error) {
+	if len(refs) == 0 {
+		return nil, nil
+	}
+	cmd := exec.Command("docker", append([]string{"inspect"}, refs...)...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	runErr := cmd.Run()
+
+	var raw []struct {
+		ID    string `json:"Id"`
+		Name  string `json:"Name"`
+		State struct {
+			Running bool `json:"Running"`
+		} `json:"State"`
+		Config struct {
+			Labels map[string]string `json:"Labels"`
+		} `json:"Config"`
+		NetworkSettings struct {
+			Ports map[string][]struct {
+				HostPort string `json:"HostPort"`
+			} `json:"Ports"`
+			Networks map[string]struct {
+				IPAddress string `json:"IPAddress"`
+			} `json:"Networks"`
+		} `json:"NetworkSettings"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &raw); err != nil {
+		if runErr != nil {
+			return nil, fmt.Errorf("docker命令执行失败: %s, 错误: %s", stderr.String(), runErr.Error())
+		}
+		return nil, fmt.Errorf("解析docker inspect输出失败: %v", err)
+	}
+
+	containers := make([]ContainerInfo, 0, len(raw))
+	for _, r := range raw {
+		info := ContainerInfo{
+			ID:      r.ID,
+			Name:    strings.TrimPrefix(r.Name, "/"),
+			Running: r.State.Running,
+			Labels:  r.Config.Labels,
+			Ports:   make(map[int]string),
+		}
+		networks := make([]string, 0, len(r.NetworkSettings.Networks))
+		for name := range r.NetworkSettings.Networks {
+			networks = append(networks, name)
+		}
+		sort.Strings(networks)
+		for _, name := range networks {
+			if ip := r.NetworkSettings.Networks[name].IPAddress; ip != "" {
+				info.IP = ip
+				break
+			}
+		}
+		for spec, bindings := range r.NetworkSettings.Ports {
+			port, proto, _ := strings.Cut(spec, "/")
+			n, err := strconv.Atoi(port)
+			if err != nil || proto != "tcp" {
+				continue
+			}
+			for _, b := range bindings {
+				if b.HostPort != "" {
+					info.Ports[n] = b.HostPort
+					break
+				}
+			}
+		}
+		containers = append(containers, info)
+	}
+	return containers, nil
+}
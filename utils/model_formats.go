@@ -0,0 +1,624 @@
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// 模型文件格式
+const (
+	ModelPickle      = "pickle"
+	ModelSafetensors = "safetensors"
+	ModelGGUF        = "gguf"
+	ModelONNX        = "onnx"
+	ModelHDF5        = "hdf5"
+	ModelKeras       = "keras"
+	ModelTorch       = "pytorch"
+)
+
+// 模型文件扩展名 → 格式
+var modelFormats = map[string]string{
+	".pkl":         ModelPickle,
+	".pickle":      ModelPickle,
+	".joblib":      ModelPickle,
+	".safetensors": ModelSafetensors,
+	".gguf":        ModelGGUF,
+	".onnx":        ModelONNX,
+	".h5":          ModelHDF5,
+	".hdf5":        ModelHDF5,
+	".hdf":         ModelHDF5,
+	".keras":       ModelKeras,
+	".pt":          ModelTorch,
+	".pth":         ModelTorch,
+	".bin":         ModelTorch,
+}
+
+var (
+	ggufMagic     = []byte("GGUF")
+	hdf5Signature = []byte("\x89HDF\r\n\x1a\n")
+)
+
+// detectModelFormat 按扩展名及文件内容识别模型格式（非模型文件返回空）
+// pickle按内容识别，改成任意扩展名（.bin/.data/.txt等）也不会被当作普通文件
+func detectModelFormat(relPath string, content []byte) string {
+	if f, ok := modelFormats[strings.ToLower(filepath.Ext(relPath))]; ok {
+		return f
+	}
+	switch {
+	case bytes.HasPrefix(content, ggufMagic):
+		return ModelGGUF
+	case bytes.HasPrefix(content, hdf5Signature):
+		return ModelHDF5
+	case looksLikePickle(content):
+		return ModelPickle
+	}
+	return ""
+}
+
+// inspectModel 按格式解析模型文件，返回风险发现与清单
+func inspectModel(format, file string, content []byte, policy SecurityPolicy, rules *RuleSet) ([]SecurityFinding, []InventoryItem) {
+	switch format {
+	case ModelPickle:
+		return CheckPickle(file, content, policy.PickleAllowlist)
+	case ModelSafetensors:
+		return InspectSafetensors(file, content, rules)
+	case ModelGGUF:
+		return InspectGGUF(file, content, rules)
+	case ModelONNX:
+		return InspectONNX(file, content, rules)
+	case ModelHDF5:
+		return InspectHDF5(file, content, rules)
+	case ModelKeras:
+		return InspectKerasArchive(file, content, rules)
+	case ModelTorch:
+		return InspectTorch(file, content, policy.PickleAllowlist)
+	}
+	return []SecurityFinding{{File: file, Rule: "high-risk-file-type", Message: "高危文件类型：" + format}}, nil
+}
+
+// scanMetadata 用规则集中的元数据规则（LLM后门特征等）检查模型元数据文本
+func scanMetadata(file, where, text string, rules *RuleSet) []SecurityFinding {
+	findings := scanLines(file, []byte(text), rules.metadataRules())
+	for i := range findings {
+		findings[i].Line = 0
+		findings[i].Message = where + "中" + findings[i].Message
+	}
+	return findings
+}
+
+// looksLikePickle 按内容判断是否为pickle
+// 协议2及以上以PROTO（0x80+版本号）开头；协议0/1没有文件头，能从头完整遍历到STOP（其后只有空白）即视为pickle
+func looksLikePickle(content []byte) bool {
+	if len(content) >= 2 && content[0] == 0x80 && content[1] >= 2 && content[1] <= 5 {
+		return true
+	}
+	if len(content) < 2 || content[0] == '.' {
+		return false
+	}
+	w := &pickleWalker{data: content}
+	end, err := w.run(0)
+	return err == nil && len(bytes.TrimSpace(content[end:])) == 0
+}
+
+// sortInventory 清单按偏移排序，便于比对
+func sortInventory(items []InventoryItem) {
+	sort.SliceStable(items, func(i, j int) bool { return items[i].Offset < items[j].Offset })
+}
+
+// ===================== safetensors =====================
+
+// safetensors各数据类型的元素大小
+var safetensorsDtypeSize = map[string]int64{
+	"F64": 8, "F32": 4, "F16": 2, "BF16": 2, "F8_E4M3": 1, "F8_E5M2": 1,
+	"I64": 8, "I32": 4, "I16": 2, "I8": 1, "U64": 8, "U32": 4, "U16": 2, "U8": 1, "BOOL": 1,
+}
+
+// maxSafetensorsHeader safetensors头部JSON的大小上限
+const maxSafetensorsHeader = 100 << 20
+
+// InspectSafetensors 解析safetensors头部（8字节长度+JSON），校验每个张量的数据范围
+func InspectSafetensors(file string, data []byte, rules *RuleSet) ([]SecurityFinding, []InventoryItem) {
+	var findings []SecurityFinding
+	var inventory []InventoryItem
+	add := func(rule, msg string, offset int64) {
+		findings = append(findings, SecurityFinding{File: file, Rule: rule, Message: msg, Offset: offset})
+	}
+	if len(data) < 8 {
+		add("safetensors-invalid", "safetensors文件过短", 0)
+		return findings, nil
+	}
+	n := binary.LittleEndian.Uint64(data)
+	if n > maxSafetensorsHeader || n > uint64(len(data)-8) {
+		add("safetensors-invalid", fmt.Sprintf("safetensors头部长度%d越界", n), 0)
+		return findings, nil
+	}
+	var header map[string]json.RawMessage
+	if err := json.Unmarshal(data[8:8+n], &header); err != nil {
+		add("safetensors-invalid", "safetensors头部JSON解析失败："+err.Error(), 8)
+		return findings, nil
+	}
+
+	base := 8 + int64(n)
+	dataLen := int64(len(data)) - base
+	type span struct {
+		name       string
+		begin, end int64
+	}
+	var spans []span
+	for name, raw := range header {
+		if name == "__metadata__" {
+			var meta map[string]string
+			if err := json.Unmarshal(raw, &meta); err != nil {
+				add("safetensors-invalid", "__metadata__必须是字符串映射："+err.Error(), 8)
+				continue
+			}
+			for k, v := range meta {
+				inventory = append(inventory, InventoryItem{Name: k, Kind: "metadata", Detail: snippet(v)})
+				findings = append(findings, scanMetadata(file, "safetensors元数据"+k, v, rules)...)
+			}
+			continue
+		}
+		var t struct {
+			DType   string  `json:"dtype"`
+			Shape   []int64 `json:"shape"`
+			Offsets []int64 `json:"data_offsets"`
+		}
+		if err := json.Unmarshal(raw, &t); err != nil {
+			add("safetensors-invalid", fmt.Sprintf("张量%s描述解析失败：%v", name, err), 8)
+			continue
+		}
+		size, ok := safetensorsDtypeSize[t.DType]
+		if !ok {
+			add("safetensors-invalid", fmt.Sprintf("张量%s的数据类型%q未知", name, t.DType), 8)
+			continue
+		}
+		if len(t.Offsets) != 2 || t.Offsets[0] < 0 || t.Offsets[0] > t.Offsets[1] || t.Offsets[1] > dataLen {
+			add("safetensors-bounds", fmt.Sprintf("张量%s的数据范围%v超出数据区（%d字节）", name, t.Offsets, dataLen), 8)
+			continue
+		}
+		count := int64(1)
+		for _, dim := range t.Shape {
+			if dim < 0 || (dim > 0 && count > math.MaxInt64/size/dim) {
+				count = -1
+				break
+			}
+			count *= dim
+		}
+		if count < 0 || count*size != t.Offsets[1]-t.Offsets[0] {
+			add("safetensors-bounds", fmt.Sprintf("张量%s的形状%v与数据长度%d不符", name, t.Shape, t.Offsets[1]-t.Offsets[0]), base+t.Offsets[0])
+			continue
+		}
+		spans = append(spans, span{name, t.Offsets[0], t.Offsets[1]})
+		inventory = append(inventory, InventoryItem{
+			Name: name, Kind: "tensor", Detail: fmt.Sprintf("%s %v", t.DType, t.Shape), Offset: base + t.Offsets[0],
+		})
+	}
+
+	// 张量数据不能重叠，且必须覆盖整个数据区（未被引用的数据可能藏有载荷）
+	sort.Slice(spans, func(i, j int) bool { return spans[i].begin < spans[j].begin })
+	covered := int64(0)
+	for i, s := range spans {
+		if i > 0 && s.begin < spans[i-1].end {
+			add("safetensors-overlap", fmt.Sprintf("张量%s与%s的数据重叠", spans[i-1].name, s.name), base+s.begin)
+		}
+		if s.end > covered {
+			covered = s.end
+		}
+	}
+	if covered < dataLen {
+		add("safetensors-unreferenced-data", fmt.Sprintf("数据区末尾有%d字节未被任何张量引用", dataLen-covered), base+covered)
+	}
+	sortInventory(inventory)
+	return findings, inventory
+}
+
+// ===================== GGUF =====================
+
+// GGUF元数据值类型
+const (
+	ggufUint8 = iota
+	ggufInt8
+	ggufUint16
+	ggufInt16
+	ggufUint32
+	ggufInt32
+	ggufFloat32
+	ggufBool
+	ggufString
+	ggufArray
+	ggufUint64
+	ggufInt64
+	ggufFloat64
+)
+
+// GGUF标量类型的字节数
+var ggufScalarSize = map[uint32]int{
+	ggufUint8: 1, ggufInt8: 1, ggufUint16: 2, ggufInt16: 2, ggufUint32: 4, ggufInt32: 4,
+	ggufFloat32: 4, ggufBool: 1, ggufUint64: 8, ggufInt64: 8, ggufFloat64: 8,
+}
+
+// ggml张量类型 → (每块元素数, 每块字节数)
+var ggmlTypeSize = map[uint32][2]int64{
+	0: {1, 4}, 1: {1, 2}, 2: {32, 18}, 3: {32, 20}, 6: {32, 22}, 7: {32, 24}, 8: {32, 34}, 9: {32, 36},
+	10: {256, 84}, 11: {256, 110}, 12: {256, 144}, 13: {256, 176}, 14: {256, 210}, 15: {256, 292},
+	24: {1, 1}, 25: {1, 2}, 26: {1, 4}, 27: {1, 8}, 28: {1, 8}, 30: {1, 2},
+}
+
+// chat模板（Jinja）中可用于沙箱逃逸的写法
+var jinjaDangerousPattern = regexp.MustCompile(`__class__|__globals__|__subclasses__|__builtins__|__import__|__mro__|__init__|\bos\.|popen|\bsystem\(`)
+
+// GGUF解析上限（防止恶意头部耗尽内存）
+const (
+	maxGGUFString = 64 << 20
+	maxGGUFCount  = 1 << 24
+)
+
+// ggufReader GGUF小端读取器
+type ggufReader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (r *ggufReader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || r.pos+n > len(r.data) {
+		r.err = fmt.Errorf("偏移%d处数据被截断", r.pos)
+		return nil
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b
+}
+
+func (r *ggufReader) u32() uint32 {
+	if b := r.next(4); b != nil {
+		return binary.LittleEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (r *ggufReader) u64() uint64 {
+	if b := r.next(8); b != nil {
+		return binary.LittleEndian.Uint64(b)
+	}
+	return 0
+}
+
+// count 读取数量字段并检查上限
+func (r *ggufReader) count() int {
+	n := r.u64()
+	if n > maxGGUFCount && r.err == nil {
+		r.err = fmt.Errorf("偏移%d处数量%d超过上限", r.pos, n)
+	}
+	return int(n)
+}
+
+func (r *ggufReader) str() string {
+	n := r.u64()
+	if n > maxGGUFString && r.err == nil {
+		r.err = fmt.Errorf("偏移%d处字符串长度%d超过上限", r.pos, n)
+		return ""
+	}
+	return string(r.next(int(n)))
+}
+
+// value 读取一个元数据值；字符串返回原文，标量返回格式化文本，数组只返回元素类型与个数
+func (r *ggufReader) value(typ uint32, depth int) (string, bool) {
+	switch typ {
+	case ggufString:
+		return r.str(), true
+	case ggufArray:
+		elem := r.u32()
+		n := r.count()
+		if depth > 4 && r.err == nil {
+			r.err = errors.New("GGUF数组嵌套过深")
+		}
+		for i := 0; i < n && r.err == nil; i++ {
+			r.value(elem, depth+1)
+		}
+		return fmt.Sprintf("array[%d]<type %d>", n, elem), false
+	}
+	size, ok := ggufScalarSize[typ]
+	if !ok {
+		if r.err == nil {
+			r.err = fmt.Errorf("偏移%d处元数据类型%d未知", r.pos, typ)
+		}
+		return "", false
+	}
+	b := r.next(size)
+	if b == nil {
+		return "", false
+	}
+	switch typ {
+	case ggufBool:
+		return fmt.Sprint(b[0] != 0), false
+	case ggufFloat32:
+		return fmt.Sprint(math.Float32frombits(binary.LittleEndian.Uint32(b))), false
+	case ggufFloat64:
+		return fmt.Sprint(math.Float64frombits(binary.LittleEndian.Uint64(b))), false
+	case ggufInt8:
+		return fmt.Sprint(int8(b[0])), false
+	case ggufInt16:
+		return fmt.Sprint(int16(binary.LittleEndian.Uint16(b))), false
+	case ggufInt32:
+		return fmt.Sprint(int32(binary.LittleEndian.Uint32(b))), false
+	case ggufInt64:
+		return fmt.Sprint(int64(binary.LittleEndian.Uint64(b))), false
+	}
+	var v uint64
+	for i := len(b) - 1; i >= 0; i-- {
+		v = v<<8 | uint64(b[i])
+	}
+	return fmt.Sprint(v), false
+}
+
+// InspectGGUF 解析GGUF头部：元数据键值（含chat模板）与张量信息，校验张量数据范围
+func InspectGGUF(file string, data []byte, rules *RuleSet) ([]SecurityFinding, []InventoryItem) {
+	var findings []SecurityFinding
+	var inventory []InventoryItem
+	add := func(rule, msg string, offset int64) {
+		findings = append(findings, SecurityFinding{File: file, Rule: rule, Message: msg, Offset: offset})
+	}
+	r := &ggufReader{data: data}
+	if !bytes.Equal(r.next(4), ggufMagic) {
+		add("gguf-invalid", "GGUF文件头非法", 0)
+		return findings, nil
+	}
+	version := r.u32()
+	if version < 2 || version > 3 {
+		add("gguf-invalid", fmt.Sprintf("不支持的GGUF版本%d", version), 4)
+		return findings, nil
+	}
+	tensorCount := r.count()
+	kvCount := r.count()
+	inventory = append(inventory, InventoryItem{Name: "version", Kind: "header", Detail: fmt.Sprint(version)})
+
+	alignment := int64(32)
+	for i := 0; i < kvCount && r.err == nil; i++ {
+		off := int64(r.pos)
+		key := r.str()
+		text, isString := r.value(r.u32(), 0)
+		if r.err != nil {
+			break
+		}
+		inventory = append(inventory, InventoryItem{Name: key, Kind: "metadata", Detail: snippet(text), Offset: off})
+		if key == "general.alignment" {
+			fmt.Sscan(text, &alignment)
+		}
+		if !isString {
+			continue
+		}
+		findings = append(findings, scanMetadata(file, "GGUF元数据"+key, text, rules)...)
+		if strings.Contains(key, "chat_template") {
+			if m := jinjaDangerousPattern.FindString(text); m != "" {
+				findings = append(findings, SecurityFinding{
+					File: file, Offset: off, Rule: "gguf-template-injection", Snippet: m,
+					Message: fmt.Sprintf("GGUF元数据%s的chat模板包含可逃逸模板沙箱的写法", key),
+				})
+			}
+		}
+	}
+
+	type tensorInfo struct {
+		name   string
+		dims   []uint64
+		typ    uint32
+		offset uint64
+	}
+	var tensors []tensorInfo
+	for i := 0; i < tensorCount && r.err == nil; i++ {
+		t := tensorInfo{name: r.str()}
+		nd := r.u32()
+		if nd > 8 && r.err == nil {
+			r.err = fmt.Errorf("张量%s维度数%d非法", t.name, nd)
+		}
+		for d := uint32(0); d < nd && r.err == nil; d++ {
+			t.dims = append(t.dims, r.u64())
+		}
+		t.typ = r.u32()
+		t.offset = r.u64()
+		tensors = append(tensors, t)
+	}
+	if r.err != nil {
+		add("gguf-invalid", "GGUF头部解析失败："+r.err.Error(), int64(r.pos))
+		return findings, inventory
+	}
+
+	if alignment <= 0 {
+		alignment = 32
+	}
+	dataStart := (int64(r.pos) + alignment - 1) / alignment * alignment
+	for _, t := range tensors {
+		inventory = append(inventory, InventoryItem{
+			Name: t.name, Kind: "tensor", Detail: fmt.Sprintf("type %d %v", t.typ, t.dims), Offset: dataStart + int64(t.offset),
+		})
+		if int64(t.offset)%alignment != 0 {
+			add("gguf-bounds", fmt.Sprintf("张量%s的偏移%d未按%d字节对齐", t.name, t.offset, alignment), dataStart)
+		}
+		bs, ok := ggmlTypeSize[t.typ]
+		if !ok {
+			continue // 未知量化类型，无法计算大小
+		}
+		count := uint64(1)
+		for _, d := range t.dims {
+			if d != 0 && count > math.MaxInt64/d {
+				count = math.MaxInt64
+				break
+			}
+			count *= d
+		}
+		size := (count + uint64(bs[0]) - 1) / uint64(bs[0]) * uint64(bs[1])
+		// 依次比较而不先做减法，避免无符号数下溢绕过检查
+		n := uint64(len(data))
+		if uint64(dataStart) > n || t.offset > n-uint64(dataStart) || size > n-uint64(dataStart)-t.offset {
+			add("gguf-bounds", fmt.Sprintf("张量%s的数据（偏移%d，%d字节）超出文件范围", t.name, t.offset, size), dataStart)
+		}
+	}
+	return findings, inventory
+}
+
+// ===================== HDF5 / Keras =====================
+
+// InspectHDF5 检查Keras HDF5模型：校验HDF5签名后定位模型配置JSON，检查Lambda层等携带代码的配置
+// 不实现完整HDF5解析；Keras把model_config以JSON文本属性保存，在文件中是连续字节
+func InspectHDF5(file string, data []byte, rules *RuleSet) ([]SecurityFinding, []InventoryItem) {
+	// HDF5签名可位于0、512、1024、2048...（用户块之后）
+	found := false
+	for off := 0; off+len(hdf5Signature) <= len(data); off = max(512, off*2) {
+		if bytes.Equal(data[off:off+len(hdf5Signature)], hdf5Signature) {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return []SecurityFinding{{File: file, Rule: "hdf5-invalid", Message: "HDF5文件签名非法"}}, nil
+	}
+
+	var findings []SecurityFinding
+	var inventory []InventoryItem
+	marker := []byte(`{"class_name"`)
+	for pos := 0; ; {
+		i := bytes.Index(data[pos:], marker)
+		if i < 0 {
+			break
+		}
+		start := pos + i
+		dec := json.NewDecoder(bytes.NewReader(data[start:]))
+		var cfg any
+		if err := dec.Decode(&cfg); err != nil {
+			pos = start + len(marker)
+			continue
+		}
+		f, inv := inspectKerasConfig(file, cfg, int64(start), rules)
+		findings = append(findings, f...)
+		inventory = append(inventory, inv...)
+		pos = start + int(dec.InputOffset())
+	}
+	return findings, inventory
+}
+
+// ===================== PyTorch =====================
+
+// InspectTorch 检查PyTorch模型（.pt/.pth/.bin）
+// torch.save的zip格式逐个检查其中的*.pkl成员（archive/data.pkl等）；旧格式是连续的pickle流，整体按pickle检查；
+// 其余内容（如原始权重数据）不含可执行的序列化对象
+func InspectTorch(file string, data []byte, allowlist []string) ([]SecurityFinding, []InventoryItem) {
+	if !bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		if looksLikePickle(data) {
+			return CheckPickle(file, data, allowlist)
+		}
+		return nil, nil
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return []SecurityFinding{{File: file, Rule: "torch-invalid", Message: "读取PyTorch模型压缩包失败：" + err.Error()}}, nil
+	}
+	var findings []SecurityFinding
+	var inventory []InventoryItem
+	for _, zf := range zr.File {
+		if !strings.HasSuffix(strings.ToLower(zf.Name), ".pkl") {
+			continue
+		}
+		member := file + "!" + zf.Name
+		rc, err := zf.Open()
+		if err != nil {
+			findings = append(findings, SecurityFinding{File: member, Rule: "pickle-parse-error", Message: "读取pickle成员失败：" + err.Error()})
+			continue
+		}
+		raw, err := io.ReadAll(io.LimitReader(rc, maxPickleDecompressed+1))
+		rc.Close()
+		if err == nil && len(raw) > maxPickleDecompressed {
+			err = fmt.Errorf("超过%dMB", maxPickleDecompressed>>20)
+		}
+		if err != nil {
+			findings = append(findings, SecurityFinding{File: member, Rule: "pickle-parse-error", Message: "读取pickle成员失败：" + err.Error()})
+			continue
+		}
+		f, inv := CheckPickle(member, raw, allowlist)
+		findings = append(findings, f...)
+		inventory = append(inventory, inv...)
+	}
+	return findings, inventory
+}
+
+// InspectKerasArchive 检查Keras 3的.keras模型（zip内的config.json）
+func InspectKerasArchive(file string, data []byte, rules *RuleSet) ([]SecurityFinding, []InventoryItem) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return []SecurityFinding{{File: file, Rule: "keras-invalid", Message: "读取.keras压缩包失败：" + err.Error()}}, nil
+	}
+	for _, zf := range zr.File {
+		if zf.Name != "config.json" {
+			continue
+		}
+		rc, err := zf.Open()
+		if err != nil {
+			return []SecurityFinding{{File: file, Rule: "keras-invalid", Message: "读取config.json失败：" + err.Error()}}, nil
+		}
+		raw, err := io.ReadAll(io.LimitReader(rc, maxSafetensorsHeader))
+		rc.Close()
+		var cfg any
+		if err == nil {
+			err = json.Unmarshal(raw, &cfg)
+		}
+		if err != nil {
+			return []SecurityFinding{{File: file, Rule: "keras-invalid", Message: "解析config.json失败：" + err.Error()}}, nil
+		}
+		return inspectKerasConfig(file, cfg, 0, rules)
+	}
+	return []SecurityFinding{{File: file, Rule: "keras-invalid", Message: ".keras压缩包缺少config.json"}}, nil
+}
+
+// inspectKerasConfig 遍历Keras模型配置，列出各层并标记Lambda等携带序列化代码的层
+func inspectKerasConfig(file string, cfg any, offset int64, rules *RuleSet) ([]SecurityFinding, []InventoryItem) {
+	var findings []SecurityFinding
+	var inventory []InventoryItem
+	var walk func(v any, inLayers bool)
+	walk = func(v any, inLayers bool) {
+		switch x := v.(type) {
+		case []any:
+			for _, e := range x {
+				walk(e, inLayers)
+			}
+		case map[string]any:
+			class, _ := x["class_name"].(string)
+			conf, _ := x["config"].(map[string]any)
+			name, _ := conf["name"].(string)
+			if inLayers && class != "" {
+				inventory = append(inventory, InventoryItem{Name: name, Kind: "layer", Detail: class, Offset: offset})
+			}
+			switch class {
+			case "Lambda":
+				findings = append(findings, SecurityFinding{
+					File: file, Offset: offset, Rule: "keras-lambda-layer", Snippet: snippet(fmt.Sprint(conf["function"])),
+					Message: fmt.Sprintf("Keras Lambda层%s携带序列化代码，加载模型时会执行", name),
+				})
+			case "__lambda__":
+				findings = append(findings, SecurityFinding{
+					File: file, Offset: offset, Rule: "keras-lambda-layer",
+					Message: "Keras配置中包含序列化的lambda函数，加载模型时会执行",
+				})
+			}
+			for k, e := range x {
+				walk(e, k == "layers")
+			}
+		case string:
+			findings = append(findings, scanMetadata(file, "Keras模型配置", x, rules)...)
+		}
+	}
+	walk(cfg, false)
+	return findings, inventory
+}
@@ -0,0 +1,310 @@
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// 反序列化时调用os.system("id")的pickle
+var (
+	evilPickleProto2 = []byte("\x80\x02cposix\nsystem\nq\x00X\x02\x00\x00\x00idq\x01\x85q\x02Rq\x03.")
+	evilPickleProto0 = []byte("cposix\nsystem\n(S'id'\ntR.")
+	torchDataPickle  = []byte("\x80\x02ctorch._utils\n_rebuild_tensor_v2\nq\x00ctorch\nFloatStorage\nq\x01\x86q\x02.")
+)
+
+// torchZip torch.save的zip格式：archive/data.pkl加上原始存储数据
+func torchZip(t *testing.T, dataPkl []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, data := range map[string][]byte{
+		"archive/data.pkl": dataPkl,
+		"archive/data/0":   bytes.Repeat([]byte{0x3f}, 64),
+		"archive/version":  []byte("3\n"),
+	} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(data)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestPickleDetectedByContent(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string][]byte{
+		"evil.bin":    evilPickleProto2,
+		"evil.data":   evilPickleProto2,
+		"evil.txt":    evilPickleProto0,
+		"model.pt":    torchZip(t, evilPickleProto2),
+		"good.pth":    torchZip(t, torchDataPickle),
+		"legacy.pt":   torchDataPickle,
+		"weights.bin": bytes.Repeat([]byte{0x00, 0x3f, 0x80, 0x41}, 256),
+		"notes.txt":   []byte("模型说明：输入一张图片。\nN.\n"),
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result := CheckModelSecurity(dir)
+	dangerous := map[string]bool{}
+	kinds := map[string]string{}
+	for _, f := range result.Files {
+		kinds[f.Path] = f.Kind
+		for _, finding := range f.Findings {
+			if finding.Rule == "pickle-dangerous-call" {
+				dangerous[finding.File] = true
+			}
+		}
+	}
+
+	for _, file := range []string{"evil.bin", "evil.data", "evil.txt", "model.pt!archive/data.pkl"} {
+		if !dangerous[file] {
+			t.Errorf("%s: os.system call not reported (kinds %v)", file, kinds)
+		}
+	}
+	for _, file := range []string{"good.pth", "legacy.pt", "weights.bin", "notes.txt"} {
+		for _, f := range result.Files {
+			if f.Path == file && len(f.Findings) > 0 {
+				t.Errorf("%s: unexpected findings %v", file, f.Findings)
+			}
+		}
+	}
+	if kinds["notes.txt"] != FileKindText {
+		t.Errorf("notes.txt classified as %q, want %q", kinds["notes.txt"], FileKindText)
+	}
+	if result.Pass {
+		t.Errorf("upload with malicious pickles passed")
+	}
+}
+
+func TestLooksLikePickle(t *testing.T) {
+	cases := []struct {
+		data []byte
+		want bool
+	}{
+		{evilPickleProto2, true},
+		{evilPickleProto0, true},
+		{[]byte("\x80\x04\x95\x05\x00\x00\x00\x00\x00\x00\x00K\x01."), true},
+		{[]byte("import os\nos.system('id')\n"), false},
+		{[]byte("hello world."), false},
+		{[]byte("."), false},
+		{nil, false},
+	}
+	for _, c := range cases {
+		if got := looksLikePickle(c.data); got != c.want {
+			t.Errorf("looksLikePickle(%q) = %v, want %v", c.data, got, c.want)
+		}
+	}
+}
+
+// findingRules 发现的规则ID集合
+func findingRules(findings []SecurityFinding) map[string]bool {
+	rules := map[string]bool{}
+	for _, f := range findings {
+		rules[f.Rule] = true
+	}
+	return rules
+}
+
+// formatCase 一个模型格式样本及期望命中的规则（want为空表示应无任何发现）
+type formatCase struct {
+	name    string
+	data    []byte
+	inspect func(file string, data []byte, rules *RuleSet) ([]SecurityFinding, []InventoryItem)
+	want    []string
+}
+
+func runFormatCases(t *testing.T, cases []formatCase) {
+	t.Helper()
+	rules := DefaultRuleSet()
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			findings, _ := c.inspect(c.name, c.data, rules)
+			got := findingRules(findings)
+			if len(c.want) == 0 && len(findings) > 0 {
+				t.Fatalf("unexpected findings: %v", findings)
+			}
+			for _, rule := range c.want {
+				if !got[rule] {
+					t.Errorf("missing %s finding, got %v", rule, findings)
+				}
+			}
+		})
+	}
+}
+
+// safetensorsFile 8字节头部长度 + 头部JSON + 数据区
+func safetensorsFile(header string, dataLen int) []byte {
+	b := binary.LittleEndian.AppendUint64(nil, uint64(len(header)))
+	b = append(b, header...)
+	return append(b, make([]byte, dataLen)...)
+}
+
+func TestInspectSafetensors(t *testing.T) {
+	const tensor = `"w":{"dtype":"F32","shape":[2,2],"data_offsets":[0,16]}`
+	lying := binary.LittleEndian.AppendUint64(nil, 1<<40)
+	lying = append(lying, `{"w":{}}`...)
+	runFormatCases(t, []formatCase{
+		{"benign.safetensors", safetensorsFile(`{`+tensor+`,"__metadata__":{"format":"pt"}}`, 16), InspectSafetensors, nil},
+		{"header-length.safetensors", lying, InspectSafetensors, []string{"safetensors-invalid"}},
+		{"truncated.safetensors", []byte{1, 2, 3}, InspectSafetensors, []string{"safetensors-invalid"}},
+		{"out-of-range.safetensors", safetensorsFile(`{"w":{"dtype":"F32","shape":[8],"data_offsets":[0,32]}}`, 16), InspectSafetensors, []string{"safetensors-bounds"}},
+		{"overlap.safetensors", safetensorsFile(`{`+tensor+`,"v":{"dtype":"F32","shape":[4],"data_offsets":[8,24]}}`, 24), InspectSafetensors, []string{"safetensors-overlap"}},
+		{"trailing.safetensors", safetensorsFile(`{`+tensor+`}`, 48), InspectSafetensors, []string{"safetensors-unreferenced-data"}},
+	})
+}
+
+// ggufTensor GGUF张量描述
+type ggufTensor struct {
+	name   string
+	dims   []uint64
+	typ    uint32
+	offset uint64
+}
+
+// ggufFile GGUF v3文件：字符串元数据、张量描述，按32字节对齐后接dataLen字节数据
+func ggufFile(meta map[string]string, tensors []ggufTensor, dataLen int) []byte {
+	str := func(b []byte, s string) []byte {
+		b = binary.LittleEndian.AppendUint64(b, uint64(len(s)))
+		return append(b, s...)
+	}
+	b := append([]byte(nil), ggufMagic...)
+	b = binary.LittleEndian.AppendUint32(b, 3)
+	b = binary.LittleEndian.AppendUint64(b, uint64(len(tensors)))
+	b = binary.LittleEndian.AppendUint64(b, uint64(len(meta)))
+	for k, v := range meta {
+		b = str(b, k)
+		b = binary.LittleEndian.AppendUint32(b, ggufString)
+		b = str(b, v)
+	}
+	for _, t := range tensors {
+		b = str(b, t.name)
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(t.dims)))
+		for _, d := range t.dims {
+			b = binary.LittleEndian.AppendUint64(b, d)
+		}
+		b = binary.LittleEndian.AppendUint32(b, t.typ)
+		b = binary.LittleEndian.AppendUint64(b, t.offset)
+	}
+	for len(b)%32 != 0 {
+		b = append(b, 0)
+	}
+	return append(b, make([]byte, dataLen)...)
+}
+
+func TestInspectGGUF(t *testing.T) {
+	f32x16 := func(offset uint64) []ggufTensor {
+		return []ggufTensor{{name: "w", dims: []uint64{16}, typ: 0, offset: offset}}
+	}
+	benignMeta := map[string]string{"tokenizer.chat_template": "{% for m in messages %}{{ m.content }}{% endfor %}"}
+
+	// 数据区只有40字节，张量从偏移64开始：旧的检查先做减法，无符号下溢后放行
+	pastEnd := ggufFile(nil, f32x16(64), 40)
+	if len(pastEnd) != 104 {
+		t.Fatalf("fixture length = %d, want 104", len(pastEnd))
+	}
+
+	runFormatCases(t, []formatCase{
+		{"benign.gguf", ggufFile(benignMeta, f32x16(0), 64), InspectGGUF, nil},
+		{"past-end.gguf", pastEnd, InspectGGUF, []string{"gguf-bounds"}},
+		{"short-data.gguf", ggufFile(nil, f32x16(0), 32), InspectGGUF, []string{"gguf-bounds"}},
+		{"huge-offset.gguf", ggufFile(nil, f32x16(1<<63), 64), InspectGGUF, []string{"gguf-bounds"}},
+		{"template.gguf", ggufFile(map[string]string{"tokenizer.chat_template": "{{ ''.__class__.__mro__[1].__subclasses__() }}"}, f32x16(0), 64), InspectGGUF, []string{"gguf-template-injection"}},
+		{"truncated.gguf", ggufFile(benignMeta, f32x16(0), 64)[:30], InspectGGUF, []string{"gguf-invalid"}},
+		{"bad-magic.gguf", []byte("GGML\x03\x00\x00\x00"), InspectGGUF, []string{"gguf-invalid"}},
+	})
+}
+
+// pbBytes/pbString protobuf length-delimited字段编码
+func pbBytes(num int, b []byte) []byte {
+	out := binary.AppendUvarint(nil, uint64(num<<3|2))
+	out = binary.AppendUvarint(out, uint64(len(b)))
+	return append(out, b...)
+}
+
+func pbString(num int, s string) []byte {
+	return pbBytes(num, []byte(s))
+}
+
+// onnxModel 一个节点、一个使用外部数据的初始化张量的ONNX模型
+func onnxModel(domain, opType, location string) []byte {
+	external := append(pbString(1, "location"), pbString(2, location)...)
+	tensor := append(pbString(8, "weight"), pbBytes(13, external)...)
+	node := append(pbString(4, opType), pbString(7, domain)...)
+	graph := append(pbBytes(1, node), pbBytes(5, tensor)...)
+	model := append(pbString(2, "pytorch"), pbBytes(7, graph)...)
+	return model
+}
+
+func TestInspectONNX(t *testing.T) {
+	runFormatCases(t, []formatCase{
+		{"benign.onnx", onnxModel("", "Relu", "model.onnx.data"), InspectONNX, nil},
+		{"subdir.onnx", onnxModel("ai.onnx", "Relu", "weights/./part0.bin"), InspectONNX, nil},
+		{"parent.onnx", onnxModel("", "Relu", "../../etc/passwd"), InspectONNX, []string{"onnx-external-path"}},
+		{"absolute.onnx", onnxModel("", "Relu", "/etc/shadow"), InspectONNX, []string{"onnx-external-path"}},
+		{"windows.onnx", onnxModel("", "Relu", `C:\Windows\win.ini`), InspectONNX, []string{"onnx-external-path"}},
+		{"backslash.onnx", onnxModel("", "Relu", `weights\..\..\secret`), InspectONNX, []string{"onnx-external-path"}},
+		{"custom-op.onnx", onnxModel("com.evil", "Backdoor", "model.onnx.data"), InspectONNX, []string{"onnx-custom-op"}},
+		{"truncated.onnx", onnxModel("", "Relu", "model.onnx.data")[:20], InspectONNX, []string{"onnx-invalid"}},
+	})
+}
+
+// kerasConfig 含一个Dense层和可选额外层的Sequential模型配置
+func kerasConfig(extra string) string {
+	layers := `{"class_name": "Dense", "config": {"name": "dense", "units": 4}}`
+	if extra != "" {
+		layers += ", " + extra
+	}
+	return `{"class_name": "Sequential", "config": {"name": "seq", "layers": [` + layers + `]}}`
+}
+
+const kerasLambda = `{"class_name": "Lambda", "config": {"name": "lambda", "function": ["4wEAAAAAAAAA", null, null]}}`
+
+// hdf5File HDF5签名 + 填充 + Keras以属性保存的模型配置
+func hdf5File(config string) []byte {
+	b := append([]byte(nil), hdf5Signature...)
+	b = append(b, make([]byte, 120)...)
+	b = append(b, config...)
+	return append(b, make([]byte, 64)...)
+}
+
+// kerasFile .keras压缩包
+func kerasFile(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, data := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(data))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestInspectKeras(t *testing.T) {
+	runFormatCases(t, []formatCase{
+		{"benign.h5", hdf5File(kerasConfig("")), InspectHDF5, nil},
+		{"lambda.h5", hdf5File(kerasConfig(kerasLambda)), InspectHDF5, []string{"keras-lambda-layer"}},
+		{"not-hdf5.h5", []byte(kerasConfig(kerasLambda)), InspectHDF5, []string{"hdf5-invalid"}},
+		{"benign.keras", kerasFile(t, map[string]string{"config.json": kerasConfig(""), "model.weights.h5": "weights"}), InspectKerasArchive, nil},
+		{"lambda.keras", kerasFile(t, map[string]string{"config.json": kerasConfig(kerasLambda)}), InspectKerasArchive, []string{"keras-lambda-layer"}},
+		{"no-config.keras", kerasFile(t, map[string]string{"model.weights.h5": "weights"}), InspectKerasArchive, []string{"keras-invalid"}},
+		{"truncated.keras", kerasFile(t, map[string]string{"config.json": kerasConfig("")})[:40], InspectKerasArchive, []string{"keras-invalid"}},
+	})
+}
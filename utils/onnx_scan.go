@@ -0,0 +1,297 @@
+package utils
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// ONNX模型检查：按protobuf线格式解析ModelProto，不依赖onnx库
+// 标记需要外部实现库的自定义算子域，以及指向模型目录之外的外部数据路径
+
+// 标准算子域（onnxruntime内置实现）
+var onnxStandardDomains = map[string]bool{
+	"": true, "ai.onnx": true, "ai.onnx.ml": true, "ai.onnx.training": true,
+	"ai.onnx.preview.training": true, "com.microsoft": true, "com.microsoft.nchwc": true,
+}
+
+// maxONNXDepth 子图（If/Loop/Scan）的最大嵌套深度
+const maxONNXDepth = 32
+
+// pbField protobuf字段
+type pbField struct {
+	num  int
+	wire int
+	v    uint64 // varint值
+	b    []byte // length-delimited内容
+}
+
+// pbFields 解析一层protobuf消息的所有字段
+func pbFields(b []byte) ([]pbField, error) {
+	var out []pbField
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			return out, errors.New("字段标签非法")
+		}
+		b = b[n:]
+		f := pbField{num: int(key >> 3), wire: int(key & 7)}
+		switch f.wire {
+		case 0:
+			v, n := binary.Uvarint(b)
+			if n <= 0 {
+				return out, errors.New("varint非法")
+			}
+			f.v, b = v, b[n:]
+		case 1, 5:
+			size := 8
+			if f.wire == 5 {
+				size = 4
+			}
+			if len(b) < size {
+				return out, errors.New("定长字段被截断")
+			}
+			b = b[size:]
+		case 2:
+			l, n := binary.Uvarint(b)
+			if n <= 0 || l > uint64(len(b)-n) {
+				return out, errors.New("长度字段越界")
+			}
+			f.b, b = b[n:n+int(l)], b[n+int(l):]
+		default:
+			return out, fmt.Errorf("不支持的wire类型%d", f.wire)
+		}
+		out = append(out, f)
+	}
+	return out, nil
+}
+
+// onnxScan 单个ONNX模型的检查状态
+type onnxScan struct {
+	file      string
+	findings  []SecurityFinding
+	inventory []InventoryItem
+	ops       map[string]int  // domain::op_type → 出现次数
+	localDoms map[string]bool // 模型内函数定义的算子域
+	custom    map[string]bool // 已报告的自定义算子
+}
+
+// InspectONNX 解析ONNX模型，列出算子集、算子与外部数据，标记自定义算子和可疑外部数据路径
+func InspectONNX(file string, data []byte, rules *RuleSet) ([]SecurityFinding, []InventoryItem) {
+	s := &onnxScan{file: file, ops: map[string]int{}, localDoms: map[string]bool{}, custom: map[string]bool{}}
+	fields, err := pbFields(data)
+	if err != nil {
+		s.add("onnx-invalid", "ONNX模型解析失败："+err.Error(), "")
+		return s.findings, nil
+	}
+
+	// 先收集模型内函数（FunctionProto）定义的算子域，这些算子不需要外部实现
+	for _, f := range fields {
+		if f.num == 25 && f.wire == 2 {
+			if fn, err := pbFields(f.b); err == nil {
+				for _, ff := range fn {
+					if ff.num == 10 && ff.wire == 2 {
+						s.localDoms[string(ff.b)] = true
+					}
+				}
+			}
+		}
+	}
+	for _, f := range fields {
+		switch {
+		case f.num == 1 && f.wire == 0:
+			s.inventory = append(s.inventory, InventoryItem{Name: "ir_version", Kind: "header", Detail: fmt.Sprint(f.v)})
+		case f.num == 2 && f.wire == 2:
+			s.inventory = append(s.inventory, InventoryItem{Name: "producer", Kind: "header", Detail: string(f.b)})
+		case f.num == 8 && f.wire == 2:
+			s.opset(f.b)
+		case f.num == 7 && f.wire == 2:
+			s.graph(f.b, 0)
+		case f.num == 14 && f.wire == 2:
+			key, value := onnxStringPair(f.b)
+			s.inventory = append(s.inventory, InventoryItem{Name: key, Kind: "metadata", Detail: snippet(value)})
+			s.findings = append(s.findings, scanMetadata(file, "ONNX元数据"+key, value, rules)...)
+		case f.num == 25 && f.wire == 2:
+			s.function(f.b)
+		}
+	}
+
+	keys := make([]string, 0, len(s.ops))
+	for k := range s.ops {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		s.inventory = append(s.inventory, InventoryItem{Name: k, Kind: "op", Detail: fmt.Sprintf("共%d处", s.ops[k])})
+	}
+	return s.findings, s.inventory
+}
+
+func (s *onnxScan) add(rule, msg, snip string) {
+	s.findings = append(s.findings, SecurityFinding{File: s.file, Rule: rule, Message: msg, Snippet: snip})
+}
+
+// opset OperatorSetIdProto{domain=1, version=2}
+func (s *onnxScan) opset(b []byte) {
+	fields, err := pbFields(b)
+	if err != nil {
+		s.add("onnx-invalid", "算子集解析失败："+err.Error(), "")
+		return
+	}
+	domain, version := "", uint64(0)
+	for _, f := range fields {
+		switch {
+		case f.num == 1 && f.wire == 2:
+			domain = string(f.b)
+		case f.num == 2 && f.wire == 0:
+			version = f.v
+		}
+	}
+	s.inventory = append(s.inventory, InventoryItem{Name: domain, Kind: "opset", Detail: fmt.Sprint(version)})
+}
+
+// graph GraphProto{node=1, initializer=5, sparse_initializer=15}
+func (s *onnxScan) graph(b []byte, depth int) {
+	if depth > maxONNXDepth {
+		s.add("onnx-invalid", "ONNX子图嵌套过深", "")
+		return
+	}
+	fields, err := pbFields(b)
+	if err != nil {
+		s.add("onnx-invalid", "计算图解析失败："+err.Error(), "")
+		return
+	}
+	for _, f := range fields {
+		if f.wire != 2 {
+			continue
+		}
+		switch f.num {
+		case 1:
+			s.node(f.b, depth)
+		case 5:
+			s.tensor(f.b)
+		case 15:
+			// SparseTensorProto{values=1, indices=2}
+			if sf, err := pbFields(f.b); err == nil {
+				for _, t := range sf {
+					if (t.num == 1 || t.num == 2) && t.wire == 2 {
+						s.tensor(t.b)
+					}
+				}
+			}
+		}
+	}
+}
+
+// function FunctionProto{node=7}
+func (s *onnxScan) function(b []byte) {
+	fields, err := pbFields(b)
+	if err != nil {
+		s.add("onnx-invalid", "模型函数解析失败："+err.Error(), "")
+		return
+	}
+	for _, f := range fields {
+		if f.num == 7 && f.wire == 2 {
+			s.node(f.b, 0)
+		}
+	}
+}
+
+// node NodeProto{name=3, op_type=4, attribute=5, domain=7}
+func (s *onnxScan) node(b []byte, depth int) {
+	fields, err := pbFields(b)
+	if err != nil {
+		s.add("onnx-invalid", "算子节点解析失败："+err.Error(), "")
+		return
+	}
+	var name, opType, domain string
+	for _, f := range fields {
+		if f.wire != 2 {
+			continue
+		}
+		switch f.num {
+		case 3:
+			name = string(f.b)
+		case 4:
+			opType = string(f.b)
+		case 7:
+			domain = string(f.b)
+		case 5:
+			s.attribute(f.b, depth)
+		}
+	}
+	s.ops[domain+"::"+opType]++
+	if !onnxStandardDomains[domain] && !s.localDoms[domain] && !s.custom[domain+"::"+opType] {
+		s.custom[domain+"::"+opType] = true
+		s.add("onnx-custom-op",
+			fmt.Sprintf("ONNX使用自定义算子域%s的算子%s（需要加载外部实现库）", domain, opType), name)
+	}
+}
+
+// attribute AttributeProto{t=5, g=6, tensors=10, graphs=11}：子图与常量张量
+func (s *onnxScan) attribute(b []byte, depth int) {
+	fields, err := pbFields(b)
+	if err != nil {
+		s.add("onnx-invalid", "算子属性解析失败："+err.Error(), "")
+		return
+	}
+	for _, f := range fields {
+		if f.wire != 2 {
+			continue
+		}
+		switch f.num {
+		case 5, 10:
+			s.tensor(f.b)
+		case 6, 11:
+			s.graph(f.b, depth+1)
+		}
+	}
+}
+
+// tensor TensorProto{name=8, external_data=13, data_location=14}：检查外部数据路径
+func (s *onnxScan) tensor(b []byte) {
+	fields, err := pbFields(b)
+	if err != nil {
+		s.add("onnx-invalid", "张量解析失败："+err.Error(), "")
+		return
+	}
+	var name string
+	for _, f := range fields {
+		if f.num == 8 && f.wire == 2 {
+			name = string(f.b)
+		}
+	}
+	for _, f := range fields {
+		if f.num != 13 || f.wire != 2 {
+			continue
+		}
+		key, value := onnxStringPair(f.b)
+		if key != "location" {
+			continue
+		}
+		s.inventory = append(s.inventory, InventoryItem{Name: name, Kind: "external_data", Detail: value})
+		clean := path.Clean(strings.ReplaceAll(value, "\\", "/"))
+		if path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") || strings.Contains(value, ":") {
+			s.add("onnx-external-path",
+				fmt.Sprintf("ONNX张量%s的外部数据指向模型目录之外", name), value)
+		}
+	}
+}
+
+// onnxStringPair StringStringEntryProto{key=1, value=2}
+func onnxStringPair(b []byte) (string, string) {
+	fields, _ := pbFields(b)
+	var key, value string
+	for _, f := range fields {
+		switch {
+		case f.num == 1 && f.wire == 2:
+			key = string(f.b)
+		case f.num == 2 && f.wire == 2:
+			value = string(f.b)
+		}
+	}
+	return key, value
+}
@@ -0,0 +1,342 @@
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 离线漏洞库：加载OSV格式（https://ossf.github.io/osv-schema/）的PyPI漏洞数据，
+// 支持单个JSON（对象或数组）、JSON文件目录，以及OSV官方导出的all.zip
+
+// OSVEntry OSV漏洞条目（只解析匹配需要的字段）
+type OSVEntry struct {
+	ID       string   `json:"id"`
+	Summary  string   `json:"summary"`
+	Aliases  []string `json:"aliases"`
+	Affected []struct {
+		Package struct {
+			Ecosystem string `json:"ecosystem"`
+			Name      string `json:"name"`
+		} `json:"package"`
+		Ranges []struct {
+			Type   string `json:"type"`
+			Events []struct {
+				Introduced   string `json:"introduced"`
+				Fixed        string `json:"fixed"`
+				LastAffected string `json:"last_affected"`
+			} `json:"events"`
+		} `json:"ranges"`
+		Versions []string `json:"versions"`
+	} `json:"affected"`
+	DatabaseSpecific struct {
+		Severity string `json:"severity"`
+	} `json:"database_specific"`
+}
+
+// VulnDB 按包名索引的离线漏洞库
+type VulnDB struct {
+	Source  string
+	entries map[string][]*OSVEntry // 规范化包名 → 漏洞
+	count   int
+}
+
+// Count 漏洞条目数
+func (db *VulnDB) Count() int {
+	if db == nil {
+		return 0
+	}
+	return db.count
+}
+
+// LoadVulnDB 从文件或目录加载OSV漏洞数据
+func LoadVulnDB(path string) (*VulnDB, error) {
+	db := &VulnDB{Source: path, entries: map[string][]*OSVEntry{}}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case info.IsDir():
+		files, err := filepath.Glob(filepath.Join(path, "*.json"))
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			data, err := os.ReadFile(f)
+			if err != nil {
+				return nil, err
+			}
+			if err := db.add(data); err != nil {
+				return nil, fmt.Errorf("%s：%v", f, err)
+			}
+		}
+	case strings.EqualFold(filepath.Ext(path), ".zip"):
+		zr, err := zip.OpenReader(path)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		for _, f := range zr.File {
+			if !strings.HasSuffix(f.Name, ".json") {
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				return nil, err
+			}
+			data, err := io.ReadAll(rc)
+			rc.Close()
+			if err != nil {
+				return nil, err
+			}
+			if err := db.add(data); err != nil {
+				return nil, fmt.Errorf("%s：%v", f.Name, err)
+			}
+		}
+	default:
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := db.add(data); err != nil {
+			return nil, fmt.Errorf("%s：%v", path, err)
+		}
+	}
+	return db, nil
+}
+
+// add 加载一个JSON文档（单个条目或条目数组）
+func (db *VulnDB) add(data []byte) error {
+	data = bytes.TrimSpace(data)
+	var entries []*OSVEntry
+	if len(data) > 0 && data[0] == '[' {
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return err
+		}
+	} else {
+		var e OSVEntry
+		if err := json.Unmarshal(data, &e); err != nil {
+			return err
+		}
+		entries = append(entries, &e)
+	}
+	for _, e := range entries {
+		seen := map[string]bool{}
+		for _, a := range e.Affected {
+			name := NormalizePackageName(a.Package.Name)
+			if a.Package.Ecosystem != "PyPI" || seen[name] {
+				continue
+			}
+			seen[name] = true
+			db.entries[name] = append(db.entries[name], e)
+		}
+		db.count++
+	}
+	return nil
+}
+
+// Match 查询某个包的某个版本受影响的漏洞
+func (db *VulnDB) Match(name, version string) []*OSVEntry {
+	if db == nil {
+		return nil
+	}
+	name = NormalizePackageName(name)
+	v, err := ParsePEP440(version)
+	if err != nil {
+		return nil
+	}
+	var out []*OSVEntry
+	for _, e := range db.entries[name] {
+		if e.affects(name, version, v) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// affects 版本是否在条目的受影响范围内
+func (e *OSVEntry) affects(name, raw string, v PEP440Version) bool {
+	for _, a := range e.Affected {
+		if a.Package.Ecosystem != "PyPI" || NormalizePackageName(a.Package.Name) != name {
+			continue
+		}
+		for _, listed := range a.Versions {
+			if listed == raw {
+				return true
+			}
+			if lv, err := ParsePEP440(listed); err == nil && lv.Compare(v) == 0 {
+				return true
+			}
+		}
+		for _, r := range a.Ranges {
+			if r.Type != "ECOSYSTEM" {
+				continue
+			}
+			// 按事件版本排序后依次判断：introduced开启受影响区间，fixed/last_affected关闭
+			type event struct {
+				kind string
+				v    PEP440Version
+			}
+			var events []event
+			for _, ev := range r.Events {
+				var kind, s string
+				switch {
+				case ev.Introduced != "":
+					kind, s = "introduced", ev.Introduced
+				case ev.Fixed != "":
+					kind, s = "fixed", ev.Fixed
+				case ev.LastAffected != "":
+					kind, s = "last_affected", ev.LastAffected
+				default:
+					continue
+				}
+				if s == "0" {
+					events = append(events, event{kind, PEP440Version{Epoch: -1}})
+					continue
+				}
+				pv, err := ParsePEP440(s)
+				if err != nil {
+					continue
+				}
+				events = append(events, event{kind, pv})
+			}
+			sort.SliceStable(events, func(i, j int) bool { return events[i].v.Compare(events[j].v) < 0 })
+			affected := false
+			for _, ev := range events {
+				switch ev.kind {
+				case "introduced":
+					if v.Compare(ev.v) >= 0 {
+						affected = true
+					}
+				case "fixed":
+					if v.Compare(ev.v) >= 0 {
+						affected = false
+					}
+				case "last_affected":
+					if v.Compare(ev.v) > 0 {
+						affected = false
+					}
+				}
+			}
+			if affected {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// PEP440Version PEP 440版本号
+type PEP440Version struct {
+	Epoch   int
+	Release []int
+	Pre     [2]int // [阶段(0=a,1=b,2=rc), 序号]，Pre[0]为-1表示无
+	Post    int    // -1表示无
+	Dev     int    // -1表示无
+}
+
+var pep440Pattern = regexp.MustCompile(`^v?(?:(\d+)!)?(\d+(?:\.\d+)*)` +
+	`(?:[-_.]?(a|b|c|rc|alpha|beta|pre|preview)[-_.]?(\d*))?` +
+	`(?:-(\d+)|[-_.]?(post|rev|r)[-_.]?(\d*))?` +
+	`(?:[-_.]?(dev)[-_.]?(\d*))?` +
+	`(?:\+[a-z0-9]+(?:[-_.][a-z0-9]+)*)?$`)
+
+// ParsePEP440 解析PEP 440版本号（忽略本地版本标识）
+func ParsePEP440(s string) (PEP440Version, error) {
+	m := pep440Pattern.FindStringSubmatch(strings.ToLower(strings.TrimSpace(s)))
+	if m == nil {
+		return PEP440Version{}, fmt.Errorf("版本号%q不符合PEP 440", s)
+	}
+	v := PEP440Version{Pre: [2]int{-1, 0}, Post: -1, Dev: -1}
+	v.Epoch, _ = strconv.Atoi(m[1])
+	for _, part := range strings.Split(m[2], ".") {
+		n, _ := strconv.Atoi(part)
+		v.Release = append(v.Release, n)
+	}
+	if m[3] != "" {
+		switch m[3] {
+		case "a", "alpha":
+			v.Pre[0] = 0
+		case "b", "beta":
+			v.Pre[0] = 1
+		default:
+			v.Pre[0] = 2
+		}
+		v.Pre[1], _ = strconv.Atoi(m[4])
+	}
+	switch {
+	case m[5] != "":
+		v.Post, _ = strconv.Atoi(m[5])
+	case m[6] != "":
+		v.Post, _ = strconv.Atoi(m[7])
+	}
+	if m[8] != "" {
+		v.Dev, _ = strconv.Atoi(m[9])
+	}
+	return v, nil
+}
+
+// Compare 比较两个版本（-1/0/1）
+func (v PEP440Version) Compare(o PEP440Version) int {
+	if c := cmpInt(v.Epoch, o.Epoch); c != 0 {
+		return c
+	}
+	for i := 0; i < len(v.Release) || i < len(o.Release); i++ {
+		var a, b int
+		if i < len(v.Release) {
+			a = v.Release[i]
+		}
+		if i < len(o.Release) {
+			b = o.Release[i]
+		}
+		if c := cmpInt(a, b); c != 0 {
+			return c
+		}
+	}
+	for i, k := range v.sortKey() {
+		if c := cmpInt(k, o.sortKey()[i]); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// sortKey 发布号相同时的排序键：X.devN < X.aN < X.bN < X.rcN < X < X.postN
+func (v PEP440Version) sortKey() [4]int {
+	pre, preN := math.MaxInt, 0
+	switch {
+	case v.Pre[0] >= 0:
+		pre, preN = v.Pre[0], v.Pre[1]
+	case v.Post < 0 && v.Dev >= 0:
+		pre = math.MinInt
+	}
+	post := math.MinInt
+	if v.Post >= 0 {
+		post = v.Post
+	}
+	dev := math.MaxInt
+	if v.Dev >= 0 {
+		dev = v.Dev
+	}
+	return [4]int{pre, preN, post, dev}
+}
+
+func cmpInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
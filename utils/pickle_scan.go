@@ -0,0 +1,653 @@
+package utils
+
+import (
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// pickle操作码静态分析：只遍历操作码、模拟栈，不执行任何对象构造
+// 列出所有GLOBAL/STACK_GLOBAL/INST引用与REDUCE/NEWOBJ/OBJ调用点，按白名单判定
+
+// DefaultPickleAllowlist 默认允许的pickle全局引用（模块前缀或完整限定名）
+var DefaultPickleAllowlist = []string{
+	"numpy", "sklearn", "scipy", "collections", "joblib.numpy_pickle",
+	"copyreg._reconstructor", "copy_reg._reconstructor", "copyreg.__newobj__",
+	"_codecs.encode", "datetime", "decimal.Decimal",
+	"builtins.set", "builtins.frozenset", "builtins.slice", "builtins.complex",
+	"builtins.bytearray", "builtins.object", "builtins.range",
+	"__builtin__.set", "__builtin__.frozenset", "__builtin__.slice", "__builtin__.complex",
+	"__builtin__.bytearray", "__builtin__.object",
+	// torch.save写入的张量重建函数与存储类型（不放开整个torch模块）
+	"torch._utils._rebuild_tensor", "torch._utils._rebuild_tensor_v2", "torch._utils._rebuild_parameter",
+	"torch.Size", "torch.device", "torch.storage.UntypedStorage",
+	"torch.FloatStorage", "torch.DoubleStorage", "torch.HalfStorage", "torch.BFloat16Storage",
+	"torch.LongStorage", "torch.IntStorage", "torch.ShortStorage", "torch.CharStorage",
+	"torch.ByteStorage", "torch.BoolStorage",
+}
+
+// 明确危险的pickle全局引用（无论白名单如何配置都拒绝）
+var pickleDangerous = []string{
+	"os", "posix", "nt", "subprocess", "sys", "socket", "shutil", "pty", "ctypes",
+	"importlib", "runpy", "webbrowser", "marshal", "pickle", "_pickle", "cPickle", "dill",
+	"types", "code", "codeop", "commands", "platform", "requests", "urllib", "http",
+	"builtins.eval", "builtins.exec", "builtins.compile", "builtins.__import__",
+	"builtins.getattr", "builtins.setattr", "builtins.open", "builtins.globals",
+	"builtins.locals", "builtins.input", "builtins.breakpoint", "builtins.vars",
+	"__builtin__.eval", "__builtin__.execfile", "__builtin__.compile", "__builtin__.__import__",
+	"__builtin__.getattr", "__builtin__.setattr", "__builtin__.open", "__builtin__.file",
+}
+
+// maxPickleDecompressed 压缩的pickle/joblib解压后的大小上限
+const maxPickleDecompressed = 512 << 20
+
+// joblib在非压缩文件中按16字节对齐numpy数组数据
+const joblibAlignment = 16
+
+// PickleRef pickle中的一处全局引用或调用点
+type PickleRef struct {
+	Offset int64  `json:"offset"`
+	Opcode string `json:"opcode"` // GLOBAL/STACK_GLOBAL/INST/REDUCE/NEWOBJ/NEWOBJ_EX/OBJ/EXT
+	Module string `json:"module"`
+	Name   string `json:"name"`
+}
+
+// Qualified 限定名 module.name
+func (r PickleRef) Qualified() string {
+	if r.Module == "" {
+		return r.Name
+	}
+	return r.Module + "." + r.Name
+}
+
+// matchGlobal 限定名是否匹配列表中的某一项（完整匹配或模块前缀匹配）
+func matchGlobal(qualified string, list []string) bool {
+	for _, e := range list {
+		if qualified == e || strings.HasPrefix(qualified, e+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckPickle 检查pickle/joblib内容，返回发现与引用清单
+func CheckPickle(file string, data []byte, allowlist []string) ([]SecurityFinding, []InventoryItem) {
+	raw, err := pickleStream(data)
+	if err != nil {
+		return []SecurityFinding{{File: file, Rule: "pickle-parse-error", Message: "无法解析pickle文件：" + err.Error()}}, nil
+	}
+	refs, err := DisassemblePickle(raw)
+
+	var findings []SecurityFinding
+	var inventory []InventoryItem
+	counted := map[string]int{}
+	add := func(ref PickleRef, rule, msg string) {
+		findings = append(findings, SecurityFinding{
+			File: file, Offset: ref.Offset, Rule: rule, Message: msg,
+			Snippet: fmt.Sprintf("%s %s", ref.Opcode, ref.Qualified()),
+		})
+	}
+	for _, ref := range refs {
+		q := ref.Qualified()
+		key := ref.Opcode + " " + q
+		if n, ok := counted[key]; ok {
+			counted[key] = n + 1
+		} else {
+			counted[key] = 1
+			inventory = append(inventory, InventoryItem{Name: q, Kind: ref.Opcode, Offset: ref.Offset})
+		}
+		switch {
+		case ref.Opcode == "EXT":
+			add(ref, "pickle-extension", "pickle使用扩展注册表引用对象（无法确定目标）")
+		case q == "":
+			add(ref, "pickle-dynamic-global", fmt.Sprintf("pickle %s引用的对象无法静态确定", ref.Opcode))
+		case matchGlobal(q, pickleDangerous):
+			if ref.Opcode == "REDUCE" || ref.Opcode == "NEWOBJ" || ref.Opcode == "NEWOBJ_EX" || ref.Opcode == "OBJ" {
+				add(ref, "pickle-dangerous-call", fmt.Sprintf("pickle反序列化时调用危险对象%s", q))
+			} else {
+				add(ref, "pickle-dangerous-global", fmt.Sprintf("pickle引用危险对象%s", q))
+			}
+		case !matchGlobal(q, allowlist) && (ref.Opcode == "GLOBAL" || ref.Opcode == "STACK_GLOBAL" || ref.Opcode == "INST"):
+			add(ref, "pickle-global-not-allowed", fmt.Sprintf("pickle引用了白名单之外的对象%s", q))
+		}
+	}
+	for i := range inventory {
+		if n := counted[inventory[i].Kind+" "+inventory[i].Name]; n > 1 {
+			inventory[i].Detail = fmt.Sprintf("共%d处", n)
+		}
+	}
+	if err != nil {
+		findings = append(findings, SecurityFinding{File: file, Rule: "pickle-parse-error", Message: "pickle操作码解析失败：" + err.Error()})
+	}
+	return findings, inventory
+}
+
+// pickleStream 识别joblib等使用的压缩格式并解压
+func pickleStream(data []byte) ([]byte, error) {
+	var r io.Reader
+	var err error
+	switch {
+	case bytes.HasPrefix(data, []byte{0x1f, 0x8b}):
+		r, err = gzip.NewReader(bytes.NewReader(data))
+	case len(data) >= 2 && data[0] == 0x78 && binary.BigEndian.Uint16(data)%31 == 0:
+		r, err = zlib.NewReader(bytes.NewReader(data))
+	case bytes.HasPrefix(data, []byte("BZh")):
+		r = bzip2.NewReader(bytes.NewReader(data))
+	case bytes.HasPrefix(data, []byte("\xfd7zXZ")), bytes.HasPrefix(data, []byte{0x04, 0x22, 0x4d, 0x18}):
+		return nil, errors.New("不支持的压缩格式（xz/lz4），无法检查")
+	default:
+		return data, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	out, err := io.ReadAll(io.LimitReader(r, maxPickleDecompressed+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(out) > maxPickleDecompressed {
+		return nil, fmt.Errorf("解压后超过%dMB", maxPickleDecompressed>>20)
+	}
+	return out, nil
+}
+
+// DisassemblePickle 遍历pickle操作码，返回全部全局引用与调用点
+// 支持连续的多个pickle流，以及joblib内联写入的numpy数组数据
+func DisassemblePickle(data []byte) ([]PickleRef, error) {
+	w := &pickleWalker{data: data}
+	pos := 0
+	for {
+		end, err := w.run(pos)
+		if err != nil {
+			return w.refs, err
+		}
+		pos = end
+		// 后续还有以PROTO开头的pickle流时继续检查（pickle.load可被连续调用）
+		if pos >= len(data) || data[pos] != 0x80 {
+			return w.refs, nil
+		}
+	}
+}
+
+// pval 模拟栈上的值（只保留判定所需的信息）
+type pval struct {
+	kind   byte // s字符串 i整数 b真值 g全局对象 t元组/列表 d字典 o调用结果 x其他
+	s      string
+	i      int64
+	items  []*pval // 元组/列表元素；字典为k,v交替；调用结果为参数
+	global string  // g：引用的限定名；o：构造该对象的类/函数
+	state  *pval   // BUILD设置的状态
+}
+
+var (
+	pvUnknown = &pval{kind: 'x'}
+	pvTrue    = &pval{kind: 'b'}
+)
+
+type pickleWalker struct {
+	data  []byte
+	stack []*pval
+	marks []int
+	memo  map[int64]*pval
+	refs  []PickleRef
+}
+
+func (w *pickleWalker) push(v *pval) { w.stack = append(w.stack, v) }
+
+func (w *pickleWalker) pop() *pval {
+	if len(w.stack) == 0 {
+		return pvUnknown
+	}
+	v := w.stack[len(w.stack)-1]
+	w.stack = w.stack[:len(w.stack)-1]
+	return v
+}
+
+func (w *pickleWalker) top() *pval {
+	if len(w.stack) == 0 {
+		return pvUnknown
+	}
+	return w.stack[len(w.stack)-1]
+}
+
+// popMark 弹出到最近的MARK为止的所有元素
+func (w *pickleWalker) popMark() []*pval {
+	if len(w.marks) == 0 {
+		items := w.stack
+		w.stack = nil
+		return items
+	}
+	m := w.marks[len(w.marks)-1]
+	w.marks = w.marks[:len(w.marks)-1]
+	if m > len(w.stack) {
+		m = len(w.stack)
+	}
+	items := append([]*pval(nil), w.stack[m:]...)
+	w.stack = w.stack[:m]
+	return items
+}
+
+func (w *pickleWalker) ref(off int, op, module, name string) {
+	w.refs = append(w.refs, PickleRef{Offset: int64(off), Opcode: op, Module: module, Name: name})
+}
+
+// callRef 记录调用点，返回调用结果
+func (w *pickleWalker) callRef(off int, op string, callable *pval, args []*pval) *pval {
+	target := callable.global
+	// copyreg._reconstructor(cls, base, state)：实际构造的是cls
+	if (target == "copyreg._reconstructor" || target == "copy_reg._reconstructor") && len(args) > 0 {
+		target = args[0].global
+	}
+	if callable.kind == 'g' {
+		module, name := splitQualified(callable.global)
+		w.ref(off, op, module, name)
+	} else {
+		w.ref(off, op, "", "")
+	}
+	return &pval{kind: 'o', global: target, items: args}
+}
+
+func splitQualified(q string) (string, string) {
+	if i := strings.LastIndex(q, "."); i >= 0 {
+		return q[:i], q[i+1:]
+	}
+	return "", q
+}
+
+// run 从pos开始遍历一个pickle流，返回STOP之后的位置
+func (w *pickleWalker) run(pos int) (int, error) {
+	d := w.data
+	w.stack, w.marks, w.memo = nil, nil, map[int64]*pval{}
+	need := func(n int) ([]byte, error) {
+		if n < 0 || pos+n > len(d) {
+			return nil, fmt.Errorf("偏移%d处数据被截断", pos)
+		}
+		b := d[pos : pos+n]
+		pos += n
+		return b, nil
+	}
+	line := func() (string, error) {
+		i := bytes.IndexByte(d[pos:], '\n')
+		if i < 0 {
+			return "", fmt.Errorf("偏移%d处缺少换行", pos)
+		}
+		s := string(d[pos : pos+i])
+		pos += i + 1
+		return s, nil
+	}
+	lenPrefixed := func(size int) ([]byte, error) {
+		b, err := need(size)
+		if err != nil {
+			return nil, err
+		}
+		var n uint64
+		switch size {
+		case 1:
+			n = uint64(b[0])
+		case 4:
+			n = uint64(binary.LittleEndian.Uint32(b))
+		default:
+			n = binary.LittleEndian.Uint64(b)
+		}
+		if n > uint64(len(d)) {
+			return nil, fmt.Errorf("偏移%d处长度字段非法", pos)
+		}
+		return need(int(n))
+	}
+	pushStr := func(b []byte, err error) error {
+		if err != nil {
+			return err
+		}
+		w.push(&pval{kind: 's', s: string(b)})
+		return nil
+	}
+	pushInt := func(v int64) { w.push(&pval{kind: 'i', i: v}) }
+
+	for pos < len(d) {
+		off := pos
+		op := d[pos]
+		pos++
+		var err error
+		switch op {
+		case '.': // STOP
+			return pos, nil
+		case '(': // MARK
+			w.marks = append(w.marks, len(w.stack))
+		case '0': // POP
+			w.pop()
+		case '1': // POP_MARK
+			w.popMark()
+		case '2': // DUP
+			w.push(w.top())
+		case 'N', 0x89: // NONE/NEWFALSE
+			w.push(pvUnknown)
+		case 0x88: // NEWTRUE
+			w.push(pvTrue)
+		case 'I', 'L': // INT/LONG
+			var s string
+			if s, err = line(); err == nil {
+				if s == "01" {
+					w.push(pvTrue) // 协议0中的True
+					break
+				}
+				v, _ := strconv.ParseInt(strings.TrimSuffix(s, "L"), 10, 64)
+				pushInt(v)
+			}
+		case 'F', 'P': // FLOAT/PERSID
+			if _, err = line(); err == nil {
+				w.push(pvUnknown)
+			}
+		case 'J': // BININT
+			var b []byte
+			if b, err = need(4); err == nil {
+				pushInt(int64(int32(binary.LittleEndian.Uint32(b))))
+			}
+		case 'K': // BININT1
+			var b []byte
+			if b, err = need(1); err == nil {
+				pushInt(int64(b[0]))
+			}
+		case 'M': // BININT2
+			var b []byte
+			if b, err = need(2); err == nil {
+				pushInt(int64(binary.LittleEndian.Uint16(b)))
+			}
+		case 0x8a, 0x8b: // LONG1/LONG4
+			size := 1
+			if op == 0x8b {
+				size = 4
+			}
+			var b []byte
+			if b, err = lenPrefixed(size); err == nil {
+				pushInt(decodeLong(b))
+			}
+		case 'G': // BINFLOAT
+			if _, err = need(8); err == nil {
+				w.push(pvUnknown)
+			}
+		case 'Q': // BINPERSID
+			w.pop()
+			w.push(pvUnknown)
+		case 'S': // STRING
+			var s string
+			if s, err = line(); err == nil {
+				if u, e := strconv.Unquote(s); e == nil {
+					s = u
+				} else if len(s) >= 2 {
+					s = s[1 : len(s)-1]
+				}
+				w.push(&pval{kind: 's', s: s})
+			}
+		case 'V': // UNICODE
+			var s string
+			if s, err = line(); err == nil {
+				w.push(&pval{kind: 's', s: s})
+			}
+		case 'U', 'C', 0x8c: // SHORT_BINSTRING/SHORT_BINBYTES/SHORT_BINUNICODE
+			err = pushStr(lenPrefixed(1))
+		case 'T', 'X', 'B': // BINSTRING/BINUNICODE/BINBYTES
+			err = pushStr(lenPrefixed(4))
+		case 0x8d, 0x8e, 0x96: // BINUNICODE8/BINBYTES8/BYTEARRAY8
+			err = pushStr(lenPrefixed(8))
+		case 0x97: // NEXT_BUFFER
+			w.push(pvUnknown)
+		case 0x98: // READONLY_BUFFER
+		case ')', ']', '}', 0x8f: // EMPTY_TUPLE/EMPTY_LIST/EMPTY_DICT/EMPTY_SET
+			kind := byte('t')
+			if op == '}' {
+				kind = 'd'
+			}
+			w.push(&pval{kind: kind})
+		case 't', 'l': // TUPLE/LIST
+			w.push(&pval{kind: 't', items: w.popMark()})
+		case 0x85, 0x86, 0x87: // TUPLE1/2/3
+			n := int(op-0x85) + 1
+			items := make([]*pval, n)
+			for k := n - 1; k >= 0; k-- {
+				items[k] = w.pop()
+			}
+			w.push(&pval{kind: 't', items: items})
+		case 'd': // DICT
+			w.push(&pval{kind: 'd', items: w.popMark()})
+		case 0x91: // FROZENSET
+			w.push(&pval{kind: 't', items: w.popMark()})
+		case 'a': // APPEND
+			v := w.pop()
+			if t := w.top(); t.kind == 't' || t.kind == 'd' {
+				t.items = append(t.items, v)
+			}
+		case 'e', 0x90: // APPENDS/ADDITEMS
+			items := w.popMark()
+			if t := w.top(); t.kind == 't' || t.kind == 'd' {
+				t.items = append(t.items, items...)
+			}
+		case 's': // SETITEM
+			v, k := w.pop(), w.pop()
+			if t := w.top(); t.kind == 't' || t.kind == 'd' {
+				t.items = append(t.items, k, v)
+			}
+		case 'u': // SETITEMS
+			items := w.popMark()
+			if t := w.top(); t.kind == 't' || t.kind == 'd' {
+				t.items = append(t.items, items...)
+			}
+		case 'p': // PUT
+			var s string
+			if s, err = line(); err == nil {
+				n, _ := strconv.ParseInt(s, 10, 64)
+				w.memo[n] = w.top()
+			}
+		case 'q', 'r': // BINPUT/LONG_BINPUT
+			size := 1
+			if op == 'r' {
+				size = 4
+			}
+			var b []byte
+			if b, err = need(size); err == nil {
+				w.memo[memoIndex(b)] = w.top()
+			}
+		case 0x94: // MEMOIZE
+			w.memo[int64(len(w.memo))] = w.top()
+		case 'g': // GET
+			var s string
+			if s, err = line(); err == nil {
+				n, _ := strconv.ParseInt(s, 10, 64)
+				w.push(w.memoGet(n))
+			}
+		case 'h', 'j': // BINGET/LONG_BINGET
+			size := 1
+			if op == 'j' {
+				size = 4
+			}
+			var b []byte
+			if b, err = need(size); err == nil {
+				w.push(w.memoGet(memoIndex(b)))
+			}
+		case 'c', 'i': // GLOBAL/INST
+			var module, name string
+			if module, err = line(); err != nil {
+				break
+			}
+			if name, err = line(); err != nil {
+				break
+			}
+			if op == 'c' {
+				w.ref(off, "GLOBAL", module, name)
+				w.push(&pval{kind: 'g', global: module + "." + name})
+			} else {
+				w.ref(off, "INST", module, name)
+				w.push(&pval{kind: 'o', global: module + "." + name, items: w.popMark()})
+			}
+		case 0x93: // STACK_GLOBAL
+			name, module := w.pop(), w.pop()
+			if module.kind == 's' && name.kind == 's' {
+				w.ref(off, "STACK_GLOBAL", module.s, name.s)
+				w.push(&pval{kind: 'g', global: module.s + "." + name.s})
+			} else {
+				w.ref(off, "STACK_GLOBAL", "", "")
+				w.push(pvUnknown)
+			}
+		case 0x82, 0x83, 0x84: // EXT1/EXT2/EXT4
+			size := map[byte]int{0x82: 1, 0x83: 2, 0x84: 4}[op]
+			var b []byte
+			if b, err = need(size); err == nil {
+				w.ref(off, "EXT", "", fmt.Sprintf("ext#%d", memoIndex(b)))
+				w.push(pvUnknown)
+			}
+		case 'R': // REDUCE
+			args := w.pop()
+			callable := w.pop()
+			w.push(w.callRef(off, "REDUCE", callable, args.items))
+		case 0x81: // NEWOBJ
+			args := w.pop()
+			cls := w.pop()
+			w.push(w.callRef(off, "NEWOBJ", cls, args.items))
+		case 0x92: // NEWOBJ_EX
+			w.pop() // kwargs
+			args := w.pop()
+			cls := w.pop()
+			w.push(w.callRef(off, "NEWOBJ_EX", cls, args.items))
+		case 'o': // OBJ
+			items := w.popMark()
+			cls := pvUnknown
+			if len(items) > 0 {
+				cls, items = items[0], items[1:]
+			}
+			w.push(w.callRef(off, "OBJ", cls, items))
+		case 'b': // BUILD
+			state := w.pop()
+			obj := w.top()
+			if obj.kind == 'o' {
+				obj.state = state
+			}
+			if obj.global == "joblib.numpy_pickle.NumpyArrayWrapper" {
+				pos, err = w.skipJoblibArray(pos, state)
+			}
+		case 0x80: // PROTO
+			_, err = need(1)
+		case 0x95: // FRAME
+			_, err = need(8)
+		default:
+			return pos, fmt.Errorf("偏移%d处存在未知操作码0x%02x", off, op)
+		}
+		if err != nil {
+			return pos, err
+		}
+	}
+	return pos, errors.New("pickle流缺少STOP操作码")
+}
+
+func (w *pickleWalker) memoGet(n int64) *pval {
+	if v, ok := w.memo[n]; ok {
+		return v
+	}
+	return pvUnknown
+}
+
+func memoIndex(b []byte) int64 {
+	var n int64
+	for i := len(b) - 1; i >= 0; i-- {
+		n = n<<8 | int64(b[i])
+	}
+	return n
+}
+
+// decodeLong 解码LONG1/LONG4的小端补码整数（超出int64时返回0，仅用于形状计算）
+func decodeLong(b []byte) int64 {
+	if len(b) == 0 {
+		return 0
+	}
+	if len(b) > 8 {
+		return 0
+	}
+	be := make([]byte, len(b))
+	for i := range b {
+		be[len(b)-1-i] = b[i]
+	}
+	v := new(big.Int).SetBytes(be)
+	if b[len(b)-1]&0x80 != 0 {
+		v.Sub(v, new(big.Int).Lsh(big.NewInt(1), uint(8*len(b))))
+	}
+	return v.Int64()
+}
+
+// dictGet 取模拟字典中字符串键对应的值
+func dictGet(d *pval, key string) *pval {
+	if d == nil || d.kind != 'd' {
+		return pvUnknown
+	}
+	for i := 0; i+1 < len(d.items); i += 2 {
+		if k := d.items[i]; k.kind == 's' && k.s == key {
+			return d.items[i+1]
+		}
+	}
+	return pvUnknown
+}
+
+var dtypePattern = regexp.MustCompile(`^[<>|=]?([a-zA-Z?])(\d*)$`)
+
+// skipJoblibArray 跳过joblib在NumpyArrayWrapper的BUILD之后内联写入的数组数据
+// object类型数组以嵌套pickle流写入，需继续检查其中的引用
+func (w *pickleWalker) skipJoblibArray(pos int, state *pval) (int, error) {
+	dtype := dictGet(state, "dtype")
+	code := ""
+	if dtype.kind == 'o' && len(dtype.items) > 0 && dtype.items[0].kind == 's' {
+		code = dtype.items[0].s
+	}
+	m := dtypePattern.FindStringSubmatch(code)
+	if m == nil {
+		return pos, fmt.Errorf("joblib数组类型%q无法识别，无法确定数据长度", code)
+	}
+	if m[1] == "O" {
+		// object数组：joblib用pickle.dump写出，按嵌套pickle流检查
+		stack, marks, memo := w.stack, w.marks, w.memo
+		end, err := w.run(pos)
+		w.stack, w.marks, w.memo = stack, marks, memo
+		return end, err
+	}
+
+	itemSize, _ := strconv.ParseInt(m[2], 10, 64)
+	switch m[1] {
+	case "U":
+		itemSize *= 4
+	case "?":
+		itemSize = 1
+	}
+	if itemSize <= 0 {
+		return pos, fmt.Errorf("joblib数组类型%q无法确定元素大小", code)
+	}
+	count := int64(1)
+	shape := dictGet(state, "shape")
+	if shape.kind != 't' {
+		return pos, errors.New("joblib数组缺少shape")
+	}
+	for _, dim := range shape.items {
+		if dim.kind != 'i' || dim.i < 0 || (dim.i > 0 && count > int64(len(w.data))/dim.i) {
+			return pos, errors.New("joblib数组shape非法")
+		}
+		count *= dim.i
+	}
+	if dictGet(state, "allow_mmap") == pvTrue {
+		// 可内存映射的数组前有1字节填充长度及填充字节
+		if pos >= len(w.data) {
+			return pos, errors.New("joblib数组数据被截断")
+		}
+		if int(w.data[pos]) <= joblibAlignment {
+			pos += 1 + int(w.data[pos])
+		}
+	}
+	size := count * itemSize
+	if size > int64(len(w.data)-pos) {
+		return pos, errors.New("joblib数组数据被截断")
+	}
+	return pos + int(size), nil
+}
@@ -0,0 +1,649 @@
+package utils
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Python源码分析：词法切分 + 轻量语法分析
+// 解析import/别名后识别危险调用点，忽略注释与普通字符串中的内容；
+// 传给eval/exec的常量字符串会作为代码再次分析
+
+// pyTokKind Python词法单元类型
+type pyTokKind int
+
+const (
+	pyName pyTokKind = iota
+	pyNumber
+	pyString
+	pyOp
+	pyNewline
+)
+
+// pyToken Python词法单元
+type pyToken struct {
+	Kind pyTokKind
+	Text string // 字符串字面量为解码后的值
+	Line int
+	Fmt  bool // f-string（含插值，不能常量折叠）
+}
+
+// maxPyEvalDepth eval/exec中常量代码的最大嵌套分析深度
+const maxPyEvalDepth = 3
+
+// Python关键字（不作为名字解析）
+var pyKeywords = map[string]bool{
+	"False": true, "None": true, "True": true, "and": true, "as": true, "assert": true,
+	"async": true, "await": true, "break": true, "class": true, "continue": true, "def": true,
+	"del": true, "elif": true, "else": true, "except": true, "finally": true, "for": true,
+	"from": true, "global": true, "if": true, "import": true, "in": true, "is": true,
+	"lambda": true, "nonlocal": true, "not": true, "or": true, "pass": true, "raise": true,
+	"return": true, "try": true, "while": true, "with": true, "yield": true,
+}
+
+// 需要解析为builtins.xxx的内置函数
+var pyBuiltins = map[string]bool{
+	"eval": true, "exec": true, "compile": true, "__import__": true, "getattr": true,
+}
+
+// 敏感模块（动态导入或动态属性访问时告警）
+var pySensitiveModules = map[string]bool{
+	"os": true, "subprocess": true, "builtins": true, "socket": true, "ctypes": true,
+	"pickle": true, "cPickle": true, "_pickle": true, "dill": true, "marshal": true,
+	"pty": true, "importlib": true,
+}
+
+// 反序列化模块（load/loads/Unpickler视为危险调用）
+var pyPickleModules = map[string]bool{
+	"pickle": true, "cPickle": true, "_pickle": true, "dill": true, "marshal": true,
+	"shelve": true, "jsonpickle": true,
+}
+
+// isPythonFile 是否为Python源文件
+func isPythonFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".py" || ext == ".pyw"
+}
+
+// pyCallRule 返回限定名对应的危险调用规则ID（非危险调用返回空）
+func pyCallRule(name string) string {
+	switch name {
+	case "os.system", "os.popen", "pty.spawn", "commands.getoutput", "commands.getstatusoutput":
+		return "os-system"
+	case "builtins.eval":
+		return "eval-call"
+	case "builtins.exec", "builtins.compile":
+		return "exec-call"
+	case "socket.socket", "socket.create_connection", "socket.fromfd", "socket.socketpair":
+		return "raw-socket"
+	case "requests.post":
+		return "requests-post"
+	}
+	mod, fn := "", name
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		mod, fn = name[:i], name[i+1:]
+	}
+	switch {
+	case mod == "":
+		return ""
+	case mod == "os" && (strings.HasPrefix(fn, "exec") || strings.HasPrefix(fn, "spawn") || strings.HasPrefix(fn, "posix_spawn")):
+		return "os-system"
+	case rootModule(name) == "subprocess":
+		return "subprocess-call"
+	case rootModule(name) == "ctypes":
+		return "ctypes-call"
+	case pyPickleModules[mod] && (fn == "load" || fn == "loads" || fn == "Unpickler" || fn == "open"):
+		return "pickle-load"
+	}
+	return ""
+}
+
+// rootModule 取限定名的顶层模块
+func rootModule(name string) string {
+	if i := strings.Index(name, "."); i >= 0 {
+		return name[:i]
+	}
+	return name
+}
+
+// AnalyzePython 分析Python源码，返回危险调用等发现
+func AnalyzePython(file string, src []byte) []SecurityFinding {
+	code := string(src)
+	a := &pyAnalyzer{
+		file:    file,
+		lines:   strings.Split(code, "\n"),
+		aliases: map[string]string{"__builtins__": "builtins"},
+		locals:  map[string]bool{},
+		seen:    map[string]bool{},
+	}
+	a.run(code)
+	return a.findings
+}
+
+// pyAnalyzer 单个文件的分析状态
+type pyAnalyzer struct {
+	file     string
+	lines    []string
+	aliases  map[string]string // 本地名 → 限定名（import/赋值产生的别名）
+	stars    []string          // from X import * 的模块
+	locals   map[string]bool   // 被本地重新赋值的内置函数名
+	findings []SecurityFinding
+	seen     map[string]bool
+
+	depth    int    // eval/exec嵌套分析深度
+	baseLine int    // 嵌套分析时所有发现都记在调用所在行
+	prefix   string // 嵌套分析时的说明前缀
+}
+
+func (a *pyAnalyzer) run(code string) {
+	var stmt []pyToken
+	for _, t := range tokenizePython(code) {
+		if t.Kind == pyNewline {
+			a.statement(stmt)
+			stmt = stmt[:0]
+			continue
+		}
+		stmt = append(stmt, t)
+	}
+	a.statement(stmt)
+}
+
+// add 记录一条发现（同一行同一规则同一说明只记一次）
+func (a *pyAnalyzer) add(line int, rule, msg string) {
+	if a.baseLine > 0 {
+		line = a.baseLine
+	}
+	msg = a.prefix + msg
+	key := fmt.Sprintf("%d|%s|%s", line, rule, msg)
+	if a.seen[key] {
+		return
+	}
+	a.seen[key] = true
+	f := SecurityFinding{File: a.file, Line: line, Rule: rule, Message: msg}
+	if line >= 1 && line <= len(a.lines) {
+		f.Snippet = snippet(a.lines[line-1])
+	}
+	a.findings = append(a.findings, f)
+}
+
+func (a *pyAnalyzer) statement(s []pyToken) {
+	if len(s) == 0 {
+		return
+	}
+	if s[0].Kind == pyName {
+		switch s[0].Text {
+		case "import":
+			a.importStmt(s[1:])
+			return
+		case "from":
+			a.fromStmt(s[1:])
+			return
+		}
+	}
+	a.scanCalls(s)
+	a.trackAssignment(s)
+}
+
+// importStmt import a.b [as c], ...
+func (a *pyAnalyzer) importStmt(s []pyToken) {
+	for _, part := range splitArgs(stripParens(s)) {
+		mod, local := "", ""
+		for i := 0; i < len(part); i++ {
+			t := part[i]
+			if t.Kind == pyName && t.Text == "as" && i+1 < len(part) {
+				local = part[i+1].Text
+				break
+			}
+			mod += t.Text
+		}
+		if mod == "" {
+			continue
+		}
+		if local == "" {
+			// import a.b 绑定的是顶层名a
+			local, mod = rootModule(mod), rootModule(mod)
+		}
+		a.bind(local, mod)
+	}
+}
+
+// fromStmt from x.y import a [as b], ... / from x import *
+func (a *pyAnalyzer) fromStmt(s []pyToken) {
+	mod, i := "", 0
+	for ; i < len(s) && !(s[i].Kind == pyName && s[i].Text == "import"); i++ {
+		mod += s[i].Text
+	}
+	mod = strings.TrimLeft(mod, ".")
+	if i >= len(s) {
+		return
+	}
+	for _, part := range splitArgs(stripParens(s[i+1:])) {
+		if len(part) == 0 {
+			continue
+		}
+		if part[0].Kind == pyOp && part[0].Text == "*" {
+			a.stars = append(a.stars, mod)
+			continue
+		}
+		name, local := part[0].Text, part[0].Text
+		if len(part) >= 3 && part[1].Text == "as" {
+			local = part[2].Text
+		}
+		if mod != "" {
+			name = mod + "." + name
+		}
+		a.bind(local, name)
+	}
+}
+
+func (a *pyAnalyzer) bind(local, qualified string) {
+	a.aliases[local] = qualified
+	delete(a.locals, local)
+}
+
+// resolve 把本地名解析为限定名
+func (a *pyAnalyzer) resolve(name string) string {
+	if q, ok := a.aliases[name]; ok {
+		return q
+	}
+	if a.locals[name] {
+		return name
+	}
+	for _, m := range a.stars {
+		if pyCallRule(m+"."+name) != "" {
+			return m + "." + name
+		}
+	}
+	if pyBuiltins[name] {
+		return "builtins." + name
+	}
+	return name
+}
+
+// scanCalls 扫描语句中的所有调用点
+func (a *pyAnalyzer) scanCalls(s []pyToken) {
+	for i, t := range s {
+		if t.Kind != pyName || pyKeywords[t.Text] {
+			continue
+		}
+		if i > 0 && s[i-1].Kind == pyOp && s[i-1].Text == "." {
+			continue // 属性名由所在表达式的起点处理
+		}
+		if i > 0 && s[i-1].Kind == pyName && (s[i-1].Text == "def" || s[i-1].Text == "class") {
+			if t.Text == "__reduce__" || t.Text == "__reduce_ex__" {
+				a.add(t.Line, "pickle-reduce", "定义了"+t.Text+"（pickle反序列化利用特征）")
+			}
+			continue
+		}
+		a.primary(s, i)
+	}
+}
+
+// trackAssignment 跟踪 x = os.system 这类别名赋值
+func (a *pyAnalyzer) trackAssignment(s []pyToken) {
+	if len(s) < 3 || s[0].Kind != pyName || s[1].Kind != pyOp || s[1].Text != "=" {
+		return
+	}
+	lhs := s[0].Text
+	if s[2].Kind == pyName && !pyKeywords[s[2].Text] {
+		if name, j := a.primary(s, 2); name != "" && j == len(s) {
+			a.bind(lhs, name)
+			return
+		}
+	}
+	delete(a.aliases, lhs)
+	if pyBuiltins[lhs] {
+		a.locals[lhs] = true
+	}
+}
+
+// primary 解析从s[i]开始的属性/调用链，记录链上的危险调用
+// 返回链末端的限定名（调用结果等无法确定时为空）及结束位置
+func (a *pyAnalyzer) primary(s []pyToken, i int) (string, int) {
+	name, j := a.atom(s, i)
+	for j < len(s) && s[j].Kind == pyOp {
+		switch s[j].Text {
+		case ".":
+			if j+1 >= len(s) || s[j+1].Kind != pyName {
+				return name, j
+			}
+			if name != "" {
+				name += "." + s[j+1].Text
+			}
+			j += 2
+		case "(":
+			end := matchClose(s, j)
+			if name != "" {
+				a.call(name, s[j+1:min(end, len(s))], s[i].Line)
+			}
+			name, j = "", end+1
+		case "[":
+			name, j = "", matchClose(s, j)+1
+		default:
+			return name, j
+		}
+	}
+	return name, j
+}
+
+// atom 解析表达式起点，处理__import__('x')、importlib.import_module('x')、getattr(obj, 'a'+'b')等动态形式
+func (a *pyAnalyzer) atom(s []pyToken, i int) (string, int) {
+	head, j := a.resolve(s[i].Text), i+1
+	// importlib.import_module需要先拼出完整限定名
+	for j+1 < len(s) && s[j].Kind == pyOp && s[j].Text == "." && s[j+1].Kind == pyName &&
+		(head == "importlib" || head == "builtins") {
+		head, j = head+"."+s[j+1].Text, j+2
+	}
+	if j >= len(s) || s[j].Kind != pyOp || s[j].Text != "(" {
+		return head, j
+	}
+	line := s[i].Line
+	end := matchClose(s, j)
+	args := splitArgs(s[j+1 : min(end, len(s))])
+	switch head {
+	case "builtins.__import__", "importlib.import_module", "importlib.__import__":
+		if len(args) > 0 {
+			if mod, ok := foldString(args[0]); ok {
+				if pySensitiveModules[rootModule(mod)] {
+					a.add(line, "dynamic-import", fmt.Sprintf("动态导入敏感模块%s", mod))
+				}
+				return mod, end + 1
+			}
+		}
+		a.add(line, "dynamic-import", "动态导入（模块名非常量）")
+		return "", end + 1
+	case "builtins.getattr":
+		if len(args) >= 2 && len(args[0]) > 0 && args[0][0].Kind == pyName {
+			obj, oj := a.primary(args[0], 0)
+			if obj != "" && oj == len(args[0]) {
+				if attr, ok := foldString(args[1]); ok {
+					return obj + "." + attr, end + 1
+				}
+				if pySensitiveModules[rootModule(obj)] {
+					a.add(line, "dynamic-getattr", fmt.Sprintf("对敏感模块%s进行动态属性访问", obj))
+				}
+			}
+		}
+		return "", end + 1
+	}
+	return head, j
+}
+
+// call 记录一次调用；eval/exec的常量字符串参数会作为代码继续分析
+func (a *pyAnalyzer) call(name string, argToks []pyToken, line int) {
+	rule := pyCallRule(name)
+	if rule == "" {
+		return
+	}
+	a.add(line, rule, "检测到危险调用"+strings.TrimPrefix(name, "builtins."))
+	if (rule != "eval-call" && rule != "exec-call") || a.depth >= maxPyEvalDepth {
+		return
+	}
+	args := splitArgs(argToks)
+	if len(args) == 0 {
+		return
+	}
+	code, ok := foldString(args[0])
+	if !ok {
+		return
+	}
+	nested := &pyAnalyzer{
+		file:     a.file,
+		lines:    a.lines,
+		aliases:  map[string]string{},
+		locals:   a.locals,
+		stars:    a.stars,
+		seen:     a.seen,
+		depth:    a.depth + 1,
+		baseLine: line,
+		prefix:   a.prefix + "动态执行的代码中",
+	}
+	if a.baseLine > 0 {
+		nested.baseLine = a.baseLine
+	}
+	for k, v := range a.aliases {
+		nested.aliases[k] = v
+	}
+	nested.run(code)
+	a.findings = append(a.findings, nested.findings...)
+}
+
+// matchClose 返回与s[j]处左括号匹配的右括号位置（未闭合时返回len(s)）
+func matchClose(s []pyToken, j int) int {
+	depth := 0
+	for k := j; k < len(s); k++ {
+		if s[k].Kind != pyOp {
+			continue
+		}
+		switch s[k].Text {
+		case "(", "[", "{":
+			depth++
+		case ")", "]", "}":
+			depth--
+			if depth == 0 {
+				return k
+			}
+		}
+	}
+	return len(s)
+}
+
+// splitArgs 按最外层逗号切分参数
+func splitArgs(s []pyToken) [][]pyToken {
+	var args [][]pyToken
+	depth, start := 0, 0
+	for k, t := range s {
+		if t.Kind != pyOp {
+			continue
+		}
+		switch t.Text {
+		case "(", "[", "{":
+			depth++
+		case ")", "]", "}":
+			depth--
+		case ",":
+			if depth == 0 {
+				args = append(args, s[start:k])
+				start = k + 1
+			}
+		}
+	}
+	if start < len(s) {
+		args = append(args, s[start:])
+	}
+	return args
+}
+
+// stripParens 去掉包住整个表达式的括号
+func stripParens(s []pyToken) []pyToken {
+	for len(s) >= 2 && s[0].Kind == pyOp && s[0].Text == "(" && matchClose(s, 0) == len(s)-1 {
+		s = s[1 : len(s)-1]
+	}
+	return s
+}
+
+// foldString 常量折叠：'sys' + 'tem'、相邻字符串拼接
+func foldString(s []pyToken) (string, bool) {
+	s = stripParens(s)
+	var b strings.Builder
+	expectStr := true
+	for _, t := range s {
+		switch {
+		case t.Kind == pyString && !t.Fmt:
+			b.WriteString(t.Text)
+			expectStr = false
+		case t.Kind == pyOp && t.Text == "+" && !expectStr:
+			expectStr = true
+		default:
+			return "", false
+		}
+	}
+	return b.String(), len(s) > 0 && !expectStr
+}
+
+// tokenizePython Python词法切分（跳过注释，括号内换行不结束语句）
+func tokenizePython(src string) []pyToken {
+	var toks []pyToken
+	line, depth := 1, 0
+	newline := func() {
+		if len(toks) > 0 && toks[len(toks)-1].Kind != pyNewline {
+			toks = append(toks, pyToken{Kind: pyNewline, Line: line})
+		}
+	}
+	for i, n := 0, len(src); i < n; {
+		c := src[i]
+		switch {
+		case c == '\n':
+			if depth == 0 {
+				newline()
+			}
+			line++
+			i++
+		case c == '\\' && i+1 < n && src[i+1] == '\n':
+			line++
+			i += 2
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f' || c == '\\':
+			i++
+		case c == '#':
+			for i < n && src[i] != '\n' {
+				i++
+			}
+		case isPyIdentStart(c):
+			j := i
+			for j < n && (isPyIdentStart(src[j]) || src[j] >= '0' && src[j] <= '9') {
+				j++
+			}
+			word := src[i:j]
+			if j < n && (src[j] == '\'' || src[j] == '"') && isPyStringPrefix(word) {
+				tok, end, lines := scanPyString(src, j, word)
+				tok.Line = line
+				toks = append(toks, tok)
+				line += lines
+				i = end
+				continue
+			}
+			toks = append(toks, pyToken{Kind: pyName, Text: word, Line: line})
+			i = j
+		case c >= '0' && c <= '9' || c == '.' && i+1 < n && src[i+1] >= '0' && src[i+1] <= '9':
+			j := i + 1
+			for j < n && (isPyIdentStart(src[j]) || src[j] >= '0' && src[j] <= '9' || src[j] == '.') {
+				j++
+			}
+			toks = append(toks, pyToken{Kind: pyNumber, Text: src[i:j], Line: line})
+			i = j
+		case c == '\'' || c == '"':
+			tok, end, lines := scanPyString(src, i, "")
+			tok.Line = line
+			toks = append(toks, tok)
+			line += lines
+			i = end
+		default:
+			op := src[i : i+1]
+			if i+1 < n {
+				next := src[i+1]
+				if next == '=' && strings.IndexByte("=!<>+-*/%&|^:@", c) >= 0 ||
+					c == '*' && next == '*' || c == '/' && next == '/' || c == '-' && next == '>' {
+					op = src[i : i+2]
+				}
+			}
+			switch c {
+			case '(', '[', '{':
+				depth++
+			case ')', ']', '}':
+				if depth > 0 {
+					depth--
+				}
+			}
+			i += len(op)
+			if op == ";" && depth == 0 {
+				newline()
+				continue
+			}
+			toks = append(toks, pyToken{Kind: pyOp, Text: op, Line: line})
+		}
+	}
+	newline()
+	return toks
+}
+
+func isPyIdentStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+func isPyStringPrefix(word string) bool {
+	switch strings.ToLower(word) {
+	case "r", "u", "b", "f", "br", "rb", "fr", "rf":
+		return true
+	}
+	return false
+}
+
+// scanPyString 解析从src[q]开始的字符串字面量，返回词法单元、结束位置及跨越的行数
+func scanPyString(src string, q int, prefix string) (pyToken, int, int) {
+	raw := strings.ContainsAny(prefix, "rR")
+	tok := pyToken{Kind: pyString, Fmt: strings.ContainsAny(prefix, "fF")}
+	quote := src[q : q+1]
+	if strings.HasPrefix(src[q:], strings.Repeat(quote, 3)) {
+		quote = strings.Repeat(quote, 3)
+	}
+	var b strings.Builder
+	lines := 0
+	i := q + len(quote)
+	for i < len(src) {
+		if strings.HasPrefix(src[i:], quote) {
+			i += len(quote)
+			break
+		}
+		c := src[i]
+		if c == '\n' {
+			if len(quote) == 1 {
+				break // 未闭合的单行字符串
+			}
+			lines++
+		}
+		if c == '\\' && i+1 < len(src) {
+			e := src[i+1]
+			if e == '\n' {
+				lines++
+			}
+			i += 2
+			if raw {
+				b.WriteByte(c)
+				b.WriteByte(e)
+				continue
+			}
+			switch e {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			case '0':
+				b.WriteByte(0)
+			case '\\', '\'', '"':
+				b.WriteByte(e)
+			case '\n':
+			case 'x':
+				if i+2 <= len(src) {
+					if v, err := strconv.ParseUint(src[i:i+2], 16, 8); err == nil {
+						b.WriteByte(byte(v))
+						i += 2
+						continue
+					}
+				}
+				b.WriteString(`\x`)
+			default:
+				b.WriteByte(c)
+				b.WriteByte(e)
+			}
+			continue
+		}
+		b.WriteByte(c)
+		i++
+	}
+	tok.Text = b.String()
+	return tok, i, lines
+}
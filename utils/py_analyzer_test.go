@@ -0,0 +1,109 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestAnalyzePython(t *testing.T) {
+	cases := []struct {
+		name string
+		src  string
+		want []string // 期望的规则ID（为空表示没有发现）
+	}{
+		// 直接调用与别名
+		{"os.system", "import os\nos.system('id')\n", []string{"os-system"}},
+		{"import alias", "import os as o\no.system('id')\n", []string{"os-system"}},
+		{"from import alias", "from os import system as run\nrun('id')\n", []string{"os-system"}},
+		{"star import", "from os import *\nsystem('id')\n", []string{"os-system"}},
+		{"assigned alias", "import subprocess\np = subprocess.Popen\np(['ls'])\n", []string{"subprocess-call"}},
+		{"submodule import", "import os.path\nos.popen('id')\n", []string{"os-system"}},
+		{"multi-line call", "import subprocess\nsubprocess.run(\n    ['ls'],\n    check=True,\n)\n", []string{"subprocess-call"}},
+		{"socket", "import socket\ns = socket.socket()\n", []string{"raw-socket"}},
+		{"pickle.loads", "import pickle\nobj = pickle.loads(data)\n", []string{"pickle-load"}},
+		{"ctypes", "import ctypes\nlibc = ctypes.CDLL('libc.so.6')\n", []string{"ctypes-call"}},
+		{"reduce", "class Evil:\n    def __reduce__(self):\n        return (print, ('x',))\n", []string{"pickle-reduce"}},
+
+		// 动态形式
+		{"getattr concat", "import os\ngetattr(os, 'sys'+'tem')('id')\n", []string{"os-system"}},
+		{"getattr variable", "import os\nname = input()\ngetattr(os, name)('id')\n", []string{"dynamic-getattr"}},
+		{"__import__", "__import__('subprocess').call(['ls'])\n", []string{"dynamic-import", "subprocess-call"}},
+		{"__import__ non-constant", "mod = input()\n__import__(mod)\n", []string{"dynamic-import"}},
+		{"import_module", "import importlib\nm = importlib.import_module('os')\nm.system('id')\n", []string{"dynamic-import", "os-system"}},
+		{"builtins.eval", "import builtins\nbuiltins.eval(code)\n", []string{"eval-call"}},
+
+		// eval/exec的常量字符串按代码分析
+		{"eval constant", "eval(\"__import__('os').system('id')\")\n", []string{"eval-call", "dynamic-import", "os-system"}},
+		{"exec constant", "import os\nexec('os.sys' + 'tem(\"id\")')\n", []string{"exec-call", "os-system"}},
+		{"exec nested", "exec(\"exec('import subprocess; subprocess.call([1])')\")\n", []string{"exec-call", "subprocess-call"}},
+
+		// 注释与字符串中的内容不报告
+		{"comment", "# uses crypto; never call os.system('rm -rf /')\nprint('ok')\n", nil},
+		{"crypto comment", "import hashlib  # crypto hash for cache keys\nkey = hashlib.sha256(b'x').hexdigest()\n", nil},
+		{"string literal", "msg = 'os.system(\"id\") and eval(x)'\nprint(msg)\n", nil},
+		{"docstring", "def f():\n    \"\"\"Never use eval(x) or subprocess.Popen here.\n    __import__('os')\n    \"\"\"\n    return 1\n", nil},
+		{"unevaluated code string", "code = \"__import__('os').system('id')\"\n", nil},
+
+		// 同名但不是危险调用
+		{"other module", "import mylib\nmylib.system('x')\n", nil},
+		{"method named eval", "model.eval()\nresult = torch.no_grad()\n", nil},
+		{"shadowed builtin", "eval = lambda x: x\neval('1')\n", nil},
+		{"local function", "def system(cmd):\n    return cmd\nsystem('id')\n", nil},
+		{"requests.get", "import requests\nr = requests.get('http://example.com')\n", nil},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			findings := AnalyzePython("app.py", []byte(c.src))
+			got := map[string]bool{}
+			for _, f := range findings {
+				got[f.Rule] = true
+			}
+			var rules []string
+			for r := range got {
+				rules = append(rules, r)
+			}
+			sort.Strings(rules)
+			want := append([]string(nil), c.want...)
+			sort.Strings(want)
+			if strings.Join(rules, ",") != strings.Join(want, ",") {
+				t.Errorf("rules = %v, want %v\nfindings: %v", rules, want, findings)
+			}
+		})
+	}
+}
+
+func TestAnalyzePythonLines(t *testing.T) {
+	src := "import os\n\n# os.system('comment')\nx = 1\nos.system('id')\neval(\"os.system('nested')\")\n"
+	lines := map[string][]int{}
+	for _, f := range AnalyzePython("app.py", []byte(src)) {
+		lines[f.Rule] = append(lines[f.Rule], f.Line)
+	}
+	// 动态执行的代码中的发现记在eval所在行
+	if got := lines["os-system"]; len(got) != 2 || got[0] != 5 || got[1] != 6 {
+		t.Errorf("os-system lines = %v, want [5 6]", got)
+	}
+	if got := lines["eval-call"]; len(got) != 1 || got[0] != 6 {
+		t.Errorf("eval-call lines = %v, want [6]", got)
+	}
+}
+
+// Python文件不再做正则匹配：注释中的crypto等关键词不应触发检查
+func TestPythonSkipsRegexRules(t *testing.T) {
+	dir := t.TempDir()
+	src := "# crypto helpers, see os.system docs\nimport hashlib\n\ndef digest(b):\n    return hashlib.sha256(b).hexdigest()\n"
+	if err := os.WriteFile(filepath.Join(dir, "app.py"), []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "requirements.txt"), []byte("numpy==1.26.4\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	result := CheckModelSecurity(dir)
+	for _, f := range result.Findings {
+		if f.File == "app.py" {
+			t.Errorf("unexpected finding in app.py: %v", f)
+		}
+	}
+}
@@ -0,0 +1,343 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// requirements.txt依赖检查：解析pip需求文件（固定版本、extras、环境标记、-r包含、索引覆盖），
+// 按允许/禁止名单、仿冒包名（typosquatting）和离线漏洞库评估每个依赖
+
+// RequirementsFile 代码包根目录下的依赖文件
+const RequirementsFile = "requirements.txt"
+
+// maxRequirementsDepth -r/-c 嵌套包含的最大深度
+const maxRequirementsDepth = 8
+
+// Requirement 一条依赖
+type Requirement struct {
+	Name      string   // 规范化包名（PEP 503）
+	RawName   string   // 文件中的包名
+	Extras    []string // extras
+	Specifier string   // 版本约束（如 ==1.2.3、>=2,<3）
+	Version   string   // 固定的版本（== 或 ===，不含通配符），未固定时为空
+	Marker    string   // 环境标记
+	URL       string   // 直接引用（name @ url、VCS、本地路径）
+	Editable  bool     // -e
+	File      string   // 所在文件（相对代码包根目录）
+	Line      int      // 行号
+}
+
+// DependencyPolicy 依赖检查策略
+type DependencyPolicy struct {
+	Allow       []string // 允许名单（非空时只允许名单中的包）
+	Deny        []string // 禁止名单
+	Popular     []string // 仿冒检测的常用包名（为空时使用内置列表）
+	RequirePins bool     // 要求固定版本（未固定时以requirements-unpinned规则报告）
+	Vulns       *VulnDB  // 离线漏洞库（为空时跳过漏洞匹配）
+}
+
+// 内置常用PyPI包名（仿冒检测基准）
+var popularPackages = []string{
+	"requests", "numpy", "pandas", "scipy", "matplotlib", "scikit-learn", "torch", "torchvision",
+	"tensorflow", "keras", "transformers", "tokenizers", "datasets", "accelerate", "safetensors",
+	"huggingface-hub", "sentencepiece", "onnx", "onnxruntime", "opencv-python", "pillow", "flask",
+	"django", "fastapi", "uvicorn", "gunicorn", "pydantic", "sqlalchemy", "psycopg2", "pymysql",
+	"redis", "celery", "boto3", "botocore", "urllib3", "certifi", "charset-normalizer", "idna",
+	"setuptools", "wheel", "pip", "six", "python-dateutil", "pytz", "pyyaml", "jinja2", "markupsafe",
+	"click", "attrs", "packaging", "cryptography", "pyopenssl", "paramiko", "protobuf", "grpcio",
+	"aiohttp", "httpx", "beautifulsoup4", "lxml", "tqdm", "joblib", "xgboost", "lightgbm", "catboost",
+	"nltk", "spacy", "gensim", "jieba", "openai", "langchain", "tiktoken", "pytest", "colorama",
+	"simplejson", "ujson", "orjson", "websocket-client", "websockets", "werkzeug", "itsdangerous",
+	"matplotlib-inline", "seaborn", "plotly", "statsmodels", "sympy", "networkx", "regex", "filelock",
+	"fsspec", "pyarrow", "h5py", "tensorboard", "timm", "einops", "peft", "bitsandbytes", "vllm",
+}
+
+var (
+	requirementNamePattern = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9._-]*)\s*(?:\[([^\]]*)\])?\s*(.*)$`)
+	packageNameSeparators  = regexp.MustCompile(`[-_.]+`)
+	urlRequirementPattern  = regexp.MustCompile(`^(?:git\+|hg\+|svn\+|bzr\+)?(?:https?|ftp|file|ssh)://|^\.{0,2}/`)
+)
+
+// NormalizePackageName PEP 503包名规范化
+func NormalizePackageName(name string) string {
+	return packageNameSeparators.ReplaceAllString(strings.ToLower(strings.TrimSpace(name)), "-")
+}
+
+// requirementsParser 解析需求文件（含嵌套包含）
+type requirementsParser struct {
+	root     string // 代码包根目录
+	reqs     []Requirement
+	findings map[string][]SecurityFinding // 文件 → 发现
+	visited  map[string]bool
+}
+
+// CheckRequirements 检查代码包根目录下的requirements.txt，返回各文件的发现与依赖清单
+func CheckRequirements(root string, policy DependencyPolicy) (map[string][]SecurityFinding, []Requirement) {
+	p := &requirementsParser{root: root, findings: map[string][]SecurityFinding{}, visited: map[string]bool{}}
+	if _, err := os.Stat(filepath.Join(root, RequirementsFile)); err != nil {
+		return nil, nil
+	}
+	p.parseFile(RequirementsFile, 0)
+	for _, r := range p.reqs {
+		p.evaluate(r, policy)
+	}
+	return p.findings, p.reqs
+}
+
+// ParseRequirements 只解析代码包根目录的requirements.txt（含-r/-c引用的文件），不做策略检查
+func ParseRequirements(root string) []Requirement {
+	p := &requirementsParser{root: root, findings: map[string][]SecurityFinding{}, visited: map[string]bool{}}
+	p.parseFile(RequirementsFile, 0)
+	return p.reqs
+}
+
+func (p *requirementsParser) add(file string, line int, rule, msg, snip string) {
+	p.findings[file] = append(p.findings[file], SecurityFinding{File: file, Line: line, Rule: rule, Message: msg, Snippet: snippet(snip)})
+}
+
+// parseFile 解析一个需求文件（rel为相对代码包根目录的路径）
+func (p *requirementsParser) parseFile(rel string, depth int) {
+	if p.visited[rel] {
+		return
+	}
+	p.visited[rel] = true
+	data, err := os.ReadFile(filepath.Join(p.root, filepath.FromSlash(rel)))
+	if err != nil {
+		return
+	}
+
+	lines := strings.Split(string(data), "\n")
+	for i := 0; i < len(lines); i++ {
+		lineNo := i + 1
+		line := strings.TrimRight(lines[i], "\r")
+		// 行尾反斜杠续行
+		for strings.HasSuffix(line, "\\") && i+1 < len(lines) {
+			i++
+			line = strings.TrimSuffix(line, "\\") + strings.TrimRight(lines[i], "\r")
+		}
+		// 注释：行首#或空白后的#
+		if idx := strings.Index(line, "#"); idx == 0 || (idx > 0 && (line[idx-1] == ' ' || line[idx-1] == '\t')) {
+			line = line[:idx]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "-") {
+			p.option(rel, lineNo, line, depth)
+			continue
+		}
+		p.requirement(rel, lineNo, line, false)
+	}
+}
+
+// option 处理pip选项行
+func (p *requirementsParser) option(rel string, lineNo int, line string, depth int) {
+	name, value := line, ""
+	if i := strings.IndexAny(line, " =\t"); i > 0 {
+		name, value = line[:i], strings.TrimSpace(strings.TrimLeft(line[i:], " =\t"))
+	} else if len(line) > 2 && line[1] != '-' {
+		name, value = line[:2], strings.TrimSpace(line[2:]) // -rfile 形式
+	}
+	switch name {
+	case "-r", "--requirement", "-c", "--constraint":
+		p.include(rel, lineNo, line, value, depth)
+	case "-e", "--editable":
+		p.requirement(rel, lineNo, value, true)
+	case "-i", "--index-url":
+		p.add(rel, lineNo, "requirements-index-override", "依赖文件覆盖了包索引地址（可能从恶意镜像安装）", line)
+	case "--extra-index-url":
+		p.add(rel, lineNo, "requirements-index-override", "依赖文件添加了额外包索引（存在依赖混淆风险）", line)
+	case "-f", "--find-links":
+		p.add(rel, lineNo, "requirements-index-override", "依赖文件指定了额外的包查找地址", line)
+	case "--trusted-host":
+		p.add(rel, lineNo, "requirements-index-override", "依赖文件关闭了对指定主机的HTTPS校验", line)
+	case "--no-index", "--hash", "--prefer-binary", "--only-binary", "--no-binary", "--pre", "--require-hashes":
+	default:
+		p.add(rel, lineNo, "requirements-invalid", "无法识别的依赖文件选项", line)
+	}
+}
+
+// include 处理 -r/-c 包含（只允许包含代码包内的文件）
+func (p *requirementsParser) include(rel string, lineNo int, line, target string, depth int) {
+	if target == "" || (urlRequirementPattern.MatchString(target) && !strings.HasPrefix(target, ".")) {
+		p.add(rel, lineNo, "requirements-include-escape", "依赖文件包含了代码包之外的需求文件", line)
+		return
+	}
+	inc := path.Clean(path.Join(path.Dir(rel), filepath.ToSlash(target)))
+	if path.IsAbs(target) || inc == ".." || strings.HasPrefix(inc, "../") {
+		p.add(rel, lineNo, "requirements-include-escape", "依赖文件包含了代码包之外的需求文件", line)
+		return
+	}
+	if _, err := os.Stat(filepath.Join(p.root, filepath.FromSlash(inc))); err != nil {
+		p.add(rel, lineNo, "requirements-include-missing", "依赖文件包含的需求文件不存在", line)
+		return
+	}
+	if depth+1 > maxRequirementsDepth {
+		p.add(rel, lineNo, "requirements-invalid", "依赖文件嵌套包含过深", line)
+		return
+	}
+	p.parseFile(inc, depth+1)
+}
+
+// requirement 解析一条PEP 508依赖（或直接引用）
+func (p *requirementsParser) requirement(rel string, lineNo int, line string, editable bool) {
+	r := Requirement{File: rel, Line: lineNo, Editable: editable}
+	spec := line
+	// 行内的 --hash 等选项
+	if i := strings.Index(spec, " --"); i >= 0 {
+		spec = strings.TrimSpace(spec[:i])
+	}
+	if i := strings.Index(spec, ";"); i >= 0 {
+		r.Marker, spec = strings.TrimSpace(spec[i+1:]), strings.TrimSpace(spec[:i])
+	}
+	// 直接引用：URL/路径（可带#egg=name）
+	if urlRequirementPattern.MatchString(spec) {
+		r.URL = spec
+		if i := strings.Index(spec, "#egg="); i >= 0 {
+			r.RawName = strings.SplitN(spec[i+5:], "&", 2)[0]
+		}
+		r.Name = NormalizePackageName(r.RawName)
+		p.reqs = append(p.reqs, r)
+		return
+	}
+	m := requirementNamePattern.FindStringSubmatch(spec)
+	if m == nil {
+		p.add(rel, lineNo, "requirements-invalid", "无法解析的依赖声明", line)
+		return
+	}
+	r.RawName, r.Name = m[1], NormalizePackageName(m[1])
+	for _, e := range strings.Split(m[2], ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			r.Extras = append(r.Extras, e)
+		}
+	}
+	rest := strings.TrimSpace(m[3])
+	if strings.HasPrefix(rest, "@") {
+		r.URL = strings.TrimSpace(rest[1:])
+	} else {
+		r.Specifier = strings.Join(strings.Fields(strings.Trim(rest, "()")), "")
+		if strings.Count(r.Specifier, ",") == 0 && !strings.Contains(r.Specifier, "*") {
+			switch {
+			case strings.HasPrefix(r.Specifier, "==="):
+				r.Version = r.Specifier[3:]
+			case strings.HasPrefix(r.Specifier, "=="):
+				r.Version = r.Specifier[2:]
+			}
+		}
+	}
+	p.reqs = append(p.reqs, r)
+}
+
+// evaluate 按策略评估一条依赖
+func (p *requirementsParser) evaluate(r Requirement, policy DependencyPolicy) {
+	line := r.RawName + r.Specifier
+	if r.URL != "" {
+		p.add(r.File, r.Line, "requirements-direct-url", "依赖直接引用了URL/VCS/本地路径，绕过包索引与版本检查", r.URL)
+	}
+	if r.Name == "" {
+		return
+	}
+	if nameListed(policy.Deny, r.Name) {
+		p.add(r.File, r.Line, "dependency-denied", fmt.Sprintf("依赖%s在禁止名单中", r.RawName), line)
+		return
+	}
+	allowed := nameListed(policy.Allow, r.Name)
+	if len(policy.Allow) > 0 && !allowed {
+		p.add(r.File, r.Line, "dependency-not-allowed", fmt.Sprintf("依赖%s不在允许名单中", r.RawName), line)
+	}
+	if !allowed {
+		if target := typosquatTarget(r.Name, policy.Popular); target != "" {
+			p.add(r.File, r.Line, "dependency-typosquat", fmt.Sprintf("依赖%s与常用包%s名称相近，疑似仿冒包", r.RawName, target), line)
+		}
+	}
+	if r.URL == "" && r.Version == "" && policy.RequirePins {
+		p.add(r.File, r.Line, "requirements-unpinned", fmt.Sprintf("依赖%s未固定版本", r.RawName), line)
+	}
+	if r.Version != "" {
+		for _, v := range policy.Vulns.Match(r.Name, r.Version) {
+			msg := fmt.Sprintf("依赖%s %s存在已知漏洞%s", r.RawName, r.Version, v.ID)
+			if len(v.Aliases) > 0 {
+				msg += "（" + strings.Join(v.Aliases, "、") + "）"
+			}
+			if v.DatabaseSpecific.Severity != "" {
+				msg += "，级别" + v.DatabaseSpecific.Severity
+			}
+			if v.Summary != "" {
+				msg += "：" + v.Summary
+			}
+			p.add(r.File, r.Line, "dependency-vulnerable", msg, line)
+		}
+	}
+}
+
+// nameListed 包名是否在名单中（按规范化名称比较）
+func nameListed(list []string, name string) bool {
+	for _, n := range list {
+		if NormalizePackageName(n) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// typosquatTarget 包名与某个常用包相近（编辑距离1，长名称允许2）但不相同时返回该常用包
+func typosquatTarget(name string, popular []string) string {
+	if len(popular) == 0 {
+		popular = popularPackages
+	}
+	if len(name) < 4 || nameListed(popular, name) {
+		return ""
+	}
+	squashed := strings.ReplaceAll(name, "-", "")
+	var candidates []string
+	for _, p := range popular {
+		target := NormalizePackageName(p)
+		limit := 1
+		if len(target) >= 10 {
+			limit = 2
+		}
+		if squashed == strings.ReplaceAll(target, "-", "") || osaDistance(name, target) <= limit {
+			candidates = append(candidates, target)
+		}
+	}
+	sort.Strings(candidates)
+	if len(candidates) == 0 {
+		return ""
+	}
+	return candidates[0]
+}
+
+// osaDistance 编辑距离（允许相邻字符交换）
+func osaDistance(a, b string) int {
+	if a == b {
+		return 0
+	}
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := 0; j <= len(b); j++ {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(a)][len(b)]
+}
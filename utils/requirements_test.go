@@ -0,0 +1,292 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTree 在dir下按相对路径写出文件
+func writeTree(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, data := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func mustPEP440(t *testing.T, s string) PEP440Version {
+	t.Helper()
+	v, err := ParsePEP440(s)
+	if err != nil {
+		t.Fatalf("ParsePEP440(%q): %v", s, err)
+	}
+	return v
+}
+
+func TestPEP440Ordering(t *testing.T) {
+	// 严格递增
+	ordered := []string{
+		"0.9", "1.0.dev0", "1.0a1.dev1", "1.0a1", "1.0a2", "1.0b1", "1.0rc1", "1.0",
+		"1.0.post1.dev0", "1.0.post1", "1.0.1", "1.2", "1.9", "1.10", "1.10.1", "2.0", "1!0.1",
+	}
+	for i := 0; i+1 < len(ordered); i++ {
+		a, b := mustPEP440(t, ordered[i]), mustPEP440(t, ordered[i+1])
+		if a.Compare(b) != -1 || b.Compare(a) != 1 {
+			t.Errorf("want %s < %s", ordered[i], ordered[i+1])
+		}
+	}
+
+	// 不同写法的同一版本
+	equal := [][2]string{
+		{"1.0", "1.0.0"}, {"1.0RC1", "1.0rc1"}, {"1.0c1", "1.0rc1"}, {"1.0alpha1", "1.0a1"},
+		{"1.0-1", "1.0.post1"}, {"1.0.rev1", "1.0.post1"}, {"v1.0", "1.0"}, {"1.0+ubuntu1", "1.0"},
+		{"0!1.0", "1.0"}, {"1.0-dev", "1.0.dev0"},
+	}
+	for _, p := range equal {
+		if c := mustPEP440(t, p[0]).Compare(mustPEP440(t, p[1])); c != 0 {
+			t.Errorf("%s vs %s = %d, want 0", p[0], p[1], c)
+		}
+	}
+
+	for _, bad := range []string{"", "latest", "1..0", "1.0-beta-foo", "==1.0"} {
+		if _, err := ParsePEP440(bad); err == nil {
+			t.Errorf("ParsePEP440(%q) accepted an invalid version", bad)
+		}
+	}
+}
+
+const testOSV = `[
+  {"id": "PYSEC-RANGE", "affected": [{"package": {"ecosystem": "PyPI", "name": "Django"},
+    "ranges": [{"type": "ECOSYSTEM", "events": [
+      {"introduced": "0"}, {"fixed": "3.2.19"}, {"introduced": "4.0"}, {"fixed": "4.1.9"}]}]}]},
+  {"id": "PYSEC-LAST", "affected": [{"package": {"ecosystem": "PyPI", "name": "py_yaml.lib"},
+    "ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "1.0"}, {"last_affected": "1.2"}]}]}]},
+  {"id": "PYSEC-LIST", "affected": [{"package": {"ecosystem": "PyPI", "name": "listed"}, "versions": ["2.0.0"]}]},
+  {"id": "GHSA-NPM", "affected": [{"package": {"ecosystem": "npm", "name": "listed"},
+    "ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "0"}]}]}]}
+]`
+
+func TestVulnDBMatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "osv.json")
+	if err := os.WriteFile(path, []byte(testOSV), 0644); err != nil {
+		t.Fatal(err)
+	}
+	db, err := LoadVulnDB(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if db.Count() != 4 {
+		t.Errorf("Count = %d, want 4", db.Count())
+	}
+
+	cases := []struct {
+		name, version, want string
+	}{
+		{"django", "1.11", "PYSEC-RANGE"},
+		{"Django", "3.2.18", "PYSEC-RANGE"},
+		{"django", "3.2.19", ""},
+		{"django", "3.10.0", ""}, // 按数值而不是字典序比较
+		{"django", "4.0rc1", ""}, // 预发布版本早于4.0
+		{"django", "4.0", "PYSEC-RANGE"},
+		{"django", "4.1.8", "PYSEC-RANGE"},
+		{"django", "4.1.9", ""},
+		{"django", "4.2", ""},
+		{"PY-YAML-lib", "0.9", ""},
+		{"py-yaml-lib", "1.0", "PYSEC-LAST"},
+		{"py-yaml-lib", "1.2", "PYSEC-LAST"},
+		{"py-yaml-lib", "1.2.post1", ""},
+		{"listed", "2.0", "PYSEC-LIST"},
+		{"listed", "2.0.1", ""},
+		{"django", "not-a-version", ""},
+		{"unknown", "1.0", ""},
+	}
+	for _, c := range cases {
+		var ids []string
+		for _, e := range db.Match(c.name, c.version) {
+			ids = append(ids, e.ID)
+		}
+		if got := strings.Join(ids, ","); got != c.want {
+			t.Errorf("Match(%s, %s) = %q, want %q", c.name, c.version, got, c.want)
+		}
+	}
+
+	var nilDB *VulnDB
+	if nilDB.Match("django", "1.0") != nil || nilDB.Count() != 0 {
+		t.Errorf("nil VulnDB should match nothing")
+	}
+}
+
+// requirementNames 依赖清单中的包名
+func requirementNames(reqs []Requirement) map[string]bool {
+	names := map[string]bool{}
+	for _, r := range reqs {
+		names[r.Name] = true
+	}
+	return names
+}
+
+// findingsByRule 按规则统计发现（规则ID → 所在文件）
+func findingsByRule(findings map[string][]SecurityFinding) map[string][]string {
+	rules := map[string][]string{}
+	for file, list := range findings {
+		for _, f := range list {
+			rules[f.Rule] = append(rules[f.Rule], file)
+		}
+	}
+	return rules
+}
+
+func TestRequirementsIncludes(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		RequirementsFile: "-r base.txt\n-c constraints/pins.txt\n--requirement=missing.txt\n" +
+			"-r ../outside.txt\n-r /etc/passwd\n-r https://example.com/req.txt\nflask==2.0.0\n",
+		"base.txt":             "-r sub/more.txt\nnumpy==1.26.4\n",
+		"sub/more.txt":         "-r ../base.txt\nrequests>=2.31  # 循环包含只解析一次\n",
+		"constraints/pins.txt": "urllib3==2.0.7\n",
+	})
+
+	findings, reqs := CheckRequirements(dir, DependencyPolicy{})
+	names := requirementNames(reqs)
+	for _, name := range []string{"flask", "numpy", "requests", "urllib3"} {
+		if !names[name] {
+			t.Errorf("%s not parsed from included files: %v", name, reqs)
+		}
+	}
+	if len(reqs) != 4 {
+		t.Errorf("parsed %d requirements, want 4 (cycle parsed twice?)", len(reqs))
+	}
+	rules := findingsByRule(findings)
+	if n := len(rules["requirements-include-escape"]); n != 3 {
+		t.Errorf("include-escape findings = %d, want 3: %v", n, findings)
+	}
+	if n := len(rules["requirements-include-missing"]); n != 1 {
+		t.Errorf("include-missing findings = %d, want 1: %v", n, findings)
+	}
+}
+
+func TestRequirementsIncludeDepth(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{RequirementsFile: "-r r1.txt\npkg-root==1.0\n"}
+	for i := 1; i <= maxRequirementsDepth+2; i++ {
+		files[fmt.Sprintf("r%d.txt", i)] = fmt.Sprintf("-r r%d.txt\npkg-level%d==1.0\n", i+1, i)
+	}
+	writeTree(t, dir, files)
+
+	findings, reqs := CheckRequirements(dir, DependencyPolicy{Popular: []string{"unrelated-name"}})
+	names := requirementNames(reqs)
+	for i := 1; i <= maxRequirementsDepth; i++ {
+		if !names[fmt.Sprintf("pkg-level%d", i)] {
+			t.Errorf("level %d not parsed", i)
+		}
+	}
+	if names[fmt.Sprintf("pkg-level%d", maxRequirementsDepth+1)] {
+		t.Errorf("include deeper than %d was parsed", maxRequirementsDepth)
+	}
+	last := fmt.Sprintf("r%d.txt", maxRequirementsDepth)
+	if got := findingsByRule(findings)["requirements-invalid"]; len(got) != 1 || got[0] != last {
+		t.Errorf("depth finding in %v, want [%s]", got, last)
+	}
+}
+
+func TestRequirementsIndexOverride(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{RequirementsFile: strings.Join([]string{
+		"--index-url https://mirror.example.com/simple",
+		"-i https://mirror.example.com/simple",
+		"--extra-index-url=https://pypi.example.com/simple",
+		"--trusted-host mirror.example.com",
+		"-f https://example.com/wheels",
+		"--require-hashes",
+		"numpy==1.26.4 --hash=sha256:0000000000000000000000000000000000000000000000000000000000000000",
+	}, "\n")})
+
+	findings, reqs := CheckRequirements(dir, DependencyPolicy{})
+	var lines []int
+	for _, f := range findings[RequirementsFile] {
+		if f.Rule != "requirements-index-override" {
+			t.Errorf("unexpected finding: %v", f)
+			continue
+		}
+		lines = append(lines, f.Line)
+	}
+	if fmt.Sprint(lines) != "[1 2 3 4 5]" {
+		t.Errorf("index override lines = %v, want [1 2 3 4 5]", lines)
+	}
+	if len(reqs) != 1 || reqs[0].Version != "1.26.4" {
+		t.Errorf("requirements = %v", reqs)
+	}
+}
+
+func TestTyposquat(t *testing.T) {
+	cases := []struct {
+		name, want string
+	}{
+		{"reqeusts", "requests"},        // 相邻字符交换
+		{"numpyy", "numpy"},             // 多一个字符
+		{"reqests", "requests"},         // 少一个字符
+		{"scikitlearn", "scikit-learn"}, // 去掉分隔符
+		{"python-dateutils", "python-dateutil"},
+		{"tensorfloww", "tensorflow"},
+		{"tensorfolww", "tensorflow"}, // 长名称允许距离2
+		{"requests", ""},              // 常用包本身
+		{"numba", ""},                 // 距离2，短名称不报告
+		{"six", ""},                   // 过短
+		{"my-internal-lib", ""},
+	}
+	for _, c := range cases {
+		if got := typosquatTarget(c.name, nil); got != c.want {
+			t.Errorf("typosquatTarget(%q) = %q, want %q", c.name, got, c.want)
+		}
+	}
+	if got := osaDistance("abcd", "abdc"); got != 1 {
+		t.Errorf("osaDistance transposition = %d, want 1", got)
+	}
+}
+
+func TestCheckRequirementsPolicy(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"osv.json": testOSV,
+		RequirementsFile: "Django==3.2.18\nreqeusts==2.31.0\nnumpyy\nleftpad==1.0\n" +
+			"git+https://github.com/example/pkg.git#egg=pkg\n",
+	})
+	db, err := LoadVulnDB(filepath.Join(dir, "osv.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	findings, _ := CheckRequirements(dir, DependencyPolicy{Deny: []string{"LeftPad"}, Allow: nil, RequirePins: true, Vulns: db})
+	byLine := map[int][]string{}
+	for _, f := range findings[RequirementsFile] {
+		byLine[f.Line] = append(byLine[f.Line], f.Rule)
+	}
+	want := map[int]string{
+		1: "dependency-vulnerable",
+		2: "dependency-typosquat",
+		3: "dependency-typosquat,requirements-unpinned",
+		4: "dependency-denied",
+		5: "requirements-direct-url",
+	}
+	for line, rules := range want {
+		if got := strings.Join(byLine[line], ","); got != rules {
+			t.Errorf("line %d rules = %q, want %q", line, got, rules)
+		}
+	}
+
+	// 允许名单中的包不做仿冒检测
+	findings, _ = CheckRequirements(dir, DependencyPolicy{Allow: []string{"django", "reqeusts", "numpyy", "leftpad", "pkg"}})
+	for _, f := range findings[RequirementsFile] {
+		if f.Rule == "dependency-typosquat" || f.Rule == "dependency-not-allowed" {
+			t.Errorf("allowed package reported: %v", f)
+		}
+	}
+}
@@ -0,0 +1,386 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/goccy/go-yaml"
+)
+
+// 规则严重级别（由低到高）
+const (
+	SeverityInfo     = "info"
+	SeverityLow      = "low"
+	SeverityMedium   = "medium"
+	SeverityHigh     = "high"
+	SeverityCritical = "critical"
+)
+
+var severityRank = map[string]int{
+	SeverityInfo: 0, SeverityLow: 1, SeverityMedium: 2, SeverityHigh: 3, SeverityCritical: 4,
+}
+
+// ValidSeverity 是否为合法的严重级别
+func ValidSeverity(s string) bool {
+	_, ok := severityRank[s]
+	return ok
+}
+
+// SeverityAtLeast 严重级别sev是否达到阈值threshold
+func SeverityAtLeast(sev, threshold string) bool {
+	return severityRank[sev] >= severityRank[threshold]
+}
+
+// 规则适用的语言
+const (
+	LangAny     = ""        // 所有文本文件
+	LangGeneric = "generic" // 没有专用分析器的文本文件（Python由语法分析器处理）
+	LangPython  = "python"
+	LangShell   = "shell"
+	LangText    = "text" // 非源代码文本（配置、说明等）
+)
+
+// fileLanguage 按扩展名判断文件语言
+func fileLanguage(relPath string) string {
+	ext := strings.ToLower(filepath.Ext(relPath))
+	switch {
+	case isPythonFile(relPath):
+		return LangPython
+	case ext == ".sh" || ext == ".bash":
+		return LangShell
+	case sourceExts[ext]:
+		return strings.TrimPrefix(ext, ".")
+	}
+	return LangText
+}
+
+// SecurityRule 安全检查规则
+// Pattern为空的规则不做正则匹配，只为内置分析器（Python语法分析、pickle、模型格式解析）产生的同ID发现声明严重级别
+type SecurityRule struct {
+	ID          string   `json:"id" yaml:"id"`
+	Severity    string   `json:"severity" yaml:"severity"`
+	Description string   `json:"description" yaml:"description"`
+	Message     string   `json:"message,omitempty" yaml:"message"`   // 命中时的说明（默认取Description）
+	Pattern     string   `json:"pattern,omitempty" yaml:"pattern"`   // 逐行匹配的正则
+	Files       []string `json:"files,omitempty" yaml:"files"`       // 适用的文件glob（空表示所有文件）
+	Language    string   `json:"language,omitempty" yaml:"language"` // 适用的语言（见Lang*）
+	Metadata    bool     `json:"metadata,omitempty" yaml:"metadata"` // 是否同时用于检查模型元数据（GGUF模板、safetensors元数据等）
+	Disabled    bool     `json:"disabled,omitempty" yaml:"disabled"` // 禁用（内置分析器的同ID发现也一并忽略）
+
+	re *regexp.Regexp
+}
+
+// RulePack 规则包（一个规则文件）
+type RulePack struct {
+	Name    string         `json:"name" yaml:"name"`
+	Version string         `json:"version" yaml:"version"`
+	Rules   []SecurityRule `json:"rules" yaml:"rules"`
+}
+
+// compile 校验并编译规则包
+func (p *RulePack) compile() error {
+	if p.Version == "" {
+		return fmt.Errorf("规则包%s缺少version", p.Name)
+	}
+	seen := map[string]bool{}
+	for i := range p.Rules {
+		r := &p.Rules[i]
+		if r.ID == "" {
+			return fmt.Errorf("规则包%s第%d条规则缺少id", p.Name, i+1)
+		}
+		if seen[r.ID] {
+			return fmt.Errorf("规则包%s中规则%s重复", p.Name, r.ID)
+		}
+		seen[r.ID] = true
+		if !ValidSeverity(r.Severity) {
+			return fmt.Errorf("规则%s的严重级别%q非法（info/low/medium/high/critical）", r.ID, r.Severity)
+		}
+		for _, g := range r.Files {
+			if _, err := path.Match(g, ""); err != nil {
+				return fmt.Errorf("规则%s的文件glob %q非法", r.ID, g)
+			}
+		}
+		if r.Pattern != "" {
+			re, err := regexp.Compile(r.Pattern)
+			if err != nil {
+				return fmt.Errorf("规则%s的正则非法：%v", r.ID, err)
+			}
+			r.re = re
+		}
+	}
+	return nil
+}
+
+// ParseRulePack 解析JSON/YAML规则包（format为json或yaml）
+func ParseRulePack(data []byte, format string) (*RulePack, error) {
+	var p RulePack
+	var err error
+	switch format {
+	case "json":
+		err = json.Unmarshal(data, &p)
+	case "yaml", "yml":
+		err = yaml.Unmarshal(data, &p)
+	default:
+		return nil, fmt.Errorf("不支持的规则文件格式%q", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("解析规则包失败：%v", err)
+	}
+	if err := p.compile(); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// LoadRulePack 从文件加载规则包（按扩展名区分JSON/YAML）
+func LoadRulePack(file string) (*RulePack, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	p, err := ParseRulePack(data, strings.TrimPrefix(strings.ToLower(filepath.Ext(file)), "."))
+	if err != nil {
+		return nil, fmt.Errorf("%s：%v", file, err)
+	}
+	if p.Name == "" {
+		p.Name = filepath.Base(file)
+	}
+	return p, nil
+}
+
+// RuleSet 合并后的规则集：按顺序合并多个规则包，后加载的同ID规则覆盖先加载的
+type RuleSet struct {
+	versions []string
+	rules    []*SecurityRule
+	byID     map[string]*SecurityRule
+}
+
+// NewRuleSet 合并规则包
+func NewRuleSet(packs ...*RulePack) *RuleSet {
+	rs := &RuleSet{byID: map[string]*SecurityRule{}}
+	for _, p := range packs {
+		rs.versions = append(rs.versions, p.Name+"@"+p.Version)
+		for i := range p.Rules {
+			r := p.Rules[i]
+			if old, ok := rs.byID[r.ID]; ok {
+				*old = r
+				continue
+			}
+			rs.rules = append(rs.rules, &r)
+			rs.byID[r.ID] = &r
+		}
+	}
+	return rs
+}
+
+// LoadRuleSet 内置规则包 + 规则文件（不存在的文件跳过）
+func LoadRuleSet(files []string) (*RuleSet, error) {
+	packs := []*RulePack{defaultRulePack()}
+	for _, f := range files {
+		if _, err := os.Stat(f); errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		p, err := LoadRulePack(f)
+		if err != nil {
+			return nil, err
+		}
+		packs = append(packs, p)
+	}
+	return NewRuleSet(packs...), nil
+}
+
+// Version 规则集版本（各规则包的name@version）
+func (rs *RuleSet) Version() string {
+	return strings.Join(rs.versions, ",")
+}
+
+// Rules 返回全部规则（按ID排序）
+func (rs *RuleSet) Rules() []SecurityRule {
+	out := make([]SecurityRule, 0, len(rs.rules))
+	for _, r := range rs.rules {
+		out = append(out, *r)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// Severity 规则的严重级别（未声明的规则按high处理）
+func (rs *RuleSet) Severity(id string) string {
+	if r, ok := rs.byID[id]; ok {
+		return r.Severity
+	}
+	return SeverityHigh
+}
+
+// Enabled 规则是否启用
+func (rs *RuleSet) Enabled(id string) bool {
+	r, ok := rs.byID[id]
+	return !ok || !r.Disabled
+}
+
+// patternRules 适用于某个文件的正则规则
+func (rs *RuleSet) patternRules(relPath string) []*SecurityRule {
+	lang := fileLanguage(relPath)
+	var out []*SecurityRule
+	for _, r := range rs.rules {
+		if r.re == nil || r.Disabled || !matchLanguage(r.Language, lang) || !MatchFileGlobs(r.Files, relPath) {
+			continue
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// metadataRules 适用于模型元数据的正则规则
+func (rs *RuleSet) metadataRules() []*SecurityRule {
+	var out []*SecurityRule
+	for _, r := range rs.rules {
+		if r.re != nil && r.Metadata && !r.Disabled {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+func matchLanguage(ruleLang, fileLang string) bool {
+	switch ruleLang {
+	case LangAny:
+		return true
+	case LangGeneric:
+		return fileLang != LangPython
+	}
+	return ruleLang == fileLang
+}
+
+// MatchFileGlobs 文件是否匹配glob列表（空列表匹配所有文件）
+// 不含"/"的glob按文件名匹配，含"/"的按相对路径匹配
+func MatchFileGlobs(globs []string, relPath string) bool {
+	if len(globs) == 0 {
+		return true
+	}
+	for _, g := range globs {
+		target := relPath
+		if !strings.Contains(g, "/") {
+			target = path.Base(relPath)
+		}
+		if ok, _ := path.Match(g, target); ok {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	defaultPackOnce sync.Once
+	defaultPack     *RulePack
+)
+
+// defaultRulePack 内置规则包
+func defaultRulePack() *RulePack {
+	defaultPackOnce.Do(func() {
+		defaultPack = &RulePack{Name: "builtin", Version: "1", Rules: builtinRules}
+		if err := defaultPack.compile(); err != nil {
+			panic(err)
+		}
+	})
+	return defaultPack
+}
+
+// DefaultRuleSet 只包含内置规则的规则集
+func DefaultRuleSet() *RuleSet {
+	return NewRuleSet(defaultRulePack())
+}
+
+// 内置规则：正则规则 + 内置分析器的规则声明
+var builtinRules = []SecurityRule{
+	// 恶意代码特征（Python文件由语法分析器处理，不做正则匹配）
+	{ID: "os-system", Severity: SeverityCritical, Description: "检测到恶意代码特征", Pattern: `os\.system\(.*\)`, Language: LangGeneric},
+	{ID: "subprocess-popen", Severity: SeverityHigh, Description: "检测到恶意代码特征", Pattern: `subprocess\.Popen\(.*\)`, Language: LangGeneric},
+	{ID: "exec-call", Severity: SeverityHigh, Description: "检测到恶意代码特征", Pattern: `exec\(.*\)`, Language: LangGeneric},
+	{ID: "eval-call", Severity: SeverityHigh, Description: "检测到恶意代码特征", Pattern: `eval\(.*\)`, Language: LangGeneric},
+	{ID: "pickle-reduce", Severity: SeverityHigh, Description: "检测到恶意代码特征", Pattern: `__reduce__`, Language: LangGeneric},
+	{ID: "raw-socket", Severity: SeverityMedium, Description: "检测到恶意代码特征", Pattern: `socket\.socket\(.*\)`, Language: LangGeneric},
+	{ID: "requests-post", Severity: SeverityMedium, Description: "检测到恶意代码特征", Pattern: `requests\.post\(.*\)`, Language: LangGeneric},
+	{ID: "malware-keyword", Severity: SeverityLow, Description: "检测到恶意代码特征", Pattern: `后门|木马|挖矿|crypto`, Language: LangGeneric},
+
+	// LLM后门特征（同时检查模型元数据）
+	{ID: "llm-trigger", Severity: SeverityHigh, Description: "检测到LLM后门特征", Pattern: `trigger\s*=\s*["'].*["']`, Metadata: true},
+	{ID: "llm-backdoor-keyword", Severity: SeverityHigh, Description: "检测到LLM后门特征", Pattern: `backdoor|backdoor_key|hidden_command`, Metadata: true},
+	{ID: "llm-prompt-injection", Severity: SeverityHigh, Description: "检测到LLM后门特征", Pattern: `system\.prompt\s*\+=\s*["'].*["']`, Metadata: true},
+	{ID: "llm-unlock", Severity: SeverityHigh, Description: "检测到LLM后门特征", Pattern: `unlock_all|bypass_security`, Metadata: true},
+
+	// Python语法分析器
+	{ID: "subprocess-call", Severity: SeverityHigh, Description: "调用subprocess执行外部命令", Language: LangPython},
+	{ID: "ctypes-call", Severity: SeverityHigh, Description: "通过ctypes调用本地代码", Language: LangPython},
+	{ID: "pickle-load", Severity: SeverityHigh, Description: "反序列化不可信数据", Language: LangPython},
+	{ID: "dynamic-import", Severity: SeverityHigh, Description: "动态导入敏感模块", Language: LangPython},
+	{ID: "dynamic-getattr", Severity: SeverityHigh, Description: "对敏感模块进行动态属性访问", Language: LangPython},
+
+	// pickle操作码分析
+	{ID: "pickle-dangerous-global", Severity: SeverityCritical, Description: "pickle引用危险对象"},
+	{ID: "pickle-dangerous-call", Severity: SeverityCritical, Description: "pickle反序列化时调用危险对象"},
+	{ID: "pickle-global-not-allowed", Severity: SeverityHigh, Description: "pickle引用了白名单之外的对象"},
+	{ID: "pickle-dynamic-global", Severity: SeverityHigh, Description: "pickle引用的对象无法静态确定"},
+	{ID: "pickle-extension", Severity: SeverityHigh, Description: "pickle使用扩展注册表引用对象"},
+	{ID: "pickle-parse-error", Severity: SeverityHigh, Description: "pickle文件无法解析"},
+
+	// 模型格式解析
+	{ID: "safetensors-invalid", Severity: SeverityHigh, Description: "safetensors格式非法"},
+	{ID: "safetensors-bounds", Severity: SeverityHigh, Description: "safetensors张量数据越界"},
+	{ID: "safetensors-overlap", Severity: SeverityMedium, Description: "safetensors张量数据重叠"},
+	{ID: "safetensors-unreferenced-data", Severity: SeverityMedium, Description: "safetensors存在未引用的数据"},
+	{ID: "gguf-invalid", Severity: SeverityHigh, Description: "GGUF格式非法"},
+	{ID: "gguf-bounds", Severity: SeverityHigh, Description: "GGUF张量数据越界"},
+	{ID: "gguf-template-injection", Severity: SeverityCritical, Description: "GGUF chat模板可逃逸模板沙箱"},
+	{ID: "onnx-invalid", Severity: SeverityHigh, Description: "ONNX格式非法"},
+	{ID: "onnx-custom-op", Severity: SeverityHigh, Description: "ONNX使用自定义算子"},
+	{ID: "onnx-external-path", Severity: SeverityCritical, Description: "ONNX外部数据指向模型目录之外"},
+	{ID: "hdf5-invalid", Severity: SeverityHigh, Description: "HDF5格式非法"},
+	{ID: "keras-invalid", Severity: SeverityHigh, Description: "Keras模型格式非法"},
+	{ID: "keras-lambda-layer", Severity: SeverityCritical, Description: "Keras Lambda层携带序列化代码"},
+	{ID: "torch-invalid", Severity: SeverityHigh, Description: "PyTorch模型格式非法"},
+
+	// 依赖检查（requirements.txt）
+	{ID: "requirements-index-override", Severity: SeverityHigh, Description: "依赖文件覆盖或添加了包索引"},
+	{ID: "requirements-include-escape", Severity: SeverityHigh, Description: "依赖文件包含了代码包之外的需求文件"},
+	{ID: "requirements-include-missing", Severity: SeverityMedium, Description: "依赖文件包含的需求文件不存在"},
+	{ID: "requirements-direct-url", Severity: SeverityMedium, Description: "依赖直接引用URL/VCS/本地路径"},
+	{ID: "requirements-invalid", Severity: SeverityLow, Description: "依赖文件存在无法解析的内容"},
+	{ID: "requirements-unpinned", Severity: SeverityLow, Description: "依赖未固定版本"},
+	{ID: "dependency-denied", Severity: SeverityCritical, Description: "依赖在禁止名单中"},
+	{ID: "dependency-not-allowed", Severity: SeverityHigh, Description: "依赖不在允许名单中"},
+	{ID: "dependency-typosquat", Severity: SeverityHigh, Description: "依赖名称疑似仿冒常用包"},
+	{ID: "dependency-vulnerable", Severity: SeverityHigh, Description: "依赖版本存在已知漏洞"},
+
+	// 密钥/凭据泄露
+	{ID: "secret-private-key", Severity: SeverityCritical, Description: "代码包中包含私钥"},
+	{ID: "secret-aws-access-key", Severity: SeverityHigh, Description: "代码包中包含AWS访问密钥ID"},
+	{ID: "secret-aws-secret-key", Severity: SeverityCritical, Description: "代码包中包含AWS Secret Access Key"},
+	{ID: "secret-gcp-api-key", Severity: SeverityHigh, Description: "代码包中包含GCP API Key"},
+	{ID: "secret-gcp-service-account", Severity: SeverityCritical, Description: "代码包中包含GCP服务账号私钥"},
+	{ID: "secret-github-token", Severity: SeverityCritical, Description: "代码包中包含GitHub令牌"},
+	{ID: "secret-slack-token", Severity: SeverityHigh, Description: "代码包中包含Slack令牌"},
+	{ID: "secret-jwt", Severity: SeverityMedium, Description: "代码包中包含JWT"},
+	{ID: "secret-password", Severity: SeverityHigh, Description: "配置中包含明文密码/密钥"},
+	{ID: "secret-high-entropy", Severity: SeverityMedium, Description: "疑似密钥的高熵字符串"},
+	{ID: "secret-file", Severity: SeverityHigh, Description: "代码包中包含凭据文件"},
+
+	// 其他
+	{ID: "high-risk-file-type", Severity: SeverityHigh, Description: "高危文件类型"},
+	{ID: "read-error", Severity: SeverityHigh, Description: "文件无法读取"},
+	{ID: "walk-error", Severity: SeverityHigh, Description: "目录无法遍历"},
+	{ID: "suppression-manifest", Severity: SeverityMedium, Description: "抑制清单无法解析"},
+	{ID: "external", Severity: SeverityCritical, Description: "外部扫描器检测到威胁"},
+	{ID: "scanner-unavailable", Severity: SeverityHigh, Description: "外部扫描器不可用，文件未完成送检（fail-closed）"},
+	{ID: "scanner-skipped", Severity: SeverityInfo, Description: "外部扫描器不可用，文件未送检即放行（fail-open）"},
+	{ID: "archive-bomb", Severity: SeverityCritical, Description: "压缩炸弹：解压后大小、文件数或压缩比超过上限"},
+	{ID: "archive-nesting", Severity: SeverityHigh, Description: "压缩包嵌套层数超过上限，内层内容无法检查"},
+}
@@ -0,0 +1,225 @@
+package utils
+
+import (
+	"sort"
+
+	"cmas-cats-go/models"
+)
+
+// SARIF 2.1.0 报告导出（供代码评审工具按文件/行号展示检查发现）
+// 规范：https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html
+
+const (
+	SARIFVersion = "2.1.0"
+	SARIFSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+
+	sarifToolName     = "cmas-security-check"
+	sarifSrcRoot      = "SRCROOT" // 代码包解压根目录
+	sarifFingerprintV = "cmasFingerprint/v1"
+)
+
+// SARIFLog SARIF日志（顶层对象）
+type SARIFLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []SARIFRun `json:"runs"`
+}
+
+// SARIFRun 一次检查
+type SARIFRun struct {
+	Tool        SARIFTool         `json:"tool"`
+	Invocations []SARIFInvocation `json:"invocations"`
+	Artifacts   []SARIFArtifact   `json:"artifacts,omitempty"`
+	Results     []SARIFResult     `json:"results"`
+	Properties  map[string]any    `json:"properties,omitempty"`
+}
+
+// SARIFTool 检查工具及其规则
+type SARIFTool struct {
+	Driver struct {
+		Name    string      `json:"name"`
+		Version string      `json:"version,omitempty"`
+		Rules   []SARIFRule `json:"rules"`
+	} `json:"driver"`
+}
+
+// SARIFRule 规则描述
+type SARIFRule struct {
+	ID                   string         `json:"id"`
+	ShortDescription     SARIFMessage   `json:"shortDescription"`
+	DefaultConfiguration map[string]any `json:"defaultConfiguration"`
+	Properties           map[string]any `json:"properties,omitempty"`
+}
+
+// SARIFInvocation 检查执行情况
+type SARIFInvocation struct {
+	ExecutionSuccessful bool           `json:"executionSuccessful"`
+	Properties          map[string]any `json:"properties,omitempty"`
+}
+
+// SARIFArtifact 被检查的文件
+type SARIFArtifact struct {
+	Location SARIFArtifactLocation `json:"location"`
+	Length   int64                 `json:"length"`
+}
+
+// SARIFArtifactLocation 文件位置（相对代码包根目录）
+type SARIFArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+// SARIFMessage 文本
+type SARIFMessage struct {
+	Text string `json:"text"`
+}
+
+// SARIFResult 单条发现
+type SARIFResult struct {
+	RuleID              string             `json:"ruleId"`
+	RuleIndex           int                `json:"ruleIndex"`
+	Level               string             `json:"level"`
+	Message             SARIFMessage       `json:"message"`
+	Locations           []SARIFLocation    `json:"locations,omitempty"`
+	PartialFingerprints map[string]string  `json:"partialFingerprints,omitempty"`
+	Suppressions        []SARIFSuppression `json:"suppressions,omitempty"`
+	Properties          map[string]any     `json:"properties,omitempty"`
+}
+
+// SARIFLocation 发现位置
+type SARIFLocation struct {
+	PhysicalLocation struct {
+		ArtifactLocation SARIFArtifactLocation `json:"artifactLocation"`
+		Region           *SARIFRegion          `json:"region,omitempty"`
+	} `json:"physicalLocation"`
+}
+
+// SARIFRegion 行号或字节偏移
+type SARIFRegion struct {
+	StartLine  int           `json:"startLine,omitempty"`
+	ByteOffset *int64        `json:"byteOffset,omitempty"`
+	Snippet    *SARIFMessage `json:"snippet,omitempty"`
+}
+
+// SARIFSuppression 抑制状态
+type SARIFSuppression struct {
+	Kind   string `json:"kind"`
+	Status string `json:"status"`
+}
+
+// sarifLevel 严重级别 → SARIF level
+func sarifLevel(severity string) string {
+	switch severity {
+	case SeverityCritical, SeverityHigh:
+		return "error"
+	case SeverityMedium:
+		return "warning"
+	}
+	return "note"
+}
+
+// sarifSecuritySeverity 严重级别 → 数值评分（代码评审工具按该属性排序/着色）
+var sarifSecuritySeverity = map[string]string{
+	SeverityCritical: "9.5", SeverityHigh: "8.0", SeverityMedium: "5.5", SeverityLow: "3.0", SeverityInfo: "0.0",
+}
+
+// ToSARIF 把检查结果转换为SARIF 2.1.0日志
+// rules用于补充规则描述（为空时使用内置规则），发现的严重级别以检查时记录的为准
+func ToSARIF(result *SecurityCheckResult, rules *RuleSet) SARIFLog {
+	if rules == nil {
+		rules = DefaultRuleSet()
+	}
+	run := SARIFRun{
+		Invocations: []SARIFInvocation{{
+			ExecutionSuccessful: true,
+			Properties:          map[string]any{"pass": result.Pass, "reason": result.Reason},
+		}},
+		Results: []SARIFResult{},
+		Properties: map[string]any{
+			"threshold":    result.Threshold,
+			"rulesVersion": result.RulesVersion,
+			"fileName":     result.FileName,
+		},
+	}
+	run.Tool.Driver.Name = sarifToolName
+	run.Tool.Driver.Version = result.RulesVersion
+	run.Tool.Driver.Rules = []SARIFRule{}
+
+	for _, f := range result.Files {
+		if f.Path == "" {
+			continue
+		}
+		run.Artifacts = append(run.Artifacts, SARIFArtifact{
+			Location: SARIFArtifactLocation{URI: f.Path, URIBaseID: sarifSrcRoot}, Length: f.Size,
+		})
+	}
+
+	// 规则按ID排序，结果通过ruleIndex引用
+	ruleIndex := map[string]int{}
+	var ids []string
+	for _, f := range result.Findings {
+		if _, ok := ruleIndex[f.Rule]; !ok {
+			ruleIndex[f.Rule] = 0
+			ids = append(ids, f.Rule)
+		}
+	}
+	sort.Strings(ids)
+	known := map[string]SecurityRule{}
+	for _, r := range rules.Rules() {
+		known[r.ID] = r
+	}
+	for i, id := range ids {
+		ruleIndex[id] = i
+		desc, sev := id, rules.Severity(id)
+		if r, ok := known[id]; ok && r.Description != "" {
+			desc = r.Description
+		}
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, SARIFRule{
+			ID:                   id,
+			ShortDescription:     SARIFMessage{Text: desc},
+			DefaultConfiguration: map[string]any{"level": sarifLevel(sev)},
+			Properties:           map[string]any{"security-severity": sarifSecuritySeverity[sev], "tags": []string{"security"}},
+		})
+	}
+
+	for _, f := range result.Findings {
+		res := SARIFResult{
+			RuleID:    f.Rule,
+			RuleIndex: ruleIndex[f.Rule],
+			Level:     sarifLevel(f.Severity),
+			Message:   SARIFMessage{Text: f.Message},
+			Properties: map[string]any{
+				"severity": f.Severity,
+				"blocking": f.Blocking,
+			},
+		}
+		if f.Fingerprint != "" {
+			res.PartialFingerprints = map[string]string{sarifFingerprintV: f.Fingerprint}
+		}
+		if f.File != "" {
+			var loc SARIFLocation
+			loc.PhysicalLocation.ArtifactLocation = SARIFArtifactLocation{URI: f.File, URIBaseID: sarifSrcRoot}
+			switch {
+			case f.Line > 0:
+				loc.PhysicalLocation.Region = &SARIFRegion{StartLine: f.Line}
+			case f.Offset > 0:
+				offset := f.Offset
+				loc.PhysicalLocation.Region = &SARIFRegion{ByteOffset: &offset}
+			}
+			if f.Snippet != "" && loc.PhysicalLocation.Region != nil {
+				loc.PhysicalLocation.Region.Snippet = &SARIFMessage{Text: f.Snippet}
+			}
+			res.Locations = []SARIFLocation{loc}
+		}
+		switch f.Suppression {
+		case models.SuppressionApproved:
+			res.Suppressions = []SARIFSuppression{{Kind: "external", Status: "accepted"}}
+			res.Properties["suppressionId"] = f.SuppressionID
+		case models.SuppressionPending:
+			res.Suppressions = []SARIFSuppression{{Kind: "inSource", Status: "underReview"}}
+		}
+		run.Results = append(run.Results, res)
+	}
+
+	return SARIFLog{Version: SARIFVersion, Schema: SARIFSchema, Runs: []SARIFRun{run}}
+}
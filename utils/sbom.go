@@ -0,0 +1,290 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CycloneDX 1.5 JSON格式的软件物料清单（https://cyclonedx.org/docs/1.5/json/）
+// 覆盖代码包内的文件及其哈希、requirements.txt中的Python依赖、服务登记的软件依赖以及运行基础镜像
+
+// SBOMSpecVersion 生成的CycloneDX规范版本
+const SBOMSpecVersion = "1.5"
+
+// CycloneDXBOM CycloneDX文档（只包含用到的字段）
+type CycloneDXBOM struct {
+	BOMFormat    string               `json:"bomFormat"`
+	SpecVersion  string               `json:"specVersion"`
+	SerialNumber string               `json:"serialNumber"`
+	Version      int                  `json:"version"`
+	Metadata     CycloneDXMetadata    `json:"metadata"`
+	Components   []CycloneDXComponent `json:"components"`
+	Dependencies []CycloneDXDep       `json:"dependencies,omitempty"`
+}
+
+// CycloneDXMetadata 文档元数据
+type CycloneDXMetadata struct {
+	Timestamp string              `json:"timestamp"`
+	Tools     []CycloneDXTool     `json:"tools,omitempty"`
+	Component *CycloneDXComponent `json:"component,omitempty"`
+}
+
+// CycloneDXTool 生成工具
+type CycloneDXTool struct {
+	Vendor string `json:"vendor,omitempty"`
+	Name   string `json:"name"`
+}
+
+// CycloneDXComponent 组件
+type CycloneDXComponent struct {
+	BOMRef             string              `json:"bom-ref"`
+	Type               string              `json:"type"` // application/library/file/container
+	Name               string              `json:"name"`
+	Version            string              `json:"version,omitempty"`
+	PURL               string              `json:"purl,omitempty"`
+	Hashes             []CycloneDXHash     `json:"hashes,omitempty"`
+	ExternalReferences []CycloneDXExtRef   `json:"externalReferences,omitempty"`
+	Properties         []CycloneDXProperty `json:"properties,omitempty"`
+}
+
+// CycloneDXHash 哈希值
+type CycloneDXHash struct {
+	Alg     string `json:"alg"`
+	Content string `json:"content"`
+}
+
+// CycloneDXExtRef 外部引用
+type CycloneDXExtRef struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+// CycloneDXProperty 名值对属性
+type CycloneDXProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// CycloneDXDep 依赖关系
+type CycloneDXDep struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn,omitempty"`
+}
+
+// SBOMInput 生成SBOM所需的代码包与服务信息
+type SBOMInput struct {
+	Root               string   // 解压后的代码包目录
+	ServiceID          string   // 服务ID
+	ServiceName        string   // 服务名称
+	Version            string   // 服务版本（上传记录ID）
+	BundleName         string   // 代码包文件名
+	BundleSHA256       string   // 代码包SHA-256
+	SoftwareDependency []string // 服务登记的软件依赖（models.Service.SoftwareDependency）
+	BaseImage          string   // 运行基础镜像（name:tag）
+}
+
+// 服务登记的软件依赖条目：name、name==version、name>=version、name version
+var softwareDependencyPattern = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9._+-]*)\s*(?:(===?|>=|<=|~=|>|<|@|\s)\s*(\S+))?$`)
+
+// GenerateSBOM 为通过检查的代码包生成CycloneDX SBOM
+func GenerateSBOM(in SBOMInput) (*CycloneDXBOM, error) {
+	root := &CycloneDXComponent{
+		BOMRef:  "service:" + in.ServiceID,
+		Type:    "application",
+		Name:    in.ServiceName,
+		Version: in.Version,
+		Properties: []CycloneDXProperty{
+			{Name: "cmas:service_id", Value: in.ServiceID},
+			{Name: "cmas:bundle", Value: in.BundleName},
+		},
+	}
+	if root.Name == "" {
+		root.Name = in.ServiceID
+	}
+	if in.BundleSHA256 != "" {
+		root.Hashes = []CycloneDXHash{{Alg: "SHA-256", Content: in.BundleSHA256}}
+	}
+	bom := &CycloneDXBOM{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  SBOMSpecVersion,
+		SerialNumber: newSerialNumber(),
+		Version:      1,
+		Metadata: CycloneDXMetadata{
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+			Tools:     []CycloneDXTool{{Vendor: "CMAS", Name: "cmas-platform"}},
+			Component: root,
+		},
+	}
+	var refs []string
+	add := func(comp CycloneDXComponent) {
+		bom.Components = append(bom.Components, comp)
+		refs = append(refs, comp.BOMRef)
+	}
+
+	// 代码包内的文件
+	files, err := sbomFiles(in.Root)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		add(f)
+	}
+
+	// requirements.txt中的Python依赖
+	seen := map[string]bool{}
+	for _, r := range ParseRequirements(in.Root) {
+		comp := CycloneDXComponent{
+			BOMRef:  componentRef("pypi", r.Name, r.Version),
+			Type:    "library",
+			Name:    r.Name,
+			Version: r.Version,
+			PURL:    "pkg:pypi/" + r.Name,
+			Properties: []CycloneDXProperty{
+				{Name: "cmas:source", Value: fmt.Sprintf("%s:%d", r.File, r.Line)},
+			},
+		}
+		if r.Version != "" {
+			comp.PURL += "@" + r.Version
+		}
+		if r.Specifier != "" && r.Version == "" {
+			comp.Properties = append(comp.Properties, CycloneDXProperty{Name: "cmas:specifier", Value: r.Specifier})
+		}
+		if r.Marker != "" {
+			comp.Properties = append(comp.Properties, CycloneDXProperty{Name: "cmas:marker", Value: r.Marker})
+		}
+		if r.URL != "" {
+			comp.ExternalReferences = []CycloneDXExtRef{{Type: "distribution", URL: r.URL}}
+		}
+		if seen[comp.BOMRef] {
+			continue
+		}
+		seen[comp.BOMRef] = true
+		add(comp)
+	}
+
+	// 服务登记的软件依赖
+	for _, dep := range in.SoftwareDependency {
+		dep = strings.TrimSpace(dep)
+		if dep == "" {
+			continue
+		}
+		comp := CycloneDXComponent{
+			Type:       "library",
+			Name:       dep,
+			Properties: []CycloneDXProperty{{Name: "cmas:source", Value: "service.software_dependency"}},
+		}
+		if m := softwareDependencyPattern.FindStringSubmatch(dep); m != nil {
+			comp.Name = m[1]
+			switch m[2] {
+			case "":
+			case "==", "===", "@", " ", "\t":
+				comp.Version = m[3]
+			default:
+				comp.Properties = append(comp.Properties, CycloneDXProperty{Name: "cmas:specifier", Value: m[2] + m[3]})
+			}
+		}
+		comp.BOMRef = componentRef("software", comp.Name, comp.Version)
+		if seen[comp.BOMRef] {
+			continue
+		}
+		seen[comp.BOMRef] = true
+		add(comp)
+	}
+
+	// 运行基础镜像
+	if in.BaseImage != "" {
+		name, tag := in.BaseImage, ""
+		if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+			name, tag = name[:i], name[i+1:]
+		}
+		comp := CycloneDXComponent{
+			BOMRef:  "container:" + in.BaseImage,
+			Type:    "container",
+			Name:    name,
+			Version: tag,
+			PURL:    "pkg:docker/" + name,
+		}
+		if tag != "" {
+			comp.PURL += "@" + tag
+		}
+		add(comp)
+	}
+
+	bom.Dependencies = []CycloneDXDep{{Ref: root.BOMRef, DependsOn: refs}}
+	return bom, nil
+}
+
+// componentRef 依赖组件的bom-ref（未固定版本时不带@version）
+func componentRef(kind, name, version string) string {
+	if version == "" {
+		return kind + ":" + name
+	}
+	return kind + ":" + name + "@" + version
+}
+
+// sbomFiles 代码包内的文件组件（相对路径排序，附SHA-256）
+func sbomFiles(root string) ([]CycloneDXComponent, error) {
+	var files []CycloneDXComponent
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		digest, err := sha256File(p)
+		if err != nil {
+			return err
+		}
+		files = append(files, CycloneDXComponent{
+			BOMRef: "file:" + rel,
+			Type:   "file",
+			Name:   rel,
+			Hashes: []CycloneDXHash{{Alg: "SHA-256", Content: digest}},
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("生成文件清单失败：%v", err)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+	return files, nil
+}
+
+// sha256File 文件SHA-256（十六进制）
+func sha256File(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// newSerialNumber 随机UUID（v4）形式的文档序列号
+func newSerialNumber() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
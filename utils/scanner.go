@@ -0,0 +1,105 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Scanner 外部扫描器（杀毒引擎等）：检查器把每个文件的内容分发给所有扫描器
+type Scanner interface {
+	Name() string
+	Scan(ctx context.Context, name string, r io.Reader) ([]ScanMatch, error)
+}
+
+// ScanMatch 外部扫描器的一条命中
+type ScanMatch struct {
+	Signature string // 特征名（如clamd的病毒名）
+	Detail    string // 补充说明
+}
+
+// ErrScannerUnavailable 扫描器不可用（连接失败、超时等），按策略决定放行还是拒绝
+var ErrScannerUnavailable = errors.New("扫描器不可用")
+
+// 外部扫描器不可用时的处理策略
+const (
+	ScannerFailOpen   = "open"   // 放行：记录提示级别的发现
+	ScannerFailClosed = "closed" // 拒绝：记录高危发现，检查不通过
+)
+
+// externalScan 一次检查中对外部扫描器的调用状态
+// 扫描器不可用后，本次检查的后续文件不再送检（避免每个文件都等待超时）
+type externalScan struct {
+	scanners []Scanner
+	failMode string
+
+	mu   sync.Mutex
+	down map[string]bool
+}
+
+func newExternalScan(policy SecurityPolicy) *externalScan {
+	mode := policy.ScannerFailure
+	if mode != ScannerFailOpen {
+		mode = ScannerFailClosed
+	}
+	return &externalScan{scanners: policy.Scanners, failMode: mode, down: map[string]bool{}}
+}
+
+// scan 并发调用所有扫描器检查单个文件
+func (e *externalScan) scan(relPath string, content []byte) []SecurityFinding {
+	if e == nil || len(e.scanners) == 0 {
+		return nil
+	}
+	results := make([][]SecurityFinding, len(e.scanners))
+	var wg sync.WaitGroup
+	for i, s := range e.scanners {
+		e.mu.Lock()
+		down := e.down[s.Name()]
+		e.mu.Unlock()
+		if down {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, s Scanner) {
+			defer wg.Done()
+			results[i] = e.scanOne(s, relPath, content)
+		}(i, s)
+	}
+	wg.Wait()
+
+	var findings []SecurityFinding
+	for _, r := range results {
+		findings = append(findings, r...)
+	}
+	return findings
+}
+
+func (e *externalScan) scanOne(s Scanner, relPath string, content []byte) []SecurityFinding {
+	matches, err := s.Scan(context.Background(), relPath, bytes.NewReader(content))
+	if err != nil {
+		msg := fmt.Sprintf("外部扫描器%s检查失败：%v", s.Name(), err)
+		if errors.Is(err, ErrScannerUnavailable) {
+			e.mu.Lock()
+			e.down[s.Name()] = true
+			e.mu.Unlock()
+			msg += "（本次检查的后续文件不再送检）"
+		}
+		rule := "scanner-unavailable"
+		if e.failMode == ScannerFailOpen {
+			rule = "scanner-skipped"
+		}
+		return []SecurityFinding{{File: relPath, Rule: rule, Message: msg}}
+	}
+	var findings []SecurityFinding
+	for _, m := range matches {
+		msg := fmt.Sprintf("外部扫描器%s检测到威胁", s.Name())
+		if m.Detail != "" {
+			msg += "：" + m.Detail
+		}
+		findings = append(findings, SecurityFinding{File: relPath, Rule: "external", Snippet: m.Signature, Message: msg})
+	}
+	return findings
+}
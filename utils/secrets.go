@@ -0,0 +1,214 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// 密钥/凭据泄露检测：已知格式（PEM私钥、AWS/GCP/GitHub/Slack令牌、JWT、配置中的密码）
+// 加上高熵字符串检测；报告中只保留脱敏后的片段
+
+// 密钥发现的处理方式
+const (
+	SecretActionBlock = "block" // 按规则严重级别参与判定（默认）
+	SecretActionWarn  = "warn"  // 只报告，不导致不通过
+)
+
+// secretRulePrefix 密钥检测规则ID前缀
+const secretRulePrefix = "secret-"
+
+// isSecretRule 是否为密钥检测规则
+func isSecretRule(id string) bool {
+	return strings.HasPrefix(id, secretRulePrefix)
+}
+
+// secretDetector 已知格式的密钥检测器（Group为密钥所在的子匹配，0表示整个匹配）
+type secretDetector struct {
+	Rule    string
+	Message string
+	Pattern *regexp.Regexp
+	Group   int
+}
+
+var secretDetectors = []secretDetector{
+	{"secret-private-key", "代码包中包含私钥", regexp.MustCompile(`-----BEGIN ((RSA|DSA|EC|OPENSSH|ENCRYPTED|PGP) )?PRIVATE KEY( BLOCK)?-----`), 0},
+	{"secret-aws-access-key", "代码包中包含AWS访问密钥ID", regexp.MustCompile(`\b((?:AKIA|ASIA|AGPA|AIDA|AROA)[0-9A-Z]{16})\b`), 1},
+	{"secret-aws-secret-key", "代码包中包含AWS Secret Access Key", regexp.MustCompile(`(?i)aws.{0,20}(?:secret|sk).{0,20}?['"=:\s]+([A-Za-z0-9/+]{40})\b`), 1},
+	{"secret-gcp-api-key", "代码包中包含GCP API Key", regexp.MustCompile(`\b(AIza[0-9A-Za-z_-]{35})\b`), 1},
+	{"secret-gcp-service-account", "代码包中包含GCP服务账号私钥", regexp.MustCompile(`"private_key_id"\s*:\s*"([0-9a-f]{40})"`), 1},
+	{"secret-github-token", "代码包中包含GitHub令牌", regexp.MustCompile(`\b((?:ghp|gho|ghu|ghs|ghr)_[A-Za-z0-9]{36}|github_pat_[A-Za-z0-9_]{82})\b`), 1},
+	{"secret-slack-token", "代码包中包含Slack令牌", regexp.MustCompile(`\b(xox[abprs]-[A-Za-z0-9-]{10,})\b`), 1},
+	{"secret-jwt", "代码包中包含JWT", regexp.MustCompile(`\b(eyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,})`), 1},
+	{"secret-password", "配置中包含明文密码/密钥", regexp.MustCompile(`(?i)(?:^|[^A-Za-z0-9])(?:[A-Za-z0-9]+[_.-])*(?:password|passwd|pwd|secret|secret[_-]?key|api[_-]?key|access[_-]?token|auth[_-]?token|client[_-]?secret)["']?\s*[:=]\s*["']?([^\s"',;#]{6,})`), 1},
+}
+
+// 明文密码检测中视为占位符的值（环境变量引用、模板、示例值）
+var secretPlaceholderPattern = regexp.MustCompile(`(?i)^(\$\{?|%\(|\{\{|<|os\.|env|getenv|config\.|settings\.|self\.|none|null|true|false|x{3,}|\*{3,}|changeme|change_me|your[_-]|example|dummy|placeholder|redacted|todo|.*\()`)
+
+// 高熵候选串：引号内或赋值号后的长令牌
+var secretCandidatePattern = regexp.MustCompile(`["']([A-Za-z0-9+/=_\-]{20,})["']|[:=]\s*([A-Za-z0-9+/=_\-]{20,})`)
+
+// 含这些词的行通常是校验和而非密钥
+var secretChecksumPattern = regexp.MustCompile(`(?i)sha1|sha256|sha384|sha512|md5|integrity|checksum|hash|digest`)
+
+// 常见的凭据文件名
+var secretFileNames = map[string]string{
+	"id_rsa": "SSH私钥", "id_dsa": "SSH私钥", "id_ecdsa": "SSH私钥", "id_ed25519": "SSH私钥", ".env": "环境变量文件",
+	".netrc": "netrc凭据", ".git-credentials": "Git凭据", ".pypirc": "PyPI凭据", ".npmrc": "npm凭据",
+	".htpasswd": "htpasswd口令文件", "credentials": "云平台凭据",
+}
+
+var secretFileExts = map[string]string{
+	".p12": "PKCS#12证书库", ".pfx": "PKCS#12证书库", ".jks": "Java密钥库", ".keystore": "Java密钥库",
+}
+
+// secretFileFinding 按文件名识别凭据文件（.env、SSH私钥、证书库等）
+func secretFileFinding(relPath string) (SecurityFinding, bool) {
+	base := path.Base(relPath)
+	lower := strings.ToLower(base)
+	kind := secretFileNames[lower]
+	if kind == "" {
+		kind = secretFileExts[path.Ext(lower)]
+	}
+	if kind == "" && strings.HasPrefix(lower, ".env.") {
+		switch path.Ext(lower) {
+		case ".example", ".sample", ".template", ".dist":
+		default:
+			kind = "环境变量文件"
+		}
+	}
+	if kind == "" {
+		return SecurityFinding{}, false
+	}
+	return SecurityFinding{File: relPath, Rule: "secret-file", Message: fmt.Sprintf("代码包中包含凭据文件（%s）", kind)}, true
+}
+
+// DetectSecrets 检测文本内容中的密钥与凭据（片段已脱敏）
+func DetectSecrets(relPath string, content []byte) []SecurityFinding {
+	findings, _ := detectSecrets(relPath, content)
+	return findings
+}
+
+// detectSecrets 同DetectSecrets，并返回识别出的密钥原文（用于脱敏同一文件中其他规则的片段）
+func detectSecrets(relPath string, content []byte) ([]SecurityFinding, []string) {
+	var findings []SecurityFinding
+	var secrets []string
+	if f, ok := secretFileFinding(relPath); ok {
+		findings = append(findings, f)
+	}
+	// 源代码中的变量赋值大多不是明文密码，只检测字符串字面量
+	quotedOnly := sourceExts[strings.ToLower(path.Ext(relPath))]
+	for i, line := range strings.Split(string(content), "\n") {
+		found := map[string]bool{} // 本行已识别的密钥，避免高熵检测重复报告
+		for _, d := range secretDetectors {
+			for _, m := range d.Pattern.FindAllStringSubmatch(line, -1) {
+				value := m[d.Group]
+				if d.Rule == "secret-password" && (secretPlaceholderPattern.MatchString(value) || found[value] ||
+					(quotedOnly && !strings.ContainsAny(m[0], `"'`))) {
+					continue
+				}
+				found[value] = true
+				snip := m[0]
+				if d.Group > 0 {
+					secrets = append(secrets, value)
+					snip = redactSecret(m[0], value)
+				}
+				findings = append(findings, SecurityFinding{
+					File: relPath, Line: i + 1, Rule: d.Rule, Message: d.Message, Snippet: snippet(snip),
+				})
+			}
+		}
+		if secretChecksumPattern.MatchString(line) {
+			continue
+		}
+		for _, m := range secretCandidatePattern.FindAllStringSubmatch(line, -1) {
+			value := m[1] + m[2]
+			if found[value] || !highEntropy(value) {
+				continue
+			}
+			found[value] = true
+			secrets = append(secrets, value)
+			findings = append(findings, SecurityFinding{
+				File: relPath, Line: i + 1, Rule: "secret-high-entropy",
+				Message: fmt.Sprintf("疑似密钥的高熵字符串（熵%.1f）", shannonEntropy(value)),
+				Snippet: snippet(redactSecret(m[0], value)),
+			})
+		}
+	}
+	return findings, secrets
+}
+
+// redactFindings 脱敏其他规则片段中出现的密钥原文
+func redactFindings(findings []SecurityFinding, secrets []string) {
+	for i := range findings {
+		if isSecretRule(findings[i].Rule) {
+			continue
+		}
+		for _, s := range secrets {
+			findings[i].Snippet = redactSecret(findings[i].Snippet, s)
+			findings[i].Message = redactSecret(findings[i].Message, s)
+		}
+	}
+}
+
+// redactSecret 片段中的密钥只保留前4个字符与长度
+func redactSecret(match, secret string) string {
+	if secret == "" || !strings.Contains(match, secret) {
+		return match
+	}
+	keep := 4
+	if len(secret) <= 8 {
+		keep = 0
+	}
+	return strings.Replace(match, secret, fmt.Sprintf("%s****(%d)", secret[:keep], len(secret)), 1)
+}
+
+// highEntropy 高熵判断：需同时含字母与数字；十六进制串（≥32字符）熵>3.0，
+// 其他串（≥24字符）熵>4.0，40字符以上>4.5
+func highEntropy(s string) bool {
+	var letters, digits bool
+	hex := true
+	for _, c := range s {
+		switch {
+		case c >= '0' && c <= '9':
+			digits = true
+		case c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F':
+			letters = true
+		case c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			letters, hex = true, false
+		default:
+			hex = false
+		}
+	}
+	if !letters || !digits {
+		return false
+	}
+	if hex {
+		return len(s) >= 32 && shannonEntropy(s) > 3.0
+	}
+	switch {
+	case len(s) >= 40:
+		return shannonEntropy(s) > 4.5
+	case len(s) >= 24:
+		return shannonEntropy(s) > 4.0
+	}
+	return false
+}
+
+// shannonEntropy 每字符香农熵（比特）
+func shannonEntropy(s string) float64 {
+	counts := map[rune]int{}
+	for _, c := range s {
+		counts[c]++
+	}
+	var h float64
+	n := float64(len(s))
+	for _, c := range counts {
+		p := float64(c) / n
+		h -= p * math.Log2(p)
+	}
+	return h
+}
@@ -3,131 +3,368 @@ package utils
 import (
 	"bytes"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
-	"regexp"
+	"sort"
 	"strings"
+
+	"cmas-cats-go/models"
 )
 
 // 安全评估结果结构体
 type SecurityCheckResult struct {
-	Pass      bool     `json:"pass"`      // 是否通过
-	Reason    string   `json:"reason"`    // 失败原因
-	Threats   []string `json:"threats"`   // 检测到的威胁
-	FileName  string   `json:"file_name"` // 文件名
-	FileType  string   `json:"file_type"` // 文件类型
-}
-
-// 高危文件扩展名
-var highRiskExts = map[string]bool{
-	".pkl":    true,
-	".pickle": true,
-	".joblib": true,
-	".h5":     true,
-	".hdf5":   true,
-	".hdf":    true,
-}
-
-// 恶意代码特征正则
-var maliciousPatterns = []*regexp.Regexp{
-	regexp.MustCompile(`os\.system\(.*\)`),
-	regexp.MustCompile(`subprocess\.Popen\(.*\)`),
-	regexp.MustCompile(`exec\(.*\)`),
-	regexp.MustCompile(`eval\(.*\)`),
-	regexp.MustCompile(`__reduce__`), // pickle反序列化漏洞特征
-	regexp.MustCompile(`socket\.socket\(.*\)`),
-	regexp.MustCompile(`requests\.post\(.*\)`), // 可疑外连
-	regexp.MustCompile(`后门|木马|挖矿|crypto`), // 中文恶意特征
-}
-
-// LLM后门特征正则
-var llmBackdoorPatterns = []*regexp.Regexp{
-	regexp.MustCompile(`trigger\s*=\s*["'].*["']`),
-	regexp.MustCompile(`backdoor|backdoor_key|hidden_command`),
-	regexp.MustCompile(`system\.prompt\s*\+=\s*["'].*["']`),
-	regexp.MustCompile(`unlock_all|bypass_security`),
-}
-
-// CheckModelSecurity 核心：模型安全评估主函数
-func CheckModelSecurity(filePath string) *SecurityCheckResult {
+	Pass                bool                 `json:"pass"`                           // 是否通过
+	Reason              string               `json:"reason"`                         // 失败原因
+	Threats             []string             `json:"threats"`                        // 检测到的威胁（导致不通过的发现）
+	FileName            string               `json:"file_name"`                      // 文件名
+	FileType            string               `json:"file_type"`                      // 文件类型
+	Files               []FileReport         `json:"files"`                          // 逐文件检查报告
+	Findings            []SecurityFinding    `json:"findings"`                       // 全部检查发现（Pass由此汇总）
+	RulesVersion        string               `json:"rules_version"`                  // 使用的规则集版本
+	Threshold           string               `json:"threshold"`                      // 不通过的严重级别阈值
+	SuppressionRequests []models.Suppression `json:"suppression_requests,omitempty"` // 代码中的抑制注释/清单产生的待审批申请
+}
+
+// SecurityFinding 单条检查发现
+type SecurityFinding struct {
+	File          string `json:"file"`                     // 相对扫描根目录的文件路径
+	Line          int    `json:"line"`                     // 行号（从1开始，0表示针对整个文件）
+	Offset        int64  `json:"offset,omitempty"`         // 二进制文件中的字节偏移
+	Rule          string `json:"rule"`                     // 命中的规则ID
+	Severity      string `json:"severity"`                 // 严重级别
+	Snippet       string `json:"snippet"`                  // 命中的代码片段
+	Message       string `json:"message"`                  // 说明
+	Fingerprint   string `json:"fingerprint"`              // 发现指纹（用于精确抑制）
+	Blocking      bool   `json:"blocking"`                 // 是否导致不通过
+	Suppression   string `json:"suppression,omitempty"`    // 抑制状态（approved/pending）
+	SuppressionID string `json:"suppression_id,omitempty"` // 生效的抑制ID
+}
+
+// FileReport 单个文件的检查报告
+type FileReport struct {
+	Path      string            `json:"path"`                // 相对扫描根目录的文件路径
+	Kind      string            `json:"kind"`                // 文件分类（source/text/model/binary）
+	Size      int64             `json:"size"`                // 文件大小
+	Findings  []SecurityFinding `json:"findings"`            // 该文件的检查发现
+	Inventory []InventoryItem   `json:"inventory,omitempty"` // 模型文件清单（pickle全局引用、张量、元数据、算子等）
+
+	markers []suppressionMarker // 文件中的抑制注释
+}
+
+// InventoryItem 模型文件清单条目
+type InventoryItem struct {
+	Name   string `json:"name"`             // 名称（pickle全局引用的限定名、张量名、元数据键等）
+	Kind   string `json:"kind"`             // 类型（pickle操作码、tensor、metadata、op、layer等）
+	Detail string `json:"detail,omitempty"` // 补充说明
+	Offset int64  `json:"offset"`           // 字节偏移
+}
+
+// DefaultFailSeverity 默认不通过阈值：达到该级别的未抑制发现导致上传被拒
+const DefaultFailSeverity = SeverityMedium
+
+// SecurityPolicy 安全检查策略
+type SecurityPolicy struct {
+	PickleAllowlist []string             // pickle允许引用的模块前缀或完整限定名
+	Rules           *RuleSet             // 规则集（为空时使用内置规则）
+	FailSeverity    string               // 不通过阈值（为空时使用DefaultFailSeverity）
+	Suppressions    []models.Suppression // 已批准的抑制（本服务及全局）
+	Scanners        []Scanner            // 外部扫描器（clamd等）
+	ScannerFailure  string               // 外部扫描器不可用时的策略（open/closed，默认closed）
+	Dependencies    DependencyPolicy     // requirements.txt依赖检查策略
+	SecretAction    string               // 密钥泄露的处理方式（block/warn，默认block）
+}
+
+// DefaultSecurityPolicy 默认安全检查策略
+var DefaultSecurityPolicy = SecurityPolicy{
+	PickleAllowlist: DefaultPickleAllowlist,
+}
+
+func (p SecurityPolicy) rules() *RuleSet {
+	if p.Rules == nil {
+		return DefaultRuleSet()
+	}
+	return p.Rules
+}
+
+func (p SecurityPolicy) threshold() string {
+	if !ValidSeverity(p.FailSeverity) {
+		return DefaultFailSeverity
+	}
+	return p.FailSeverity
+}
+
+// 文件分类
+const (
+	FileKindSource = "source" // 源代码
+	FileKindText   = "text"   // 其他文本（配置、说明等）
+	FileKindModel  = "model"  // 模型/序列化文件
+	FileKindBinary = "binary" // 其他二进制
+)
+
+// 源代码扩展名
+var sourceExts = map[string]bool{
+	".py": true, ".pyw": true, ".sh": true, ".js": true, ".ts": true,
+	".go": true, ".java": true, ".c": true, ".cpp": true, ".rb": true, ".php": true,
+}
+
+// maxSnippetLen 报告中代码片段的最大长度
+const maxSnippetLen = 120
+
+// CheckModelSecurity 核心：模型安全评估主函数（默认策略）
+// path可以是单个文件，也可以是解压后的目录（递归检查其中每个文件）
+func CheckModelSecurity(path string) *SecurityCheckResult {
+	return CheckModelSecurityWithPolicy(path, DefaultSecurityPolicy)
+}
+
+// CheckModelSecurityWithPolicy 按指定策略进行模型安全评估
+func CheckModelSecurityWithPolicy(path string, policy SecurityPolicy) *SecurityCheckResult {
+	rules := policy.rules()
+	ext := newExternalScan(policy)
 	result := &SecurityCheckResult{
-		FileName: filepath.Base(filePath),
-		Pass:     true,
+		FileName:     filepath.Base(path),
+		Pass:         true,
+		RulesVersion: rules.Version(),
+		Threshold:    policy.threshold(),
 	}
 
-	// 步骤1：检测文件类型
-	fileExt := strings.ToLower(filepath.Ext(filePath))
-	result.FileType = fileExt
-	if highRiskExts[fileExt] {
-		result.Threats = append(result.Threats, fmt.Sprintf("高危文件类型：%s", fileExt))
+	info, err := os.Stat(path)
+	if err != nil {
 		result.Pass = false
+		result.Reason = "无法读取文件内容：" + err.Error()
+		return result
 	}
 
-	// 步骤2：读取文件内容
-	content, err := readFileContent(filePath)
-	if err != nil {
-		if result.Pass {
-			result.Reason = "无法读取文件内容：" + err.Error()
-			result.Pass = false
+	if info.IsDir() {
+		result.FileType = "dir"
+		err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.Type().IsRegular() {
+				return nil // 目录及链接等非普通文件不检查（解压阶段已拒绝链接）
+			}
+			rel, _ := filepath.Rel(path, p)
+			result.Files = append(result.Files, checkFile(p, filepath.ToSlash(rel), policy, rules, ext))
+			return nil
+		})
+		if err != nil {
+			result.Files = append(result.Files, FileReport{Findings: []SecurityFinding{{
+				Rule: "walk-error", Message: "遍历目录失败：" + err.Error(),
+			}}})
 		}
 	} else {
-		// 步骤3：检测恶意代码特征
-		for _, pattern := range maliciousPatterns {
-			if pattern.MatchString(content) {
-				threat := fmt.Sprintf("检测到恶意代码特征：%s", pattern.String())
-				result.Threats = append(result.Threats, threat)
-				result.Pass = false
-			}
+		result.FileType = strings.ToLower(filepath.Ext(path))
+		result.Files = append(result.Files, checkFile(path, filepath.Base(path), policy, rules, ext))
+	}
+
+	// 代码包根目录下的requirements.txt（含-r包含的文件）：依赖名单、仿冒包名与离线漏洞库
+	if info.IsDir() {
+		attachRequirements(result, path, policy.Dependencies)
+	}
+
+	// 代码包根目录下的抑制清单
+	var manifest []models.Suppression
+	if info.IsDir() {
+		if manifest, err = loadSuppressionManifest(path); err != nil {
+			result.Files = append(result.Files, FileReport{Findings: []SecurityFinding{{
+				Rule: "suppression-manifest", Message: "抑制清单解析失败：" + err.Error(),
+			}}})
 		}
+	}
 
-		// 步骤4：检测LLM后门特征
-		for _, pattern := range llmBackdoorPatterns {
-			if pattern.MatchString(content) {
-				threat := fmt.Sprintf("检测到LLM后门特征：%s", pattern.String())
-				result.Threats = append(result.Threats, threat)
-				result.Pass = false
+	// 汇总：按规则严重级别与阈值、已批准的抑制计算最终结论
+	requested := map[string]bool{}
+	for i := range result.Files {
+		fr := &result.Files[i]
+		kept := fr.Findings[:0]
+		for _, f := range fr.Findings {
+			if !rules.Enabled(f.Rule) {
+				continue
+			}
+			f.Severity = rules.Severity(f.Rule)
+			f.Fingerprint = FindingFingerprint(f)
+			if s, ok := findSuppression(policy.Suppressions, f); ok {
+				f.Suppression, f.SuppressionID = models.SuppressionApproved, s.ID
+			} else if req, ok := suppressionRequest(fr.markers, manifest, f); ok {
+				f.Suppression = models.SuppressionPending
+				key := req.Rule + "|" + req.File + "|" + req.Fingerprint
+				if !requested[key] {
+					requested[key] = true
+					result.SuppressionRequests = append(result.SuppressionRequests, req)
+				}
+			}
+			f.Blocking = f.Suppression != models.SuppressionApproved && SeverityAtLeast(f.Severity, result.Threshold) &&
+				!(policy.SecretAction == SecretActionWarn && isSecretRule(f.Rule))
+			kept = append(kept, f)
+			result.Findings = append(result.Findings, f)
+			if f.Blocking {
+				result.Threats = append(result.Threats, f.String())
 			}
 		}
+		fr.Findings = kept
+	}
+	result.Pass = len(result.Threats) == 0
+	switch {
+	case !result.Pass:
+		result.Reason = strings.Join(result.Threats, "；")
+	case len(result.Findings) > 0:
+		result.Reason = fmt.Sprintf("模型安全评估通过（%d条发现低于阈值%s或已批准抑制）", len(result.Findings), result.Threshold)
+	default:
+		result.Reason = "模型安全评估通过，无风险特征"
 	}
+	return result
+}
 
-	// 步骤5：调用外部工具检测（可选，增强安全性）
-	externalThreats := runExternalSecurityCheck(filePath)
-	if len(externalThreats) > 0 {
-		result.Threats = append(result.Threats, externalThreats...)
-		result.Pass = false
+// attachRequirements 把依赖检查的发现与依赖清单并入对应文件的报告
+func attachRequirements(result *SecurityCheckResult, root string, policy DependencyPolicy) {
+	findings, reqs := CheckRequirements(root, policy)
+	index := map[string]int{}
+	for i, f := range result.Files {
+		index[f.Path] = i
+	}
+	report := func(file string) *FileReport {
+		i, ok := index[file]
+		if !ok {
+			result.Files = append(result.Files, FileReport{Path: file, Kind: FileKindText})
+			i = len(result.Files) - 1
+			index[file] = i
+		}
+		return &result.Files[i]
 	}
+	for _, r := range reqs {
+		detail := r.Specifier
+		if r.URL != "" {
+			detail = r.URL
+		}
+		if r.Marker != "" {
+			detail += "; " + r.Marker
+		}
+		fr := report(r.File)
+		fr.Inventory = append(fr.Inventory, InventoryItem{Name: r.RawName, Kind: "dependency", Detail: detail})
+	}
+	files := make([]string, 0, len(findings))
+	for f := range findings {
+		files = append(files, f)
+	}
+	sort.Strings(files)
+	for _, f := range files {
+		fr := report(f)
+		fr.Findings = append(fr.Findings, findings[f]...)
+	}
+}
 
-	// 最终结果
-	if !result.Pass {
-		result.Reason = strings.Join(result.Threats, "；")
-	} else {
-		result.Reason = "模型安全评估通过，无风险特征"
+// String 威胁描述（兼容原有threats字符串列表）
+func (f SecurityFinding) String() string {
+	loc := f.File
+	if f.Line > 0 {
+		loc = fmt.Sprintf("%s:%d", f.File, f.Line)
+	} else if f.Offset > 0 {
+		loc = fmt.Sprintf("%s@%d", f.File, f.Offset)
 	}
+	msg := f.Message
+	if f.Severity != "" {
+		msg = "[" + f.Severity + "]" + msg
+	}
+	if f.Snippet != "" {
+		return fmt.Sprintf("%s：%s（%s，规则%s）", msg, f.Snippet, loc, f.Rule)
+	}
+	return fmt.Sprintf("%s（%s，规则%s）", msg, loc, f.Rule)
+}
 
-	return result
+// classifyFile 按扩展名与内容对文件分类
+func classifyFile(relPath string, content []byte) string {
+	ext := strings.ToLower(filepath.Ext(relPath))
+	switch {
+	case detectModelFormat(relPath, content) != "":
+		return FileKindModel
+	case isBinary(content):
+		return FileKindBinary
+	case sourceExts[ext]:
+		return FileKindSource
+	default:
+		return FileKindText
+	}
 }
 
-// 读取文件内容（兼容文本/二进制）
-func readFileContent(filePath string) (string, error) {
-	content, err := os.ReadFile(filePath)
+// checkFile 检查单个文件
+func checkFile(absPath, relPath string, policy SecurityPolicy, rules *RuleSet, ext *externalScan) FileReport {
+	report := FileReport{Path: relPath}
+	content, err := os.ReadFile(absPath)
 	if err != nil {
-		return "", err
+		report.Findings = append(report.Findings, SecurityFinding{
+			File: relPath, Rule: "read-error", Message: "无法读取文件内容：" + err.Error(),
+		})
+		return report
+	}
+	report.Size = int64(len(content))
+	report.Kind = classifyFile(relPath, content)
+
+	switch report.Kind {
+	case FileKindModel:
+		// 按格式解析模型文件（pickle操作码、safetensors/GGUF/ONNX头部、Keras配置）
+		findings, inventory := inspectModel(detectModelFormat(relPath, content), relPath, content, policy, rules)
+		report.Findings = append(report.Findings, findings...)
+		report.Inventory = inventory
+	case FileKindSource, FileKindText:
+		if isPythonFile(relPath) {
+			// Python源码按语法分析，注释和字符串中的内容不再误报
+			report.Findings = append(report.Findings, AnalyzePython(relPath, content)...)
+		}
+		// 规则包中适用于该文件（语言、文件glob）的正则规则
+		report.Findings = append(report.Findings, scanLines(relPath, content, rules.patternRules(relPath))...)
+		report.markers = parseInlineMarkers(content)
+		secrets, values := detectSecrets(relPath, content)
+		redactFindings(report.Findings, values)
+		report.Findings = append(report.Findings, secrets...)
+	default:
+		// 二进制文件只按文件名识别凭据文件（证书库、密钥库等）
+		if f, ok := secretFileFinding(relPath); ok {
+			report.Findings = append(report.Findings, f)
+		}
 	}
 
-	// 判断是否为文本文件
-	if isBinary(content) {
-		return "", fmt.Errorf("文件为二进制类型，跳过内容检测")
+	// 分发给外部扫描器（clamd等，未配置时跳过）
+	report.Findings = append(report.Findings, ext.scan(relPath, content)...)
+	return report
+}
+
+// scanLines 逐行匹配规则，记录行号与命中片段
+func scanLines(relPath string, content []byte, rules []*SecurityRule) []SecurityFinding {
+	var findings []SecurityFinding
+	if len(rules) == 0 {
+		return nil
+	}
+	for i, line := range strings.Split(string(content), "\n") {
+		for _, rule := range rules {
+			if m := rule.re.FindString(line); m != "" {
+				msg := rule.Message
+				if msg == "" {
+					msg = rule.Description
+				}
+				findings = append(findings, SecurityFinding{
+					File:    relPath,
+					Line:    i + 1,
+					Rule:    rule.ID,
+					Snippet: snippet(m),
+					Message: msg,
+				})
+			}
+		}
 	}
-	return string(content), nil
+	return findings
+}
+
+// snippet 截断过长的命中片段
+func snippet(s string) string {
+	s = strings.TrimSpace(s)
+	if r := []rune(s); len(r) > maxSnippetLen {
+		return string(r[:maxSnippetLen]) + "..."
+	}
+	return s
 }
 
 // 判断是否为二进制文件
 func isBinary(data []byte) bool {
 	return bytes.Contains(data, []byte{0}) // 包含空字节即为二进制
 }
-
-// 彻底禁用外部clamav扫描，避免报错
-func runExternalSecurityCheck(filePath string) []string {
-    // 直接返回空切片，不执行任何外部扫描
-    return []string{}
-}
\ No newline at end of file
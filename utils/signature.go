@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// 代码包签名：提供者用ed25519私钥对代码包SHA-256摘要（64位小写十六进制字符串的ASCII字节）签名，
+// Platform在上传、部署前校验，Site可用Apply返回的公钥独立校验
+
+// SignatureAlgorithm 代码包签名算法
+const SignatureAlgorithm = "ed25519"
+
+// ParseEd25519PublicKey 解析ed25519公钥：base64编码的32字节原始公钥，或PEM（PKIX）格式
+func ParseEd25519PublicKey(s string) (ed25519.PublicKey, error) {
+	s = strings.TrimSpace(s)
+	if block, _ := pem.Decode([]byte(s)); block != nil {
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("解析PEM公钥失败：%v", err)
+		}
+		pub, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return nil, errors.New("公钥不是ed25519类型")
+		}
+		return pub, nil
+	}
+	raw, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("公钥不是有效的base64：%v", err)
+	}
+	if len(raw) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("ed25519公钥长度应为%d字节，实际%d字节", ed25519.PublicKeySize, len(raw))
+	}
+	return ed25519.PublicKey(raw), nil
+}
+
+// KeyFingerprint 公钥指纹（公钥SHA-256的前16个十六进制字符）
+func KeyFingerprint(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return hex.EncodeToString(sum[:8])
+}
+
+// VerifyBundleSignature 校验代码包摘要的签名（signature为base64编码）
+func VerifyBundleSignature(pub ed25519.PublicKey, digest, signature string) error {
+	digest = strings.ToLower(strings.TrimSpace(digest))
+	if len(digest) != sha256.Size*2 {
+		return fmt.Errorf("代码包摘要%q不是SHA-256十六进制串", digest)
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(signature))
+	if err != nil {
+		return fmt.Errorf("签名不是有效的base64：%v", err)
+	}
+	if len(sig) != ed25519.SignatureSize {
+		return fmt.Errorf("ed25519签名长度应为%d字节，实际%d字节", ed25519.SignatureSize, len(sig))
+	}
+	if !ed25519.Verify(pub, []byte(digest), sig) {
+		return errors.New("签名与代码包摘要不匹配")
+	}
+	return nil
+}
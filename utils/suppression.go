@@ -0,0 +1,168 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+
+	"cmas-cats-go/models"
+)
+
+// 抑制：提供者在代码中用注释或清单声明“已审查的误报”，只产生待审批申请，
+// 由管理员批准后才会在后续检查中生效
+
+// 行内抑制注释：# cmas-ignore[rule-a,rule-b]: 理由
+// 注释与代码同行时作用于本行；独占一行时作用于下一行
+var inlineSuppressionPattern = regexp.MustCompile(`cmas-ignore\[([A-Za-z0-9_,\-\s]+)\]\s*:?\s*(.*)`)
+
+// SuppressionManifestNames 代码包根目录下的抑制清单文件名（按顺序取第一个存在的）
+var SuppressionManifestNames = []string{"cmas-suppressions.yaml", "cmas-suppressions.yml", "cmas-suppressions.json"}
+
+// suppressionMarker 文件中的一条抑制注释
+type suppressionMarker struct {
+	line       int
+	rules      []string
+	reason     string
+	standalone bool // 注释独占一行
+}
+
+// covers 注释是否覆盖该发现
+func (m suppressionMarker) covers(f SecurityFinding) bool {
+	if f.Line != m.line && !(m.standalone && f.Line == m.line+1) {
+		return false
+	}
+	for _, r := range m.rules {
+		if r == f.Rule {
+			return true
+		}
+	}
+	return false
+}
+
+// parseInlineMarkers 收集文件中的抑制注释
+func parseInlineMarkers(content []byte) []suppressionMarker {
+	if !bytes.Contains(content, []byte("cmas-ignore[")) {
+		return nil
+	}
+	var markers []suppressionMarker
+	for i, line := range strings.Split(string(content), "\n") {
+		loc := inlineSuppressionPattern.FindStringSubmatchIndex(line)
+		if loc == nil {
+			continue
+		}
+		m := suppressionMarker{line: i + 1, reason: strings.TrimSpace(line[loc[4]:loc[5]])}
+		for _, r := range strings.Split(line[loc[2]:loc[3]], ",") {
+			if r = strings.TrimSpace(r); r != "" {
+				m.rules = append(m.rules, r)
+			}
+		}
+		switch strings.TrimSpace(line[:loc[0]]) {
+		case "#", "//", "--", ";", "/*":
+			m.standalone = true
+		}
+		markers = append(markers, m)
+	}
+	return markers
+}
+
+// suppressionManifest 抑制清单文件格式
+type suppressionManifest struct {
+	Suppressions []struct {
+		Rule        string `json:"rule" yaml:"rule"`
+		File        string `json:"file" yaml:"file"`               // 文件路径或glob（空表示任意文件）
+		Fingerprint string `json:"fingerprint" yaml:"fingerprint"` // 发现指纹（空表示该规则在该文件的全部发现）
+		Reason      string `json:"reason" yaml:"reason"`
+	} `json:"suppressions" yaml:"suppressions"`
+}
+
+// loadSuppressionManifest 读取代码包根目录下的抑制清单（不存在时返回空）
+func loadSuppressionManifest(root string) ([]models.Suppression, error) {
+	for _, name := range SuppressionManifestNames {
+		data, err := os.ReadFile(filepath.Join(root, name))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		var m suppressionManifest
+		if strings.HasSuffix(name, ".json") {
+			err = json.Unmarshal(data, &m)
+		} else {
+			err = yaml.Unmarshal(data, &m)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s：%v", name, err)
+		}
+		var out []models.Suppression
+		for _, e := range m.Suppressions {
+			if e.Rule == "" {
+				return nil, fmt.Errorf("%s：抑制条目缺少rule", name)
+			}
+			out = append(out, models.Suppression{
+				Rule: e.Rule, File: e.File, Fingerprint: e.Fingerprint, Reason: e.Reason,
+				Source: models.SuppressionSourceManifest, Status: models.SuppressionPending,
+			})
+		}
+		return out, nil
+	}
+	return nil, nil
+}
+
+// FindingFingerprint 发现指纹：规则、文件与命中内容的摘要，不随行号变化
+func FindingFingerprint(f SecurityFinding) string {
+	content := f.Snippet
+	if content == "" {
+		content = f.Message
+	}
+	sum := sha256.Sum256([]byte(f.Rule + "|" + f.File + "|" + content))
+	return hex.EncodeToString(sum[:8])
+}
+
+// SuppressionMatches 抑制是否适用于该发现：规则相同，文件（glob）与指纹为空或一致
+func SuppressionMatches(s models.Suppression, f SecurityFinding) bool {
+	if s.Rule != f.Rule {
+		return false
+	}
+	if s.File != "" && s.File != f.File && !MatchFileGlobs([]string{s.File}, f.File) {
+		return false
+	}
+	return s.Fingerprint == "" || s.Fingerprint == f.Fingerprint
+}
+
+// findSuppression 查找适用于该发现的已批准抑制
+func findSuppression(suppressions []models.Suppression, f SecurityFinding) (models.Suppression, bool) {
+	for _, s := range suppressions {
+		if s.Status == models.SuppressionApproved && SuppressionMatches(s, f) {
+			return s, true
+		}
+	}
+	return models.Suppression{}, false
+}
+
+// suppressionRequest 由文件中的抑制注释或清单为该发现生成待审批申请
+func suppressionRequest(markers []suppressionMarker, manifest []models.Suppression, f SecurityFinding) (models.Suppression, bool) {
+	for _, m := range markers {
+		if m.covers(f) {
+			return models.Suppression{
+				Rule: f.Rule, File: f.File, Fingerprint: f.Fingerprint, Reason: m.reason,
+				Source: models.SuppressionSourceInline, Status: models.SuppressionPending,
+			}, true
+		}
+	}
+	for _, s := range manifest {
+		if SuppressionMatches(s, f) {
+			return s, true
+		}
+	}
+	return models.Suppression{}, false
+}